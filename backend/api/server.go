@@ -14,14 +14,56 @@ type AccessRestAdapter interface {
 	PutUser(w http.ResponseWriter, r *http.Request, id uuid.UUID)
 	ListUsers(w http.ResponseWriter, r *http.Request)
 	PostUser(w http.ResponseWriter, r *http.Request)
+	DeleteUserLockout(w http.ResponseWriter, r *http.Request, id UUID)
 	PostGroup(w http.ResponseWriter, r *http.Request)
+	PostGroupMember(w http.ResponseWriter, r *http.Request, id UUID)
+	PutGroupMember(w http.ResponseWriter, r *http.Request, id UUID, userID UUID)
+	DeleteGroupMember(w http.ResponseWriter, r *http.Request, id UUID, userID UUID)
+	ListMemberships(w http.ResponseWriter, r *http.Request)
+	PostTwoFactor(w http.ResponseWriter, r *http.Request)
+	VerifyTwoFactor(w http.ResponseWriter, r *http.Request)
+	DeleteTwoFactor(w http.ResponseWriter, r *http.Request)
+	StartOIDCLogin(w http.ResponseWriter, r *http.Request)
+	FinishOIDCLogin(w http.ResponseWriter, r *http.Request, params FinishOIDCLoginParams)
+	CreateSession(w http.ResponseWriter, r *http.Request)
+	DeleteSession(w http.ResponseWriter, r *http.Request)
 }
 
 type HostingRestAdapter interface {
 	GetInstance(w http.ResponseWriter, r *http.Request, id UUID)
+	GetInstanceQR(w http.ResponseWriter, r *http.Request, id UUID, params GetInstanceQRParams)
 	DeleteInstance(w http.ResponseWriter, r *http.Request, id UUID)
 	ListInstances(w http.ResponseWriter, r *http.Request)
 	PostInstance(w http.ResponseWriter, r *http.Request)
+	ListRoutingPresets(w http.ResponseWriter, r *http.Request)
+	ListRuleSets(w http.ResponseWriter, r *http.Request)
+	PostRuleSet(w http.ResponseWriter, r *http.Request)
+	GetRuleSet(w http.ResponseWriter, r *http.Request, id UUID)
+	PutRuleSet(w http.ResponseWriter, r *http.Request, id UUID)
+	DeleteRuleSet(w http.ResponseWriter, r *http.Request, id UUID)
+	ListInstanceHealthChecks(w http.ResponseWriter, r *http.Request, id UUID)
+	RotateInstanceCredentials(w http.ResponseWriter, r *http.Request, id UUID)
+	ListInstanceClients(w http.ResponseWriter, r *http.Request, id UUID)
+	PostProbeReports(w http.ResponseWriter, r *http.Request)
+	ListProbeRanges(w http.ResponseWriter, r *http.Request)
+	ListNotifications(w http.ResponseWriter, r *http.Request)
+	RenewInstance(w http.ResponseWriter, r *http.Request, id UUID)
+	DeleteInstanceClient(w http.ResponseWriter, r *http.Request, id UUID, userID UUID)
+	GetGroupSettings(w http.ResponseWriter, r *http.Request, id UUID)
+	PutGroupSettings(w http.ResponseWriter, r *http.Request, id UUID)
+	ListUserUsage(w http.ResponseWriter, r *http.Request, id UUID, params ListUserUsageParams)
+	ListGroupUsage(w http.ResponseWriter, r *http.Request, id UUID, params ListGroupUsageParams)
+	GetUserQuota(w http.ResponseWriter, r *http.Request, id UUID)
+	PutUserQuota(w http.ResponseWriter, r *http.Request, id UUID)
+	GetUserRateLimit(w http.ResponseWriter, r *http.Request, id UUID)
+	ListInstanceRequests(w http.ResponseWriter, r *http.Request, id UUID)
+	ListAuditEntries(w http.ResponseWriter, r *http.Request, params ListAuditEntriesParams)
+	ExplainDecision(w http.ResponseWriter, r *http.Request)
+	ApproveInstanceRequest(w http.ResponseWriter, r *http.Request, id UUID)
+	RejectInstanceRequest(w http.ResponseWriter, r *http.Request, id UUID)
+	PutUserRateLimit(w http.ResponseWriter, r *http.Request, id UUID)
+	DeleteUserSuspension(w http.ResponseWriter, r *http.Request, id UUID)
+	GetGroupCosts(w http.ResponseWriter, r *http.Request, id UUID, params GetGroupCostsParams)
 }
 
 type Server struct {
@@ -49,6 +91,10 @@ func (s *Server) PostUser(w http.ResponseWriter, r *http.Request) {
 	s.access.PostUser(w, r)
 }
 
+func (s *Server) DeleteUserLockout(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.access.DeleteUserLockout(w, r, id)
+}
+
 func (s *Server) ListUsers(w http.ResponseWriter, r *http.Request) {
 	s.access.ListUsers(w, r)
 }
@@ -57,6 +103,50 @@ func (s *Server) PostGroup(w http.ResponseWriter, r *http.Request) {
 	s.access.PostGroup(w, r)
 }
 
+func (s *Server) PostGroupMember(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.access.PostGroupMember(w, r, id)
+}
+
+func (s *Server) PutGroupMember(w http.ResponseWriter, r *http.Request, id UUID, userID UUID) {
+	s.access.PutGroupMember(w, r, id, userID)
+}
+
+func (s *Server) DeleteGroupMember(w http.ResponseWriter, r *http.Request, id UUID, userID UUID) {
+	s.access.DeleteGroupMember(w, r, id, userID)
+}
+
+func (s *Server) ListMemberships(w http.ResponseWriter, r *http.Request) {
+	s.access.ListMemberships(w, r)
+}
+
+func (s *Server) PostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	s.access.PostTwoFactor(w, r)
+}
+
+func (s *Server) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	s.access.VerifyTwoFactor(w, r)
+}
+
+func (s *Server) DeleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	s.access.DeleteTwoFactor(w, r)
+}
+
+func (s *Server) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	s.access.StartOIDCLogin(w, r)
+}
+
+func (s *Server) FinishOIDCLogin(w http.ResponseWriter, r *http.Request, params FinishOIDCLoginParams) {
+	s.access.FinishOIDCLogin(w, r, params)
+}
+
+func (s *Server) CreateSession(w http.ResponseWriter, r *http.Request) {
+	s.access.CreateSession(w, r)
+}
+
+func (s *Server) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	s.access.DeleteSession(w, r)
+}
+
 func (s *Server) PostInstance(w http.ResponseWriter, r *http.Request) {
 	s.hosting.PostInstance(w, r)
 }
@@ -65,6 +155,10 @@ func (s *Server) GetInstance(w http.ResponseWriter, r *http.Request, id UUID) {
 	s.hosting.GetInstance(w, r, id)
 }
 
+func (s *Server) GetInstanceQR(w http.ResponseWriter, r *http.Request, id UUID, params GetInstanceQRParams) {
+	s.hosting.GetInstanceQR(w, r, id, params)
+}
+
 func (s *Server) DeleteInstance(w http.ResponseWriter, r *http.Request, id UUID) {
 	s.hosting.DeleteInstance(w, r, id)
 }
@@ -72,3 +166,119 @@ func (s *Server) DeleteInstance(w http.ResponseWriter, r *http.Request, id UUID)
 func (s *Server) ListInstances(w http.ResponseWriter, r *http.Request) {
 	s.hosting.ListInstances(w, r)
 }
+
+func (s *Server) ListRoutingPresets(w http.ResponseWriter, r *http.Request) {
+	s.hosting.ListRoutingPresets(w, r)
+}
+
+func (s *Server) ListRuleSets(w http.ResponseWriter, r *http.Request) {
+	s.hosting.ListRuleSets(w, r)
+}
+
+func (s *Server) PostRuleSet(w http.ResponseWriter, r *http.Request) {
+	s.hosting.PostRuleSet(w, r)
+}
+
+func (s *Server) GetRuleSet(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.GetRuleSet(w, r, id)
+}
+
+func (s *Server) PutRuleSet(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.PutRuleSet(w, r, id)
+}
+
+func (s *Server) DeleteRuleSet(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.DeleteRuleSet(w, r, id)
+}
+
+func (s *Server) ListInstanceHealthChecks(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.ListInstanceHealthChecks(w, r, id)
+}
+
+func (s *Server) RotateInstanceCredentials(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.RotateInstanceCredentials(w, r, id)
+}
+
+func (s *Server) ListInstanceClients(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.ListInstanceClients(w, r, id)
+}
+
+func (s *Server) DeleteInstanceClient(w http.ResponseWriter, r *http.Request, id UUID, userID UUID) {
+	s.hosting.DeleteInstanceClient(w, r, id, userID)
+}
+
+func (s *Server) GetGroupSettings(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.GetGroupSettings(w, r, id)
+}
+
+func (s *Server) PutGroupSettings(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.PutGroupSettings(w, r, id)
+}
+
+func (s *Server) PostProbeReports(w http.ResponseWriter, r *http.Request) {
+	s.hosting.PostProbeReports(w, r)
+}
+
+func (s *Server) ListProbeRanges(w http.ResponseWriter, r *http.Request) {
+	s.hosting.ListProbeRanges(w, r)
+}
+
+func (s *Server) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	s.hosting.ListNotifications(w, r)
+}
+
+func (s *Server) RenewInstance(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.RenewInstance(w, r, id)
+}
+
+func (s *Server) ListUserUsage(w http.ResponseWriter, r *http.Request, id UUID, params ListUserUsageParams) {
+	s.hosting.ListUserUsage(w, r, id, params)
+}
+
+func (s *Server) ListGroupUsage(w http.ResponseWriter, r *http.Request, id UUID, params ListGroupUsageParams) {
+	s.hosting.ListGroupUsage(w, r, id, params)
+}
+
+func (s *Server) GetUserQuota(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.GetUserQuota(w, r, id)
+}
+
+func (s *Server) PutUserQuota(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.PutUserQuota(w, r, id)
+}
+
+func (s *Server) ListAuditEntries(w http.ResponseWriter, r *http.Request, params ListAuditEntriesParams) {
+	s.hosting.ListAuditEntries(w, r, params)
+}
+
+func (s *Server) ExplainDecision(w http.ResponseWriter, r *http.Request) {
+	s.hosting.ExplainDecision(w, r)
+}
+
+func (s *Server) ListInstanceRequests(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.ListInstanceRequests(w, r, id)
+}
+
+func (s *Server) ApproveInstanceRequest(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.ApproveInstanceRequest(w, r, id)
+}
+
+func (s *Server) RejectInstanceRequest(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.RejectInstanceRequest(w, r, id)
+}
+
+func (s *Server) GetUserRateLimit(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.GetUserRateLimit(w, r, id)
+}
+
+func (s *Server) PutUserRateLimit(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.PutUserRateLimit(w, r, id)
+}
+
+func (s *Server) DeleteUserSuspension(w http.ResponseWriter, r *http.Request, id UUID) {
+	s.hosting.DeleteUserSuspension(w, r, id)
+}
+
+func (s *Server) GetGroupCosts(w http.ResponseWriter, r *http.Request, id UUID, params GetGroupCostsParams) {
+	s.hosting.GetGroupCosts(w, r, id, params)
+}
@@ -0,0 +1,68 @@
+// mockoidc runs a mock OpenID provider, to try the login through an identity
+// provider locally. Every login succeeds, as the user with the MOCK_OIDC_CLAIMS.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"vpainless/pkg/oidc/oidctest"
+)
+
+func main() {
+	addr := getenv("MOCK_OIDC_ADDR", "127.0.0.1:8081")
+	server, err := oidctest.New(
+		getenv("MOCK_OIDC_ISSUER", "http://"+addr),
+		getenv("MOCK_OIDC_CLIENT_ID", "vpainless"),
+		getenv("MOCK_OIDC_CLIENT_SECRET", "secret"),
+	)
+	if err != nil {
+		slog.Error("unable to create the mock provider", "error", err)
+		os.Exit(1)
+	}
+
+	if v := os.Getenv("MOCK_OIDC_CLAIMS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &server.Claims); err != nil {
+			slog.Error("invalid MOCK_OIDC_CLAIMS environment variable", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	s := &http.Server{
+		Handler: server,
+		Addr:    addr,
+	}
+
+	go func() {
+		slog.Info("starting mock oidc provider", "addr", addr, "issuer", server.Issuer, "claims", server.Claims)
+		err := s.ListenAndServe()
+		if err != http.ErrServerClosed {
+			slog.Error("server listen error", "err", err)
+			os.Exit(1)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		slog.Warn("error shutting down server", "error", err)
+	}
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -45,6 +45,54 @@ func (s *MockServer) PutUser(w http.ResponseWriter, r *http.Request, id uuid.UUI
 	panic("not implemented")
 }
 
+func (s *MockServer) DeleteUserLockout(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListMemberships(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PostGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PutGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) FinishOIDCLogin(w http.ResponseWriter, r *http.Request, params api.FinishOIDCLoginParams) {
+	panic("not implemented")
+}
+
+func (s *MockServer) CreateSession(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
 func (s *MockServer) PostUser(w http.ResponseWriter, r *http.Request) {
 	var req api.PostUserJSONRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -156,6 +204,10 @@ func (s *MockServer) GetInstance(w http.ResponseWriter, r *http.Request, id uuid
 	writeJSON(w, http.StatusOK, result)
 }
 
+func (s *MockServer) GetInstanceQR(w http.ResponseWriter, r *http.Request, id uuid.UUID, params api.GetInstanceQRParams) {
+	panic("not implemented")
+}
+
 func (s *MockServer) ListInstances(w http.ResponseWriter, r *http.Request) {
 	oneInstances(w, r)
 	// zeroInstances(w, r)
@@ -195,3 +247,119 @@ func (s *MockServer) DeleteInstance(w http.ResponseWriter, r *http.Request, id u
 	time.Sleep(5 * time.Second)
 	writeJSON(w, http.StatusNoContent, nil)
 }
+
+func (s *MockServer) ListRoutingPresets(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListRuleSets(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PostRuleSet(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) GetRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PutRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListInstanceHealthChecks(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) RotateInstanceCredentials(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListInstanceClients(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteInstanceClient(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) GetGroupSettings(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PutGroupSettings(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PostProbeReports(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListProbeRanges(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) RenewInstance(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListUserUsage(w http.ResponseWriter, r *http.Request, id api.UUID, params api.ListUserUsageParams) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListGroupUsage(w http.ResponseWriter, r *http.Request, id api.UUID, params api.ListGroupUsageParams) {
+	panic("not implemented")
+}
+
+func (s *MockServer) GetUserQuota(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PutUserQuota(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListAuditEntries(w http.ResponseWriter, r *http.Request, params api.ListAuditEntriesParams) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ExplainDecision(w http.ResponseWriter, r *http.Request) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ListInstanceRequests(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) ApproveInstanceRequest(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) RejectInstanceRequest(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) GetUserRateLimit(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) PutUserRateLimit(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) DeleteUserSuspension(w http.ResponseWriter, r *http.Request, id api.UUID) {
+	panic("not implemented")
+}
+
+func (s *MockServer) GetGroupCosts(w http.ResponseWriter, r *http.Request, id api.UUID, params api.GetGroupCostsParams) {
+	panic("not implemented")
+}
@@ -0,0 +1,262 @@
+// Probe is an agent that runs inside the country, checks if the instances are
+// reachable, and reports back to vpainless. Instances are probed the way
+// clients connect to them, so the reports show if they are blocked.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/pkg/log"
+	"vpainless/pkg/tlsprobe"
+)
+
+const probeTimeout = 10 * time.Second
+
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	// Token is sent as a Bearer token instead of the username and password, for
+	// the users with two factor authentication. TokenFile is read before every
+	// run instead, so the token can be renewed without restarting the agent.
+	Token     string
+	TokenFile string
+	Interval  time.Duration
+}
+
+func loadConfig() (*Config, error) {
+	baseURL := os.Getenv("VPAINLESS_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("missing vpainless url, set VPAINLESS_URL environment variable")
+	}
+
+	token := os.Getenv("VPAINLESS_TOKEN")
+	tokenFile := os.Getenv("VPAINLESS_TOKEN_FILE")
+	username := os.Getenv("VPAINLESS_USERNAME")
+	password := os.Getenv("VPAINLESS_PASSWORD")
+	if token == "" && tokenFile == "" {
+		if username == "" {
+			return nil, fmt.Errorf("missing username, set VPAINLESS_USERNAME, VPAINLESS_TOKEN or VPAINLESS_TOKEN_FILE environment variable")
+		}
+
+		if password == "" {
+			return nil, fmt.Errorf("missing password, set VPAINLESS_PASSWORD environment variable")
+		}
+	}
+
+	interval := 5 * time.Minute
+	if v := os.Getenv("PROBE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROBE_INTERVAL environment variable: %w", err)
+		}
+		interval = d
+	}
+
+	return &Config{
+		URL:       strings.TrimSuffix(baseURL, "/"),
+		Username:  username,
+		Password:  password,
+		Token:     token,
+		TokenFile: tokenFile,
+		Interval:  interval,
+	}, nil
+}
+
+func main() {
+	logger := slog.New(&log.CustomHandler{
+		Handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	})
+	slog.SetDefault(logger)
+
+	config, err := loadConfig()
+	if err != nil {
+		slog.Error("unable to load configs", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	agent := &Agent{config: config, client: &http.Client{Timeout: 30 * time.Second}}
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := agent.Run(ctx); err != nil {
+			slog.ErrorContext(ctx, "error probing instances", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Good Bye!")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type Agent struct {
+	config *Config
+	client *http.Client
+}
+
+// Run probes all the instances visible to the agent user, and reports the results.
+func (a *Agent) Run(ctx context.Context) error {
+	var instances []api.Instance
+	if err := a.do(ctx, http.MethodGet, "/api/instances", nil, &instances); err != nil {
+		return fmt.Errorf("error listing instances: %w", err)
+	}
+
+	var reports []api.ProbeReport
+	for _, instance := range instances {
+		if instance.Id == nil || instance.Ip == nil || instance.Status == nil {
+			continue
+		}
+		// Instances that are not set up yet are not worth probing.
+		switch *instance.Status {
+		case api.Ok, api.Degraded, api.Blocked:
+		default:
+			continue
+		}
+
+		report := probe(ctx, instance)
+		slog.InfoContext(ctx, "probed instance", "instance_id", report.InstanceId, "tcp", *report.Tcp, "tls", *report.Tls, "error", report.Error)
+		reports = append(reports, report)
+	}
+
+	if len(reports) == 0 {
+		return nil
+	}
+
+	if err := a.do(ctx, http.MethodPost, "/api/probes/reports", reports, nil); err != nil {
+		return fmt.Errorf("error submitting reports: %w", err)
+	}
+	return nil
+}
+
+func (a *Agent) do(ctx context.Context, method, path string, body, result any) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.config.URL+path, &buf)
+	if err != nil {
+		return err
+	}
+	token, err := a.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.SetBasicAuth(a.config.Username, a.config.Password)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// token returns the Bearer token of the agent, if it is configured with one.
+func (a *Agent) token() (string, error) {
+	if a.config.TokenFile == "" {
+		return a.config.Token, nil
+	}
+
+	b, err := os.ReadFile(a.config.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading token file: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// probe connects to the instance like a client does; a tcp connection to port 443
+// and a tls handshake with the reality destination as the SNI.
+func probe(ctx context.Context, instance api.Instance) api.ProbeReport {
+	tcp, tls := false, false
+	report := api.ProbeReport{InstanceId: *instance.Id, Tcp: &tcp, Tls: &tls}
+
+	var errs []error
+	addr := net.JoinHostPort(*instance.Ip, "443")
+	dialer := &net.Dialer{Timeout: probeTimeout}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("tcp: %w", err))
+	} else {
+		tcp = true
+		latency := int(time.Since(start).Milliseconds())
+		report.LatencyMs = &latency
+		conn.Close()
+	}
+
+	err = tlsprobe.Probe(ctx, serverName(instance), tlsprobe.Options{
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		Timeout: probeTimeout,
+	})
+	// The destination's support of h2 and tls 1.3 is not the concern of the
+	// probe, a completed handshake is enough to tell it is not blocked.
+	if err != nil && !errors.Is(err, tlsprobe.ErrTLSVersion) && !errors.Is(err, tlsprobe.ErrALPN) {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	} else {
+		tls = true
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		msg := err.Error()
+		report.Error = &msg
+	}
+	return report
+}
+
+// serverName returns the reality destination of the instance from its connection string.
+func serverName(instance api.Instance) string {
+	const fallback = "www.speedtest.net"
+	if instance.ConnectionString == nil {
+		return fallback
+	}
+
+	u, err := url.Parse(*instance.ConnectionString)
+	if err != nil {
+		return fallback
+	}
+
+	if sni := u.Query().Get("sni"); sni != "" {
+		return sni
+	}
+	return fallback
+}
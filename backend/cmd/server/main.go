@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,11 +12,14 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"vpainless/api"
 	hostingAdapter "vpainless/internal/access/adapter/hosting"
+	oidcAdapter "vpainless/internal/access/adapter/oidc"
 	accessRest "vpainless/internal/access/adapter/rest"
 	accessStorage "vpainless/internal/access/adapter/storage"
 	access "vpainless/internal/access/core"
@@ -27,6 +31,9 @@ import (
 	internaldb "vpainless/internal/pkg/db"
 	"vpainless/internal/pkg/log"
 	"vpainless/pkg/middleware"
+	"vpainless/pkg/oidc"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 //go:embed default/startup.sh
@@ -37,6 +44,78 @@ type Config struct {
 	DBDir               string
 	VpainlessPrivateKey string
 	VpainlessPublicKey  string
+	HealthCheckInterval time.Duration
+	SchedulerInterval   time.Duration
+	UsageInterval       time.Duration
+	// PolicyPath is an optional policy directory or bundle overriding the embedded policies.
+	PolicyPath string
+	// OIDC is the optional identity provider users can log in through.
+	OIDC *OIDCConfig
+	// TrustProxyHeaders trusts the client address set by the reverse proxy in
+	// the X-Real-IP header. It should only be set behind one.
+	TrustProxyHeaders bool
+}
+
+type OIDCConfig struct {
+	oidc.Config
+	UsernameClaim string
+	GroupsClaim   string
+	// Groups maps the groups of the provider to the groups users are provisioned into.
+	Groups map[string]access.GroupID
+}
+
+// loadOIDCConfig loads the identity provider from the OIDC_* environment
+// variables. It is disabled unless OIDC_ISSUER is set.
+func loadOIDCConfig() (*OIDCConfig, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	config := &OIDCConfig{
+		Config: oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       []string{"profile", "email"},
+		},
+		UsernameClaim: oidcAdapter.DefaultUsernameClaim,
+		GroupsClaim:   oidcAdapter.DefaultGroupsClaim,
+		Groups:        map[string]access.GroupID{},
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("missing oidc client, set OIDC_CLIENT_ID and OIDC_REDIRECT_URL environment variables")
+	}
+
+	if v := os.Getenv("OIDC_SCOPES"); v != "" {
+		config.Scopes = strings.Fields(v)
+	}
+	if v := os.Getenv("OIDC_USERNAME_CLAIM"); v != "" {
+		config.UsernameClaim = v
+	}
+	if v := os.Getenv("OIDC_GROUPS_CLAIM"); v != "" {
+		config.GroupsClaim = v
+	}
+
+	// OIDC_GROUPS is a comma separated list of <provider group>=<group id>. The
+	// users with none of the groups are provisioned into the group of *, if any.
+	if v := os.Getenv("OIDC_GROUPS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			name, id, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid OIDC_GROUPS environment variable: %q is not <group>=<id>", pair)
+			}
+
+			groupID, err := uuid.FromString(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OIDC_GROUPS environment variable: %w", err)
+			}
+			config.Groups[name] = access.GroupID{UUID: groupID}
+		}
+	}
+
+	return config, nil
 }
 
 func loadConfig() (*Config, error) {
@@ -60,7 +139,53 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("missing path to the public key, set the VPAINLESS_PUBLIC_KEY environment variable")
 	}
 
+	healthCheckInterval := 5 * time.Minute
+	if v := os.Getenv("HEALTH_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HEALTH_CHECK_INTERVAL environment variable: %w", err)
+		}
+		healthCheckInterval = d
+	}
+
+	schedulerInterval := 10 * time.Minute
+	if v := os.Getenv("SCHEDULER_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCHEDULER_INTERVAL environment variable: %w", err)
+		}
+		schedulerInterval = d
+	}
+
+	usageInterval := 15 * time.Minute
+	if v := os.Getenv("USAGE_COLLECTION_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid USAGE_COLLECTION_INTERVAL environment variable: %w", err)
+		}
+		usageInterval = d
+	}
+
+	oidcConfig, err := loadOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	trustProxyHeaders := false
+	if v := os.Getenv("TRUST_PROXY_HEADERS"); v != "" {
+		trustProxyHeaders, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUST_PROXY_HEADERS environment variable: %w", err)
+		}
+	}
+
 	return &Config{
+		OIDC:                oidcConfig,
+		TrustProxyHeaders:   trustProxyHeaders,
+		HealthCheckInterval: healthCheckInterval,
+		SchedulerInterval:   schedulerInterval,
+		UsageInterval:       usageInterval,
+		PolicyPath:          os.Getenv("POLICY_PATH"),
 		MigrationsPath:      migrationsPath,
 		DBDir:               dbDir,
 		VpainlessPrivateKey: privateKeyPath,
@@ -124,8 +249,29 @@ func main() {
 	accessRepository := accessStorage.NewRepository(accessDB)
 	accessService := access.NewService(accessRepository, adapter)
 	accessRestAdapter := accessRest.NewAdapter(accessService)
+
+	if config.OIDC != nil {
+		provider, err := oidc.Discover(context.Background(), http.DefaultClient, config.OIDC.Config)
+		if err != nil {
+			slog.Error("unable to discover the identity provider", "issuer", config.OIDC.Issuer, "error", err)
+			os.Exit(1)
+		}
+
+		identities := oidcAdapter.NewAdapter(provider)
+		identities.UsernameClaim = config.OIDC.UsernameClaim
+		identities.GroupsClaim = config.OIDC.GroupsClaim
+		accessService.UseIdentityProvider(identities, config.OIDC.Groups)
+	}
 	apiServer := api.NewServer(accessRestAdapter, hostingRestAdapter)
 
+	// Decisions are logged as json lines on stdout, apart from the logs on stderr.
+	decisions := authz.NewDecisionLogger(os.Stdout)
+	accessService.LogDecisions(decisions)
+	if err := hostingService.LogDecisions(context.Background(), decisions); err != nil {
+		slog.Error("unable to enable decision logs", "error", err)
+		os.Exit(1)
+	}
+
 	r := http.NewServeMux()
 
 	handler := api.HandlerWithOptions(apiServer, api.StdHTTPServerOptions{
@@ -134,15 +280,56 @@ func main() {
 		Middlewares: []api.MiddlewareFunc{
 			api.MiddlewareFunc(authz.AuthenticationMiddleware(accessService, []middleware.Exclusion{
 				{PathPrefix: "/api/users", Method: "POST"},
+				{PathPrefix: "/api/auth/oidc", Method: "GET"},
 			})),
 			api.MiddlewareFunc(middleware.BasicAuthMiddleware([]middleware.Exclusion{
 				{PathPrefix: "/api/users", Method: "POST"},
+				{PathPrefix: "/api/auth/oidc", Method: "GET"},
 			})),
 			api.MiddlewareFunc(middleware.RequestIDMiddleware),
+			api.MiddlewareFunc(middleware.ClientIPMiddleware(config.TrustProxyHeaders)),
 		},
 	})
 
-	startServer(context.Background(), logger, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if config.PolicyPath != "" {
+		// Both modules check the set before either loads it, so a bad set leaves
+		// all policies as they were.
+		reload := func(ctx context.Context, modules map[string]string) error {
+			if err := errors.Join(accessService.CheckPolicies(ctx, modules), hostingService.CheckPolicies(ctx, modules)); err != nil {
+				return err
+			}
+			return errors.Join(accessService.ReloadPolicies(ctx, modules), hostingService.ReloadPolicies(ctx, modules))
+		}
+
+		modules, err := authz.LoadModules(config.PolicyPath)
+		if err == nil {
+			err = reload(ctx, modules)
+		}
+		if err != nil {
+			slog.Error("unable to load policies", "path", config.PolicyPath, "error", err)
+			os.Exit(1)
+		}
+
+		go func() {
+			err := authz.WatchModules(ctx, config.PolicyPath, func(ctx context.Context, modules map[string]string) {
+				if err := reload(ctx, modules); err != nil {
+					slog.ErrorContext(ctx, "refused policies, keeping the last loaded ones", "path", config.PolicyPath, "error", err)
+				}
+			})
+			if err != nil {
+				slog.Error("unable to watch policies", "path", config.PolicyPath, "error", err)
+			}
+		}()
+	}
+
+	go hostingService.MonitorHealth(ctx, config.HealthCheckInterval)
+	go hostingService.ScheduleInstances(ctx, config.SchedulerInterval)
+	go hostingService.CollectUsage(ctx, config.UsageInterval)
+
+	startServer(ctx, logger, handler)
 	logger.Info("Good Bye!")
 }
 
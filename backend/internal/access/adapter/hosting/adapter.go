@@ -11,6 +11,8 @@ import (
 
 type groupService interface {
 	SaveGroup(ctx context.Context, g *hosting.Group) error
+	RecordAudit(ctx context.Context, entry *hosting.AuditEntry) error
+	RequiresTwoFactor(ctx context.Context, id hosting.GroupID) (bool, error)
 }
 
 type Adapter struct {
@@ -37,3 +39,21 @@ func (a *Adapter) NotifyGroupCreated(ctx context.Context, g *core.Group) error {
 		},
 	})
 }
+
+func (a *Adapter) RecordAudit(ctx context.Context, e *core.AuditEvent) error {
+	diff := make(map[string]hosting.AuditChange, len(e.Diff))
+	for field, change := range e.Diff {
+		diff[field] = hosting.AuditChange{Old: change.Old, New: change.New}
+	}
+
+	return a.service.RecordAudit(ctx, &hosting.AuditEntry{
+		GroupID: hosting.GroupID{UUID: e.GroupID.UUID},
+		Action:  hosting.AuditAction(e.Action),
+		Target:  e.Target,
+		Diff:    diff,
+	})
+}
+
+func (a *Adapter) RequiresTwoFactor(ctx context.Context, id core.GroupID) (bool, error) {
+	return a.service.RequiresTwoFactor(ctx, hosting.GroupID{UUID: id.UUID})
+}
@@ -0,0 +1,66 @@
+package oidc
+
+import (
+	"context"
+	"slices"
+
+	"vpainless/internal/access/core"
+	"vpainless/pkg/oidc"
+)
+
+const (
+	DefaultUsernameClaim = "preferred_username"
+	DefaultGroupsClaim   = "groups"
+)
+
+// Adapter maps the claims of the id tokens of an OpenID provider to identities.
+type Adapter struct {
+	provider *oidc.Provider
+	// UsernameClaim and GroupsClaim are the claims the username and the groups
+	// of the users are taken from.
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+func NewAdapter(provider *oidc.Provider) *Adapter {
+	return &Adapter{
+		provider:      provider,
+		UsernameClaim: DefaultUsernameClaim,
+		GroupsClaim:   DefaultGroupsClaim,
+	}
+}
+
+func (a *Adapter) AuthCodeURL(state, nonce string) string {
+	return a.provider.AuthCodeURL(state, nonce)
+}
+
+func (a *Adapter) Exchange(ctx context.Context, code, nonce string) (string, *core.Identity, error) {
+	token, claims, err := a.provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, a.identity(claims), nil
+}
+
+func (a *Adapter) Verify(ctx context.Context, token string) (*core.Identity, error) {
+	claims, err := a.provider.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.identity(claims), nil
+}
+
+// identity maps the claims to an identity. Users logged in with a second factor
+// when the authentication methods of the token include mfa or otp.
+func (a *Adapter) identity(claims *oidc.Claims) *core.Identity {
+	methods := claims.Strings("amr")
+	return &core.Identity{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Username: claims.String(a.UsernameClaim),
+		Groups:   claims.Strings(a.GroupsClaim),
+		MFA:      slices.Contains(methods, "mfa") || slices.Contains(methods, "otp"),
+	}
+}
@@ -0,0 +1,58 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+
+	"vpainless/internal/access/core"
+	"vpainless/pkg/oidc"
+	"vpainless/pkg/oidc/oidctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapter(t *testing.T) {
+	server, ts, err := oidctest.NewTestServer("vpainless", "secret")
+	require.NoError(t, err, "should start the mock provider")
+	defer ts.Close()
+
+	ctx := context.Background()
+	provider, err := oidc.Discover(ctx, ts.Client(), oidc.Config{
+		Issuer:       server.Issuer,
+		ClientID:     "vpainless",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+	})
+	require.NoError(t, err, "should discover the mock provider")
+
+	adapter := NewAdapter(provider)
+	adapter.GroupsClaim = "roles"
+
+	code := server.Code("http://localhost:8080/api/auth/oidc/callback", "nonce", map[string]any{
+		"sub":                "42",
+		"preferred_username": "john",
+		"roles":              []string{"family", "friends"},
+		"amr":                []string{"pwd", "otp"},
+	})
+	token, identity, err := adapter.Exchange(ctx, code, "nonce")
+	require.NoError(t, err, "should exchange the code without any error")
+	require.Equal(t, &core.Identity{
+		Issuer:   server.Issuer,
+		Subject:  "42",
+		Username: "john",
+		Groups:   []string{"family", "friends"},
+		MFA:      true,
+	}, identity, "should map the claims to the identity")
+
+	verified, err := adapter.Verify(ctx, token)
+	require.NoError(t, err, "should verify the token without any error")
+	require.Equal(t, identity, verified, "should map the token to the same identity")
+
+	token, err = server.Sign(map[string]any{"sub": "43", "amr": "pwd"})
+	require.NoError(t, err)
+	identity, err = adapter.Verify(ctx, token)
+	require.NoError(t, err, "should verify the token without any error")
+	require.False(t, identity.MFA, "should not count passwords as a second factor")
+	require.Empty(t, identity.Username)
+	require.Empty(t, identity.Groups)
+}
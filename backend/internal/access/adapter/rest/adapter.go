@@ -10,6 +10,10 @@ import (
 type AccessService interface {
 	userService
 	groupService
+	membershipService
+	twoFactorService
+	loginService
+	sessionService
 }
 
 type Adapter struct {
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"vpainless/api"
+	"vpainless/internal/access/core"
+)
+
+// loginCookie keeps the state and the nonce of a login through the identity
+// provider, until the provider sends the user back.
+const loginCookie = "vpainless_oidc"
+
+type loginService interface {
+	StartLogin(ctx context.Context) (url, state, nonce string, err error)
+	FinishLogin(ctx context.Context, code, nonce string) (*core.Login, error)
+}
+
+func (a *Adapter) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	url, state, nonce, err := a.service.StartLogin(ctx)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, core.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		slog.ErrorContext(ctx, "error starting login", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    state + "." + nonce,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (a *Adapter) FinishOIDCLogin(w http.ResponseWriter, r *http.Request, params api.FinishOIDCLoginParams) {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("no login was started"))
+		return
+	}
+
+	state, nonce, ok := strings.Cut(cookie.Value, ".")
+	if !ok || state != params.State {
+		writeJSONError(w, http.StatusBadRequest, errors.New("the login was not started by this client"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	ctx := r.Context()
+	login, err := a.service.FinishLogin(ctx, params.Code, nonce)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrAlreadyExists):
+			status = http.StatusConflict
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, core.ErrForbidden):
+			status = http.StatusForbidden
+		}
+		slog.ErrorContext(ctx, "error finishing login", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.OIDCLogin{
+		Token: toPointer(login.Token),
+		User: &api.User{
+			Id:       toPointer(login.User.ID.UUID),
+			Username: toPointer(login.User.Username),
+			Role:     toPointer(api.Role(login.User.Role)),
+			GroupId:  toUUIDPointer(login.User.GroupID.UUID),
+		},
+	})
+}
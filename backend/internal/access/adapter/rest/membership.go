@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/access/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type membershipService interface {
+	ListMemberships(ctx context.Context) ([]*core.Membership, error)
+	AddMember(ctx context.Context, group core.GroupID, username string, role core.Role) (*core.Membership, error)
+	UpdateMember(ctx context.Context, group core.GroupID, id core.UserID, role core.Role) (*core.Membership, error)
+	RemoveMember(ctx context.Context, group core.GroupID, id core.UserID) error
+}
+
+func (a *Adapter) ListMemberships(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	memberships, err := a.service.ListMemberships(ctx)
+	if err != nil {
+		if errors.Is(err, core.ErrUnauthorized) {
+			writeJSONError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		slog.ErrorContext(ctx, "error listing memberships", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := make([]api.Membership, 0, len(memberships))
+	for _, m := range memberships {
+		result = append(result, mapMembership(m))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) PostGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req api.PostGroupMemberJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	role, err := mapCoreRole(&req.Role)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	membership, err := a.service.AddMember(ctx, core.GroupID{UUID: id}, req.Username, role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrAlreadyExists):
+			status = http.StatusConflict
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error adding member", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapMembership(membership))
+}
+
+func (a *Adapter) PutGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	var req api.PutGroupMemberJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	role, err := mapCoreRole(&req.Role)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	membership, err := a.service.UpdateMember(ctx, core.GroupID{UUID: id}, core.UserID{UUID: userID}, role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error updating member", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapMembership(membership))
+}
+
+func (a *Adapter) DeleteGroupMember(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	ctx := r.Context()
+	if err := a.service.RemoveMember(ctx, core.GroupID{UUID: id}, core.UserID{UUID: userID}); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error removing member", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func mapMembership(m *core.Membership) api.Membership {
+	return api.Membership{
+		UserId:    toPointer(m.UserID.UUID),
+		GroupId:   toPointer(m.GroupID.UUID),
+		Role:      toPointer(api.Role(m.Role)),
+		Username:  toPointer(m.Username),
+		GroupName: toPointer(m.GroupName),
+	}
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/access/core"
+	"vpainless/pkg/middleware"
+)
+
+type sessionService interface {
+	CreateSession(ctx context.Context, creds middleware.Credentials) (string, *core.Session, error)
+	DeleteSession(ctx context.Context, token string) error
+}
+
+func (a *Adapter) CreateSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	creds, err := middleware.GetCreds(ctx)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	token, session, err := a.service.CreateSession(ctx, creds)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error creating session", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, api.Session{
+		Token:     toPointer(token),
+		ExpiresAt: toPointer(session.ExpiresAt),
+	})
+}
+
+func (a *Adapter) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	creds, err := middleware.GetCreds(ctx)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := a.service.DeleteSession(ctx, creds.Token); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error deleting session", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/access/core"
+)
+
+type twoFactorService interface {
+	EnrollTwoFactor(ctx context.Context) (*core.TwoFactorEnrollment, error)
+	VerifyTwoFactor(ctx context.Context, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context) error
+}
+
+func (a *Adapter) PostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	enrollment, err := a.service.EnrollTwoFactor(ctx)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrAlreadyExists):
+			status = http.StatusConflict
+		case errors.Is(err, core.ErrNotFound), errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error enrolling two factor", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, api.TwoFactorEnrollment{
+		Secret: toPointer(enrollment.Secret),
+		Uri:    toPointer(enrollment.URI),
+	})
+}
+
+func (a *Adapter) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req api.VerifyTwoFactorJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	codes, err := a.service.VerifyTwoFactor(ctx, req.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrAlreadyExists):
+			status = http.StatusConflict
+		case errors.Is(err, core.ErrBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error verifying two factor", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.RecoveryCodes{RecoveryCodes: &codes})
+}
+
+func (a *Adapter) DeleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := a.service.DisableTwoFactor(ctx); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error disabling two factor", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
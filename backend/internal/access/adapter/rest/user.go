@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"vpainless/api"
 	"vpainless/internal/access/core"
@@ -20,6 +22,7 @@ type userService interface {
 	UpdateUser(ctx context.Context, user *core.User) (*core.User, error)
 	CreateUser(ctx context.Context, user *core.User) (*core.User, error)
 	ListUsers(ctx context.Context) ([]*core.User, error)
+	UnlockUser(ctx context.Context, id core.UserID) error
 }
 
 func (a *Adapter) GetMe(w http.ResponseWriter, r *http.Request) {
@@ -44,11 +47,12 @@ func (a *Adapter) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The group and the role are of the group the request acts in.
 	writeJSON(w, http.StatusOK, api.User{
 		Id:       toPointer(user.ID.UUID),
 		Username: toPointer(user.Username),
-		Role:     toPointer(api.UserRole(user.Role)),
-		GroupId:  toUUIDPointer(user.GroupID.UUID),
+		Role:     toPointer(api.Role(principal.Role)),
+		GroupId:  toUUIDPointer(principal.GroupID),
 	})
 }
 
@@ -71,7 +75,7 @@ func (a *Adapter) GetUser(w http.ResponseWriter, r *http.Request, id uuid.UUID)
 	writeJSON(w, http.StatusOK, api.User{
 		Id:       toPointer(user.ID.UUID),
 		Username: toPointer(user.Username),
-		Role:     toPointer(api.UserRole(user.Role)),
+		Role:     toPointer(api.Role(user.Role)),
 		GroupId:  toUUIDPointer(user.GroupID.UUID),
 	})
 }
@@ -91,14 +95,20 @@ func mapCoreUser(apiUser api.User) (core.User, error) {
 	}, nil
 }
 
-func mapCoreRole(role *api.UserRole) (core.Role, error) {
+func mapCoreRole(role *api.Role) (core.Role, error) {
 	if role == nil {
 		return core.Client, nil
 	}
 
 	switch *role {
+	case api.Owner:
+		return core.Owner, nil
 	case api.Admin:
 		return core.Admin, nil
+	case api.Operator:
+		return core.Operator, nil
+	case api.Viewer:
+		return core.Viewer, nil
 	case api.Client:
 		return core.Client, nil
 	default:
@@ -142,7 +152,7 @@ func (a *Adapter) PutUser(w http.ResponseWriter, r *http.Request, id uuid.UUID)
 		Id:       toPointer(u.ID.UUID),
 		GroupId:  toUUIDPointer(u.GroupID.UUID),
 		Username: toPointer(u.Username),
-		Role:     toPointer(api.UserRole(u.Role)),
+		Role:     toPointer(api.Role(u.Role)),
 	})
 }
 
@@ -163,6 +173,7 @@ func (a *Adapter) PostUser(w http.ResponseWriter, r *http.Request) {
 	u, err := a.service.CreateUser(ctx, &user)
 	status := http.StatusCreated
 	if err != nil {
+		var lockout *authz.LockoutError
 		s := http.StatusInternalServerError
 		switch {
 		case errors.Is(err, core.ErrNotFound):
@@ -171,6 +182,9 @@ func (a *Adapter) PostUser(w http.ResponseWriter, r *http.Request) {
 			s = http.StatusBadRequest
 		case errors.Is(err, core.ErrUnauthorized):
 			s = http.StatusUnauthorized
+		case errors.As(err, &lockout):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+			s = http.StatusTooManyRequests
 		}
 		if !errors.Is(err, core.ErrAlreadyExists) {
 			slog.ErrorContext(ctx, "error creating user", "error", err)
@@ -185,10 +199,28 @@ func (a *Adapter) PostUser(w http.ResponseWriter, r *http.Request) {
 		Id:       toPointer(u.ID.UUID),
 		GroupId:  toUUIDPointer(u.GroupID.UUID),
 		Username: toPointer(u.Username),
-		Role:     toPointer(api.UserRole(u.Role)),
+		Role:     toPointer(api.Role(u.Role)),
 	})
 }
 
+func (a *Adapter) DeleteUserLockout(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	if err := a.service.UnlockUser(ctx, core.UserID{UUID: id}); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		}
+		slog.ErrorContext(ctx, "error unlocking user", "error", err)
+		writeJSONError(w, status, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (a *Adapter) ListUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	users, err := a.service.ListUsers(ctx)
@@ -209,7 +241,7 @@ func (a *Adapter) ListUsers(w http.ResponseWriter, r *http.Request) {
 			Id:       toPointer(u.ID.UUID),
 			GroupId:  toUUIDPointer(u.GroupID.UUID),
 			Username: toPointer(u.Username),
-			Role:     toPointer(api.UserRole(u.Role)),
+			Role:     toPointer(api.Role(u.Role)),
 		})
 	}
 
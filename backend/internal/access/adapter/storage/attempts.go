@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) GetAttempts(ctx context.Context, key string) (*core.Attempts, error) {
+	var result core.Attempts
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select key, failures, locked_until, updated_at
+			from login_attempts
+			where key = ?;
+		`, key)
+		query, args := qb.SQL()
+
+		var (
+			lockedUntil sql.NullString
+			updatedAt   string
+		)
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&result.Key, &result.Failures, &lockedUntil, &updatedAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrNotFound
+			}
+			return err
+		}
+
+		var err error
+		if lockedUntil.Valid {
+			result.LockedUntil, err = time.Parse(time.DateTime, lockedUntil.String)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.UpdatedAt, err = time.Parse(time.DateTime, updatedAt)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *Repository) SaveAttempts(ctx context.Context, attempts *core.Attempts) error {
+	var lockedUntil *string
+	if !attempts.LockedUntil.IsZero() {
+		v := attempts.LockedUntil.UTC().Format(time.DateTime)
+		lockedUntil = &v
+	}
+
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into login_attempts (key, failures, locked_until, updated_at)
+			values (?, ?, ?, ?)
+			on conflict (key) do update set
+				failures = excluded.failures,
+				locked_until = excluded.locked_until,
+				updated_at = excluded.updated_at;
+		`, attempts.Key, attempts.Failures, lockedUntil, attempts.UpdatedAt.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) DeleteAttempts(ctx context.Context, key string) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		_, err := tx.ExecContext(ctx, `delete from login_attempts where key = ?;`, key)
+		return err
+	})
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/access/core"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_Delete_Attempts() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	repo := NewRepository(s.db)
+	_, err := repo.GetAttempts(ctx, "user:john")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find keys without failed attempts")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	attempts := &core.Attempts{Key: "user:john", Failures: 1, UpdatedAt: now}
+	s.Require().NoError(repo.SaveAttempts(ctx, attempts), "should save attempts without any error")
+
+	actual, err := repo.GetAttempts(ctx, "user:john")
+	s.Require().NoError(err, "should get attempts without any error")
+	s.Require().Equal(attempts, actual, "unlocked keys should have no lockout")
+
+	attempts.Failures = 5
+	attempts.LockedUntil = now.Add(time.Minute)
+	s.Require().NoError(repo.SaveAttempts(ctx, attempts), "should update attempts without any error")
+
+	actual, err = repo.GetAttempts(ctx, "user:john")
+	s.Require().NoError(err, "should get attempts without any error")
+	s.Require().Equal(attempts, actual)
+
+	s.Require().NoError(repo.DeleteAttempts(ctx, "user:john"), "should delete attempts without any error")
+	_, err = repo.GetAttempts(ctx, "user:john")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find deleted attempts")
+}
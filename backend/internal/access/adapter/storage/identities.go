@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) FindIdentity(ctx context.Context, issuer, subject string) (core.UserID, error) {
+	var id core.UserID
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`select user_id from identities where issuer = ? and subject = ?;`, issuer, subject)
+		query, args := qb.SQL()
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return core.UserID{}, err
+	}
+
+	return id, nil
+}
+
+func (r *Repository) SaveIdentity(ctx context.Context, issuer, subject string, user core.UserID) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into identities (issuer, subject, user_id) values (?, ?, ?)
+			on conflict (issuer, subject) do update set
+				user_id = excluded.user_id;
+		`, issuer, subject, user)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+
+	"vpainless/internal/access/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Find_Identity() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	userID := core.UserID{UUID: uuid.FromStringOrNil("22222222-0000-0000-0000-000000000000")}
+
+	repo := NewRepository(s.db)
+	_, err := repo.FindIdentity(ctx, "https://sso.example.com", "42")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find unlinked identities")
+
+	s.Require().NoError(repo.SaveIdentity(ctx, "https://sso.example.com", "42", userID), "should save identity without any error")
+
+	actual, err := repo.FindIdentity(ctx, "https://sso.example.com", "42")
+	s.Require().NoError(err, "should find identity without any error")
+	s.Require().Equal(userID, actual, "should find the linked user")
+
+	_, err = repo.FindIdentity(ctx, "https://other.example.com", "42")
+	s.Require().ErrorIs(err, core.ErrNotFound, "subjects should be scoped to their issuer")
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// membershipColumns are the columns of the memberships the policies filter on.
+var membershipColumns = authz.Columns{
+	"user_id":  "m.user_id",
+	"group_id": "m.group_id",
+}
+
+const membershipQuery = `
+	select m.user_id, m.group_id, m.role, u.username, g.name
+	from memberships m
+	inner join users u on u.id = m.user_id
+	inner join groups g on g.id = m.group_id
+`
+
+func (r *Repository) GetMembership(ctx context.Context, user core.UserID, group core.GroupID) (*core.Membership, error) {
+	var result *core.Membership
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(membershipQuery)
+		qb.Where(
+			querybuilder.Condition("m.user_id = ?", []any{user}),
+			querybuilder.Condition("m.group_id = ?", []any{group}),
+		)
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanMembership(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) ListMemberships(ctx context.Context, partial authz.Clause) ([]*core.Membership, error) {
+	var result []*core.Membership
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(membershipQuery)
+		cond, err := partial.Cond(membershipColumns)
+		if err != nil {
+			return err
+		}
+		qb.Where(cond)
+		qb.Append(" order by g.name, u.username;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			membership, err := scanMembership(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, membership)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanMembership(row Scanner) (*core.Membership, error) {
+	var membership core.Membership
+	if err := row.Scan(&membership.UserID, &membership.GroupID, &membership.Role, &membership.Username, &membership.GroupName); err != nil {
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+func (r *Repository) SaveMembership(ctx context.Context, membership *core.Membership) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		return saveMembership(ctx, tx, membership.UserID, membership.GroupID, membership.Role)
+	})
+}
+
+func saveMembership(ctx context.Context, tx *db.Tx, user core.UserID, group core.GroupID, role core.Role) error {
+	qb := querybuilder.New(`
+		insert into memberships (user_id, group_id, role) values (?, ?, ?)
+		on conflict (user_id, group_id) do update set
+			role = excluded.role;
+	`, user, group, role)
+	query, args := qb.SQL()
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *Repository) DeleteMembership(ctx context.Context, user core.UserID, group core.GroupID) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`delete from memberships where user_id = ? and group_id = ?;`, user, group)
+		query, args := qb.SQL()
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return core.ErrNotFound
+		}
+
+		return nil
+	})
+}
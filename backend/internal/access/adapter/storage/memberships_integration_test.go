@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_List_Delete_Memberships() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-333333333333")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11111111-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22222222-0000-0000-0000-000000000000")}
+
+	repo := NewRepository(s.db)
+	_, err := repo.SaveGroup(ctx, &core.Group{ID: otherGroupID, Name: "other_group"})
+	s.Require().NoError(err, "should save group without any error")
+
+	_, err = repo.GetMembership(ctx, clientID, otherGroupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find memberships of other groups")
+
+	s.Require().NoError(repo.SaveMembership(ctx, &core.Membership{UserID: clientID, GroupID: otherGroupID, Role: core.Operator}),
+		"should save membership without any error")
+
+	actual, err := repo.GetMembership(ctx, clientID, otherGroupID)
+	s.Require().NoError(err, "should get membership without any error")
+	s.Require().Equal(&core.Membership{
+		UserID:    clientID,
+		GroupID:   otherGroupID,
+		Role:      core.Operator,
+		Username:  "user_2",
+		GroupName: "other_group",
+	}, actual, "should get the saved membership")
+
+	memberships, err := repo.ListMemberships(ctx, authz.Clause{})
+	s.Require().NoError(err, "should list memberships without any error")
+	s.Require().Len(memberships, 3, "should list every membership")
+
+	memberships, err = repo.ListMemberships(ctx, authz.Clause{
+		Condition: "m.user_id = ?",
+		Values:    []any{clientID},
+	})
+	s.Require().NoError(err, "should list memberships without any error")
+	s.Require().Len(memberships, 2, "should list the memberships of the user")
+
+	memberships, err = repo.ListMemberships(ctx, authz.Clause{
+		Condition: "m.group_id = ?",
+		Values:    []any{otherGroupID},
+	})
+	s.Require().NoError(err, "should list memberships without any error")
+	s.Require().Equal([]*core.Membership{actual}, memberships, "should list the members of the group")
+
+	s.Require().NoError(repo.DeleteMembership(ctx, clientID, otherGroupID), "should delete membership without any error")
+	s.Require().ErrorIs(repo.DeleteMembership(ctx, clientID, otherGroupID), core.ErrNotFound, "should not delete missing memberships")
+
+	_, err = repo.GetMembership(ctx, adminID, groupID)
+	s.Require().NoError(err, "other memberships should be kept")
+}
+
+func (s *RepositoryTestSuite) Test_SaveUser_Syncs_Membership() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-333333333333")}
+	userID := core.UserID{UUID: uuid.FromStringOrNil("33333333-0000-0000-0000-000000000000")}
+
+	repo := NewRepository(s.db)
+	_, err := repo.SaveGroup(ctx, &core.Group{ID: otherGroupID, Name: "other_group"})
+	s.Require().NoError(err, "should save group without any error")
+
+	user, err := repo.GetUser(ctx, userID, authz.Clause{})
+	s.Require().NoError(err, "should get user without any error")
+
+	user.GroupID = groupID
+	user.Role = core.Viewer
+	_, err = repo.SaveUser(ctx, user, authz.Clause{})
+	s.Require().NoError(err, "should save user without any error")
+
+	membership, err := repo.GetMembership(ctx, userID, groupID)
+	s.Require().NoError(err, "should add the membership of the user group")
+	s.Require().Equal(core.Viewer, membership.Role, "membership should have the role of the user")
+
+	user.GroupID = otherGroupID
+	_, err = repo.SaveUser(ctx, user, authz.Clause{})
+	s.Require().NoError(err, "should save user without any error")
+
+	_, err = repo.GetMembership(ctx, userID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should remove the membership of the group the user left")
+
+	_, err = repo.GetMembership(ctx, userID, otherGroupID)
+	s.Require().NoError(err, "should add the membership of the new group")
+
+	user.GroupID = core.GroupID{}
+	_, err = repo.SaveUser(ctx, user, authz.Clause{})
+	s.Require().NoError(err, "should save user without any error")
+
+	_, err = repo.GetMembership(ctx, userID, otherGroupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should remove the membership of users left without a group")
+}
@@ -6,7 +6,6 @@ import (
 
 	"vpainless/internal/pkg/authz"
 	"vpainless/internal/pkg/db"
-	"vpainless/pkg/querybuilder"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -39,11 +38,12 @@ func uuidOrNull(anID uuid.UUID) sql.NullString {
 	}
 }
 
-func fromPolicyPartial(pc authz.Clause) querybuilder.Cond {
-	return querybuilder.Cond{
-		Text: pc.Condition,
-		Args: pc.Values,
-	}
+// userColumns are the columns of the users the policies filter on. Users are
+// filtered by the groups they are members of, as group_ids.
+var userColumns = authz.Columns{
+	"id":        "id",
+	"group_id":  "group_id",
+	"group_ids": "(select m.group_id from memberships m where m.user_id = u.id)",
 }
 
 type Scanner interface {
@@ -58,6 +58,11 @@ func (s *RepositoryTestSuite) SetupTest() {
 	tx, err := s.db.Begin()
 	s.Require().NoError(err, "should begin the transaction successfully")
 	_, err = tx.Exec(`
+		delete from login_attempts;
+		delete from identities;
+		delete from recovery_codes;
+		delete from two_factors;
+		delete from memberships;
 		delete from users;
 		delete from groups;
 
@@ -67,6 +72,10 @@ func (s *RepositoryTestSuite) SetupTest() {
 		('11111111-0000-0000-0000-000000000000', '00000000-0000-0000-0000-111111111111', 'user_1', 'password', 'admin'),
 		('22222222-0000-0000-0000-000000000000', '00000000-0000-0000-0000-111111111111', 'user_2', 'password', 'client'),
 		('33333333-0000-0000-0000-000000000000', null, 'user_3', 'password', 'client');
+
+		insert into memberships (user_id, group_id, role) values
+		('11111111-0000-0000-0000-000000000000', '00000000-0000-0000-0000-111111111111', 'admin'),
+		('22222222-0000-0000-0000-000000000000', '00000000-0000-0000-0000-111111111111', 'client');
 	`)
 	s.Require().NoError(err, "should insert test materials successfully")
 	s.Require().NoError(tx.Commit(), "should commit tx successfully")
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) GetSession(ctx context.Context, hash string) (*core.Session, error) {
+	var result core.Session
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select user_id, two_factor, created_at, expires_at
+			from sessions
+			where token = ?;
+		`, hash)
+		query, args := qb.SQL()
+
+		var createdAt, expiresAt string
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&result.UserID, &result.TwoFactor, &createdAt, &expiresAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrNotFound
+			}
+			return err
+		}
+
+		var err error
+		result.CreatedAt, err = time.Parse(time.DateTime, createdAt)
+		if err != nil {
+			return err
+		}
+
+		result.ExpiresAt, err = time.Parse(time.DateTime, expiresAt)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *Repository) SaveSession(ctx context.Context, hash string, session *core.Session) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into sessions (token, user_id, two_factor, created_at, expires_at)
+			values (?, ?, ?, ?, ?);
+		`, hash, session.UserID, session.TwoFactor,
+			session.CreatedAt.UTC().Format(time.DateTime), session.ExpiresAt.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) DeleteSession(ctx context.Context, hash string) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		result, err := tx.ExecContext(ctx, `delete from sessions where token = ?;`, hash)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return core.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *Repository) DeleteExpiredSessions(ctx context.Context, before time.Time) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		_, err := tx.ExecContext(ctx, `delete from sessions where expires_at <= ?;`, before.UTC().Format(time.DateTime))
+		return err
+	})
+}
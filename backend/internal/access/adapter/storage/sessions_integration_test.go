@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/access/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_Delete_Session() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	repo := NewRepository(s.db)
+	_, err := repo.GetSession(ctx, "hash")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find unknown sessions")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	session := &core.Session{
+		UserID:    core.UserID{UUID: uuid.FromStringOrNil("11111111-0000-0000-0000-000000000000")},
+		TwoFactor: true,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	s.Require().NoError(repo.SaveSession(ctx, "hash", session), "should save session without any error")
+
+	expired := *session
+	expired.ExpiresAt = now.Add(-time.Minute)
+	s.Require().NoError(repo.SaveSession(ctx, "expired", &expired), "should save session without any error")
+
+	actual, err := repo.GetSession(ctx, "hash")
+	s.Require().NoError(err, "should get session without any error")
+	s.Require().Equal(session, actual, "should get the saved session")
+
+	s.Require().NoError(repo.DeleteExpiredSessions(ctx, now), "should delete expired sessions without any error")
+	_, err = repo.GetSession(ctx, "expired")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should delete the expired sessions")
+	_, err = repo.GetSession(ctx, "hash")
+	s.Require().NoError(err, "should keep the sessions that are not expired")
+
+	s.Require().NoError(repo.DeleteSession(ctx, "hash"), "should delete session without any error")
+	s.Require().ErrorIs(repo.DeleteSession(ctx, "hash"), core.ErrNotFound, "should not delete a session twice")
+}
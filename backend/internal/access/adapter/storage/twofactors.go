@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vpainless/internal/access/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) GetTwoFactor(ctx context.Context, user core.UserID) (*core.TwoFactor, error) {
+	var twoFactor core.TwoFactor
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`select user_id, secret, enabled from two_factors where user_id = ?;`, user)
+		query, args := qb.SQL()
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.Enabled)
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+func (r *Repository) SaveTwoFactor(ctx context.Context, twoFactor *core.TwoFactor) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into two_factors (user_id, secret, enabled) values (?, ?, ?)
+			on conflict (user_id) do update set
+				secret = excluded.secret,
+				enabled = excluded.enabled;
+		`, twoFactor.UserID, twoFactor.Secret, twoFactor.Enabled)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) DeleteTwoFactor(ctx context.Context, user core.UserID) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		if _, err := tx.ExecContext(ctx, `delete from recovery_codes where user_id = ?;`, user); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `delete from two_factors where user_id = ?;`, user)
+		return err
+	})
+}
+
+func (r *Repository) SaveRecoveryCodes(ctx context.Context, user core.UserID, hashes []string) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		if _, err := tx.ExecContext(ctx, `delete from recovery_codes where user_id = ?;`, user); err != nil {
+			return err
+		}
+
+		for _, hash := range hashes {
+			if _, err := tx.ExecContext(ctx, `insert into recovery_codes (user_id, code) values (?, ?);`, user, hash); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, user core.UserID, hash string) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		result, err := tx.ExecContext(ctx, `delete from recovery_codes where user_id = ? and code = ?;`, user, hash)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return core.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) UseTOTPStep(ctx context.Context, user core.UserID, step int64) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		result, err := tx.ExecContext(ctx, `update two_factors set last_step = ? where user_id = ? and last_step < ?;`, step, user, step)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return core.ErrNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+
+	"vpainless/internal/access/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_Delete_TwoFactor() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	userID := core.UserID{UUID: uuid.FromStringOrNil("11111111-0000-0000-0000-000000000000")}
+
+	repo := NewRepository(s.db)
+	_, err := repo.GetTwoFactor(ctx, userID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find two factor of users who have not enrolled")
+
+	twoFactor := &core.TwoFactor{UserID: userID, Secret: "JBSWY3DPEHPK3PXP"}
+	s.Require().NoError(repo.SaveTwoFactor(ctx, twoFactor), "should save two factor without any error")
+
+	twoFactor.Enabled = true
+	s.Require().NoError(repo.SaveTwoFactor(ctx, twoFactor), "should enable two factor without any error")
+
+	actual, err := repo.GetTwoFactor(ctx, userID)
+	s.Require().NoError(err, "should get two factor without any error")
+	s.Require().Equal(twoFactor, actual, "should get the saved two factor")
+
+	s.Require().NoError(repo.SaveRecoveryCodes(ctx, userID, []string{"old"}), "should save recovery codes without any error")
+	s.Require().NoError(repo.SaveRecoveryCodes(ctx, userID, []string{"a", "b"}), "should replace recovery codes without any error")
+
+	s.Require().ErrorIs(repo.UseRecoveryCode(ctx, userID, "old"), core.ErrNotFound, "replaced codes should not be usable")
+	s.Require().NoError(repo.UseRecoveryCode(ctx, userID, "a"), "should use recovery code without any error")
+	s.Require().ErrorIs(repo.UseRecoveryCode(ctx, userID, "a"), core.ErrNotFound, "recovery codes should be usable once")
+
+	s.Require().NoError(repo.UseTOTPStep(ctx, userID, 41152263), "should use the step without any error")
+	s.Require().ErrorIs(repo.UseTOTPStep(ctx, userID, 41152263), core.ErrNotFound, "steps should be usable once")
+	s.Require().ErrorIs(repo.UseTOTPStep(ctx, userID, 41152262), core.ErrNotFound, "earlier steps should not be usable")
+	s.Require().NoError(repo.UseTOTPStep(ctx, userID, 41152264), "later steps should be usable")
+
+	s.Require().NoError(repo.DeleteTwoFactor(ctx, userID), "should delete two factor without any error")
+	_, err = repo.GetTwoFactor(ctx, userID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find deleted two factor")
+	s.Require().ErrorIs(repo.UseRecoveryCode(ctx, userID, "b"), core.ErrNotFound, "should delete the recovery codes along")
+}
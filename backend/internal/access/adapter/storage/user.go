@@ -21,14 +21,17 @@ func (r *Repository) GetUser(ctx context.Context, id core.UserID, partial authz.
 		qb := querybuilder.New(
 			"select u.id, u.group_id, u.username, u.password, u.role from users u",
 		)
+		cond, err := partial.Cond(userColumns)
+		if err != nil {
+			return err
+		}
 		qb.Where(
 			querybuilder.Condition("id = ?", []any{id}),
-			fromPolicyPartial(partial),
+			cond,
 		)
 
 		query, args := qb.SQL()
 		row := tx.QueryRowContext(ctx, query, args...)
-		var err error
 		user, err = scanUser(row)
 		if errors.Is(err, sql.ErrNoRows) {
 			exists = false
@@ -63,11 +66,18 @@ func scanUser(row Scanner) (*core.User, error) {
 	return &user, nil
 }
 
-// SaveUser creates a users and return it. It failes if a user with similar username exists
+// SaveUser creates a users and return it. It failes if a user with similar username exists.
+// The membership of the user in their group is kept in sync with their group and role.
 func (r *Repository) SaveUser(ctx context.Context, user *core.User, partial authz.Clause) (*core.User, error) {
 	var result *core.User
 
 	err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		var oldGroupID sql.NullString
+		err := tx.QueryRowContext(ctx, "select group_id from users where id = ?;", user.ID).Scan(&oldGroupID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
 		qb := querybuilder.New(`
 				insert into users (id, group_id, username, password, role)
 				values (?, ?, ?, ?, ?)
@@ -77,13 +87,31 @@ func (r *Repository) SaveUser(ctx context.Context, user *core.User, partial auth
 					group_id = excluded.group_id,
 					role = excluded.role
 		`, user.ID, uuidOrNull(user.GroupID.UUID), user.Username, user.Password, user.Role)
-		qb.Where(fromPolicyPartial(partial))
+		cond, err := partial.Cond(userColumns)
+		if err != nil {
+			return err
+		}
+		qb.Where(cond)
 		qb.Append(" returning id, group_id, username, password, role;")
 		query, args := qb.SQL()
 		row := tx.QueryRowContext(ctx, query, args...)
-		var err error
 		result, err = scanUser(row)
-		return err
+		if err != nil {
+			return err
+		}
+
+		if oldGroupID.Valid && oldGroupID.String != result.GroupID.String() {
+			qb := querybuilder.New(`delete from memberships where user_id = ? and group_id = ?;`, result.ID, oldGroupID.String)
+			query, args := qb.SQL()
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return err
+			}
+		}
+
+		if result.GroupID.IsNil() {
+			return nil
+		}
+		return saveMembership(ctx, tx, result.ID, result.GroupID, result.Role)
 	})
 	if err != nil {
 		return nil, err
@@ -116,9 +144,11 @@ func (r *Repository) ListUsers(ctx context.Context, partial authz.Clause) ([]*co
 		qb := querybuilder.New(
 			"select u.id, u.group_id, u.username, u.password, u.role from users u",
 		)
-		qb.Where(
-			fromPolicyPartial(partial),
-		)
+		cond, err := partial.Cond(userColumns)
+		if err != nil {
+			return err
+		}
+		qb.Where(cond)
 
 		query, args := qb.SQL()
 		rows, err := tx.QueryContext(ctx, query, args...)
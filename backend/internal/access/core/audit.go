@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+)
+
+type AuditAction string
+
+const (
+	AuditUserCreate   AuditAction = "user.create"
+	AuditUserUpdate   AuditAction = "user.update"
+	AuditUserUnlock   AuditAction = "user.unlock"
+	AuditGroupCreate  AuditAction = "group.create"
+	AuditMemberAdd    AuditAction = "member.add"
+	AuditMemberUpdate AuditAction = "member.update"
+	AuditMemberRemove AuditAction = "member.remove"
+	AuditLoginFailure AuditAction = "login.failure"
+	AuditLoginLockout AuditAction = "login.lockout"
+
+	AuditTwoFactorEnable  AuditAction = "two_factor.enable"
+	AuditTwoFactorDisable AuditAction = "two_factor.disable"
+
+	// redacted replaces the secrets in the audit log.
+	redacted = "[redacted]"
+)
+
+// Change is the value of a field before and after an action.
+type Change struct {
+	Old any
+	New any
+}
+
+// AuditEvent is an action to be appended to the audit log, which is kept by the
+// hosting module. The principal and the request id are taken from the context.
+type AuditEvent struct {
+	Action AuditAction
+	// GroupID is the group the target belongs to. Events are visible to its admins.
+	GroupID GroupID
+	Target  string
+	Diff    map[string]Change
+}
+
+// audit records an action after it is committed. The audit log is in another
+// database, so failing to record is only logged, and does not fail the action.
+func (s *Service) audit(ctx context.Context, event *AuditEvent) {
+	if err := s.hosting.RecordAudit(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "error recording audit event", "action", event.Action, "target", event.Target, "error", err)
+	}
+}
+
+// userDiff returns the changed fields of a user. Passwords are redacted.
+func userDiff(old, new *User) map[string]Change {
+	diff := map[string]Change{}
+	if old == nil {
+		old = &User{}
+	}
+
+	if old.Username != new.Username {
+		diff["username"] = Change{Old: old.Username, New: new.Username}
+	}
+	if old.Role != new.Role {
+		diff["role"] = Change{Old: old.Role, New: new.Role}
+	}
+	if old.GroupID != new.GroupID {
+		diff["group_id"] = Change{Old: old.GroupID, New: new.GroupID}
+	}
+	if old.Password != new.Password {
+		diff["password"] = Change{Old: redacted, New: redacted}
+	}
+	return diff
+}
@@ -65,13 +65,28 @@ func (s *Service) CreateGroup(ctx context.Context, g *Group) (*Group, error) {
 			return err
 		}
 
+		// The creator of the group is its first owner. Users that already
+		// have a group become members of the new one.
+		if !user.GroupID.IsNil() {
+			return s.repo.SaveMembership(ctx, &Membership{UserID: user.ID, GroupID: g.ID, Role: Owner})
+		}
+
 		user.GroupID = g.ID
-		user.Role = Admin
+		user.Role = Owner
 		_, err = s.repo.SaveUser(ctx, user, authz.Clause{})
 		return err
 	}); err != nil {
 		return nil, err
 	}
 
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditGroupCreate,
+		GroupID: result.ID,
+		Target:  "groups/" + result.ID.String(),
+		Diff: map[string]Change{
+			"name": {New: result.Name},
+			"host": {New: result.Host},
+		},
+	})
 	return result, nil
 }
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Identity is a user of an external OpenID provider, such as Keycloak or Authelia.
+type Identity struct {
+	Issuer  string
+	Subject string
+	// Username is the preferred username of the user, the user is provisioned with.
+	Username string
+	// Groups are the groups of the user at the provider.
+	Groups []string
+	// MFA reports whether the user logged in with a second factor at the provider.
+	MFA bool
+}
+
+// Login is a completed login through the identity provider. The token is the id
+// token, which authenticates the requests of the user until it expires.
+type Login struct {
+	Token string
+	User  *User
+}
+
+type identityProvider interface {
+	// AuthCodeURL returns the url of the provider the users log in at.
+	AuthCodeURL(state, nonce string) string
+	// Exchange redeems the code of a login with the nonce, and returns its id token.
+	Exchange(ctx context.Context, code, nonce string) (string, *Identity, error)
+	// Verify verifies the id token, and returns the identity it asserts.
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+// AnyGroup maps the users of the provider with none of the mapped groups, to the
+// group they are provisioned into. Without it, they are not provisioned.
+const AnyGroup = "*"
+
+// UseIdentityProvider lets the users log in through the provider. Users logging
+// in for the first time are provisioned into the first of their groups at the
+// provider that is mapped to a group, or the group of AnyGroup.
+func (s *Service) UseIdentityProvider(provider identityProvider, groups map[string]GroupID) {
+	s.identities = provider
+	s.identityGroups = groups
+}
+
+// StartLogin returns the url the users log in at, along with the state and the
+// nonce the login should be finished with.
+func (s *Service) StartLogin(ctx context.Context) (url, state, nonce string, err error) {
+	if s.identities == nil {
+		return "", "", "", errors.Join(ErrNotFound, errors.New("no identity provider is configured"))
+	}
+
+	state, err = randomString()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	nonce, err = randomString()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return s.identities.AuthCodeURL(state, nonce), state, nonce, nil
+}
+
+// FinishLogin exchanges the code of a login for the id token, and returns the user
+// of the identity. Users are provisioned on their first login, unless their username
+// is taken by another user, or none of their groups is mapped to a group.
+func (s *Service) FinishLogin(ctx context.Context, code, nonce string) (*Login, error) {
+	if s.identities == nil {
+		return nil, errors.Join(ErrNotFound, errors.New("no identity provider is configured"))
+	}
+
+	token, identity, err := s.identities.Exchange(ctx, code, nonce)
+	if err != nil {
+		return nil, errors.Join(ErrUnauthorized, err)
+	}
+
+	var (
+		result      *User
+		provisioned bool
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		id, err := s.repo.FindIdentity(ctx, identity.Issuer, identity.Subject)
+		if err == nil {
+			result, err = s.repo.GetUser(ctx, id, authz.Clause{})
+			if err == nil && result == nil {
+				err = ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return s.checkIdentityTwoFactor(ctx, result, identity)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		username := identity.Username
+		if username == "" {
+			username = identity.Subject
+		}
+
+		groupID, ok := s.identityGroup(identity.Groups)
+		if !ok {
+			return errors.Join(ErrForbidden, errors.New("none of the groups of the user is mapped to a group"))
+		}
+
+		_, err = s.repo.FindUserByName(ctx, username)
+		if err == nil {
+			return errors.Join(ErrAlreadyExists, errors.New("the username is taken by another user"))
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		// Provisioned users log in through the provider only, until they set a password.
+		password, err := randomString()
+		if err != nil {
+			return err
+		}
+
+		user := &User{
+			ID:       UserID{uuid.Must(uuid.NewV4())},
+			Username: username,
+			Password: generatePassword(username, password),
+			GroupID:  groupID,
+			Role:     Client,
+		}
+
+		result, err = s.repo.SaveUser(ctx, user, authz.Clause{})
+		if err != nil {
+			return err
+		}
+
+		provisioned = true
+		return s.repo.SaveIdentity(ctx, identity.Issuer, identity.Subject, result.ID)
+	}); err != nil {
+		return nil, err
+	}
+
+	if provisioned {
+		s.audit(ctx, &AuditEvent{
+			Action:  AuditUserCreate,
+			GroupID: result.GroupID,
+			Target:  "users/" + result.ID.String(),
+			Diff:    userDiff(nil, result),
+		})
+	}
+	return &Login{Token: token, User: result}, nil
+}
+
+// identityGroup returns the group mapped to the first of the groups of the
+// provider, or to AnyGroup.
+func (s *Service) identityGroup(groups []string) (GroupID, bool) {
+	for _, group := range groups {
+		if id, ok := s.identityGroups[group]; ok {
+			return id, true
+		}
+	}
+
+	id, ok := s.identityGroups[AnyGroup]
+	return id, ok
+}
+
+// authenticateToken authenticates the user of the id token. The user has a second
+// factor if they logged in with one at the provider. Users who enabled two factor
+// authentication here are rejected unless they did, so the token does not skip it.
+func (s *Service) authenticateToken(ctx context.Context, token string) (*User, bool, error) {
+	if s.identities == nil {
+		return nil, false, errors.Join(ErrUnauthorized, errors.New("no identity provider is configured"))
+	}
+
+	identity, err := s.identities.Verify(ctx, token)
+	if err != nil {
+		s.audit(ctx, &AuditEvent{
+			Action: AuditLoginFailure,
+			Target: "users",
+		})
+		s.recordLoginFailure(ctx, nil)
+		return nil, false, errors.Join(ErrUnauthorized, err)
+	}
+
+	id, err := s.repo.FindIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			err = errors.Join(ErrUnauthorized, errors.New("the identity is not linked to any user"))
+		}
+		return nil, false, err
+	}
+
+	user, err := s.repo.GetUser(ctx, id, authz.Clause{})
+	if err != nil {
+		return nil, false, err
+	}
+	if user == nil {
+		return nil, false, ErrUnauthorized
+	}
+
+	if err := s.checkIdentityTwoFactor(ctx, user, identity); err != nil {
+		s.audit(ctx, &AuditEvent{
+			Action:  AuditLoginFailure,
+			GroupID: user.GroupID,
+			Target:  "users/" + user.ID.String(),
+		})
+		return nil, false, err
+	}
+
+	return user, identity.MFA, nil
+}
+
+// checkIdentityTwoFactor rejects the identities logged in without a second factor
+// at the provider, if their user enabled two factor authentication here.
+func (s *Service) checkIdentityTwoFactor(ctx context.Context, user *User, identity *Identity) error {
+	if identity.MFA {
+		return nil
+	}
+
+	twoFactor, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if twoFactor.Enabled {
+		return errors.Join(ErrUnauthorized, errors.New("two factor authentication is enabled, log in with a second factor at the provider"))
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository keeps the users, identities and two factors in memory. The rest
+// of the repository is not used by the logins, and panics if called.
+type fakeRepository struct {
+	AccessRepository
+
+	users      map[UserID]*User
+	identities map[string]UserID
+	twoFactors map[UserID]*TwoFactor
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		users:      map[UserID]*User{},
+		identities: map[string]UserID{},
+		twoFactors: map[UserID]*TwoFactor{},
+	}
+}
+
+func (r *fakeRepository) Transact(ctx context.Context, _ sql.IsolationLevel, fn db.TransactionFunc) error {
+	return fn(ctx)
+}
+
+func (r *fakeRepository) GetUser(_ context.Context, id UserID, _ authz.Clause) (*User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeRepository) SaveUser(_ context.Context, user *User, _ authz.Clause) (*User, error) {
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *fakeRepository) FindUserByName(_ context.Context, username string) (*User, error) {
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *fakeRepository) FindIdentity(_ context.Context, issuer, subject string) (UserID, error) {
+	id, ok := r.identities[issuer+"|"+subject]
+	if !ok {
+		return UserID{}, ErrNotFound
+	}
+	return id, nil
+}
+
+func (r *fakeRepository) SaveIdentity(_ context.Context, issuer, subject string, user UserID) error {
+	r.identities[issuer+"|"+subject] = user
+	return nil
+}
+
+func (r *fakeRepository) GetTwoFactor(_ context.Context, user UserID) (*TwoFactor, error) {
+	twoFactor, ok := r.twoFactors[user]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return twoFactor, nil
+}
+
+type fakeHosting struct {
+	hostingAdapter
+
+	events []*AuditEvent
+}
+
+func (h *fakeHosting) RecordAudit(_ context.Context, e *AuditEvent) error {
+	h.events = append(h.events, e)
+	return nil
+}
+
+// fakeProvider asserts the identity for every code and token.
+type fakeProvider struct {
+	identity *Identity
+}
+
+func (p *fakeProvider) AuthCodeURL(state, nonce string) string {
+	return "https://example.com/authorize?state=" + state + "&nonce=" + nonce
+}
+
+func (p *fakeProvider) Exchange(_ context.Context, code, _ string) (string, *Identity, error) {
+	if code != "code" {
+		return "", nil, errors.New("invalid code")
+	}
+	return "token", p.identity, nil
+}
+
+func (p *fakeProvider) Verify(_ context.Context, token string) (*Identity, error) {
+	if token != "token" {
+		return nil, errors.New("invalid token")
+	}
+	return p.identity, nil
+}
+
+var (
+	familyID  = GroupID{uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	friendsID = GroupID{uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+)
+
+func setupLogin(t *testing.T, identity *Identity, groups map[string]GroupID) (*Service, *fakeRepository, *fakeHosting) {
+	t.Helper()
+
+	repo := newFakeRepository()
+	hosting := &fakeHosting{}
+	s := NewService(repo, hosting)
+	s.UseIdentityProvider(&fakeProvider{identity: identity}, groups)
+	return s, repo, hosting
+}
+
+func TestFinishLogin_Provision(t *testing.T) {
+	identity := &Identity{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"strangers", "friends", "family"}}
+	s, repo, hosting := setupLogin(t, identity, map[string]GroupID{"family": familyID, "friends": friendsID})
+	ctx := context.Background()
+
+	_, err := s.FinishLogin(ctx, "other", "nonce")
+	require.ErrorIs(t, err, ErrUnauthorized, "should reject codes the provider does not exchange")
+
+	login, err := s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err, "should provision the user on their first login")
+	require.Equal(t, "token", login.Token)
+	require.Equal(t, "john", login.User.Username, "should provision the user with their preferred username")
+	require.Equal(t, friendsID, login.User.GroupID, "should provision the user into the first of their mapped groups")
+	require.Equal(t, Client, login.User.Role, "should provision the user as a client")
+	require.Len(t, hosting.events, 1, "should audit the provisioning")
+	require.Equal(t, AuditUserCreate, hosting.events[0].Action)
+
+	id, err := repo.FindIdentity(ctx, identity.Issuer, identity.Subject)
+	require.NoError(t, err, "should link the identity to the user")
+	require.Equal(t, login.User.ID, id)
+
+	again, err := s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err, "should log the user in again")
+	require.Equal(t, login.User, again.User, "should return the provisioned user")
+	require.Len(t, repo.users, 1, "should not provision the user twice")
+	require.Len(t, hosting.events, 1, "should only audit the provisioning")
+}
+
+func TestFinishLogin_Subject(t *testing.T) {
+	identity := &Identity{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"family"}}
+	s, repo, _ := setupLogin(t, identity, map[string]GroupID{"family": familyID})
+	ctx := context.Background()
+
+	user := &User{ID: UserID{uuid.Must(uuid.NewV4())}, Username: "johnny", GroupID: friendsID, Role: Operator}
+	repo.users[user.ID] = user
+	require.NoError(t, repo.SaveIdentity(ctx, identity.Issuer, identity.Subject, user.ID))
+
+	login, err := s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err, "should log in the linked user")
+	require.Equal(t, user, login.User, "should map the identity by its subject rather than its username")
+
+	identity.Subject = "43"
+	identity.Username = ""
+	login, err = s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err, "should provision the user of another subject")
+	require.Equal(t, "43", login.User.Username, "should provision users without a preferred username with their subject")
+	require.NotEqual(t, user.ID, login.User.ID)
+}
+
+func TestFinishLogin_UsernameTaken(t *testing.T) {
+	identity := &Identity{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"family"}}
+	s, repo, hosting := setupLogin(t, identity, map[string]GroupID{"family": familyID})
+	ctx := context.Background()
+
+	user := &User{ID: UserID{uuid.Must(uuid.NewV4())}, Username: "john", GroupID: familyID, Role: Owner}
+	repo.users[user.ID] = user
+
+	_, err := s.FinishLogin(ctx, "code", "nonce")
+	require.ErrorIs(t, err, ErrAlreadyExists, "should not take over the user with the username")
+	require.Empty(t, repo.identities, "should not link the identity to the user")
+	require.Len(t, repo.users, 1, "should not provision another user")
+	require.Empty(t, hosting.events)
+}
+
+func TestFinishLogin_UnmappedGroups(t *testing.T) {
+	identity := &Identity{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"strangers"}}
+	ctx := context.Background()
+
+	s, repo, _ := setupLogin(t, identity, map[string]GroupID{"family": familyID})
+	_, err := s.FinishLogin(ctx, "code", "nonce")
+	require.ErrorIs(t, err, ErrForbidden, "should not provision users with none of the mapped groups")
+	require.Empty(t, repo.users)
+	require.Empty(t, repo.identities)
+
+	s, _, _ = setupLogin(t, identity, map[string]GroupID{"family": familyID, AnyGroup: friendsID})
+	login, err := s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err, "should provision users with none of the mapped groups into the group of any")
+	require.Equal(t, friendsID, login.User.GroupID)
+}
+
+func TestAuthenticateToken_TwoFactor(t *testing.T) {
+	identity := &Identity{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"family"}}
+	s, repo, _ := setupLogin(t, identity, map[string]GroupID{"family": familyID})
+	ctx := context.Background()
+
+	login, err := s.FinishLogin(ctx, "code", "nonce")
+	require.NoError(t, err)
+
+	_, _, err = s.authenticateToken(ctx, "other")
+	require.ErrorIs(t, err, ErrUnauthorized, "should reject tokens the provider does not verify")
+
+	user, twoFactor, err := s.authenticateToken(ctx, login.Token)
+	require.NoError(t, err, "should authenticate the user of the token")
+	require.Equal(t, login.User, user)
+	require.False(t, twoFactor)
+
+	repo.twoFactors[user.ID] = &TwoFactor{UserID: user.ID, Enabled: true}
+	_, _, err = s.authenticateToken(ctx, login.Token)
+	require.ErrorIs(t, err, ErrUnauthorized, "should reject tokens without a second factor of users with two factor enabled")
+	_, err = s.FinishLogin(ctx, "code", "nonce")
+	require.ErrorIs(t, err, ErrUnauthorized, "should reject logins without a second factor of users with two factor enabled")
+
+	identity.MFA = true
+	user, twoFactor, err = s.authenticateToken(ctx, login.Token)
+	require.NoError(t, err, "should authenticate tokens with a second factor at the provider")
+	require.Equal(t, login.User, user)
+	require.True(t, twoFactor)
+}
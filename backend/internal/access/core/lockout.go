@@ -0,0 +1,205 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/middleware"
+)
+
+// Lockout locks a key out after Threshold failed attempts within the Window. The
+// first lockout lasts Base, and each further failure doubles it, up to Max.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+var (
+	// UserLockout limits the failed logins of each username.
+	UserLockout = Lockout{Threshold: 5, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+	// IPLockout limits the failed logins from each address, whichever usernames they try.
+	IPLockout = Lockout{Threshold: 20, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+	// RegistrationLockout limits the anonymous registrations from each address.
+	RegistrationLockout = Lockout{Threshold: 5, Base: time.Hour, Max: 24 * time.Hour, Window: 24 * time.Hour}
+)
+
+// Attempts are the failed logins with a key, such as 'user:<username>' or
+// 'ip:<address>', or the registrations from an address. Attempts are stored, so
+// they survive the restarts.
+type Attempts struct {
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+	UpdatedAt   time.Time
+}
+
+// add records an attempt. It reports whether the attempt locked the key out.
+func (a *Attempts) add(policy Lockout, now time.Time) bool {
+	if now.Sub(a.UpdatedAt) > policy.Window {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.UpdatedAt = now
+
+	if a.Failures < policy.Threshold {
+		return false
+	}
+
+	d := policy.Max
+	if shift := a.Failures - policy.Threshold; shift < 32 {
+		d = min(policy.Base<<shift, policy.Max)
+	}
+	a.LockedUntil = now.Add(d)
+	return true
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func registrationKey(ip string) string {
+	return "register:" + ip
+}
+
+// checkLockout returns a lockout error if the key is locked out. Otherwise, it
+// reports whether the key has any attempts.
+func (s *Service) checkLockout(ctx context.Context, key string) (bool, error) {
+	attempts, err := s.repo.GetAttempts(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if attempts.LockedUntil.After(now) {
+		return true, &authz.LockoutError{RetryAfter: attempts.LockedUntil.Sub(now)}
+	}
+
+	return true, nil
+}
+
+// recordAttempt records an attempt with the key. Lockouts are logged and audited
+// as login.lockout, in the group of the user if any. Failing to record is only
+// logged, so it does not change the outcome of the attempt.
+func (s *Service) recordAttempt(ctx context.Context, key string, policy Lockout, user *User) {
+	var (
+		attempts *Attempts
+		locked   bool
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		var err error
+		attempts, err = s.repo.GetAttempts(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			attempts, err = &Attempts{Key: key}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		locked = attempts.add(policy, time.Now())
+		return s.repo.SaveAttempts(ctx, attempts)
+	}); err != nil {
+		slog.ErrorContext(ctx, "error recording attempt", "key", key, "error", err)
+		return
+	}
+
+	if !locked {
+		return
+	}
+
+	slog.WarnContext(ctx, "locked out after too many attempts", "key", key, "failures", attempts.Failures, "until", attempts.LockedUntil)
+	event := &AuditEvent{
+		Action: AuditLoginLockout,
+		Target: "users",
+		Diff: map[string]Change{
+			"key":          {New: key},
+			"failures":     {New: attempts.Failures},
+			"locked_until": {New: attempts.LockedUntil.UTC()},
+		},
+	}
+	if user != nil {
+		event.GroupID = user.GroupID
+		event.Target = "users/" + user.ID.String()
+	}
+	s.audit(ctx, event)
+}
+
+// recordLoginFailure records a failed login from the client address, and of the
+// user if any.
+func (s *Service) recordLoginFailure(ctx context.Context, user *User) {
+	if ip := middleware.GetClientIP(ctx); ip != "" {
+		s.recordAttempt(ctx, ipKey(ip), IPLockout, nil)
+	}
+	if user != nil {
+		s.recordAttempt(ctx, userKey(user.Username), UserLockout, user)
+	}
+}
+
+// clearAttempts deletes the attempts with the key, after a successful login.
+func (s *Service) clearAttempts(ctx context.Context, key string) {
+	if err := s.repo.DeleteAttempts(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "error clearing attempts", "key", key, "error", err)
+	}
+}
+
+// UnlockUser clears the failed logins of a user, which ends their lockout. It is
+// allowed to whoever can edit the user.
+func (s *Service) UnlockUser(ctx context.Context, id UserID) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	var user *User
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err = s.repo.GetUser(ctx, id, authz.Clause{})
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrNotFound
+		}
+
+		input := map[string]any{
+			"id":       user.ID,
+			"new_role": user.Role,
+			"old_role": user.Role,
+		}
+		if !user.GroupID.IsNil() {
+			input["new_group_id"] = user.GroupID
+			input["old_group_id"] = user.GroupID
+		}
+
+		policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.Resource{
+			Group: ResourceUsers,
+			Value: input,
+		})
+		if err != nil || !policy.Allow {
+			return errors.Join(err, ErrUnauthorized)
+		}
+
+		return s.repo.DeleteAttempts(ctx, userKey(user.Username))
+	}); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "unlocked user", "id", user.ID, "username", user.Username)
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditUserUnlock,
+		GroupID: user.GroupID,
+		Target:  "users/" + user.ID.String(),
+	})
+	return nil
+}
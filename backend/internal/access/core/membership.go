@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const ResourceMemberships = "memberships"
+
+// Membership is the role of a user in a group. Users can be members of several
+// groups, and act in one of them per request. The group of a user is their default
+// membership, the one they act in when they do not choose another.
+type Membership struct {
+	UserID    UserID  `json:"user_id"`
+	GroupID   GroupID `json:"group_id"`
+	Role      Role    `json:"role"`
+	Username  string  `json:"username"`
+	GroupName string  `json:"group_name"`
+}
+
+// ListMemberships lists the memberships of the principal, along with the members
+// of the group they act in, if they are allowed to see them.
+func (s *Service) ListMemberships(ctx context.Context) ([]*Membership, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.Resource{Group: ResourceMemberships})
+	if err != nil || !policy.Allow {
+		return nil, errors.Join(err, ErrUnauthorized)
+	}
+
+	return s.repo.ListMemberships(ctx, policy.Partial)
+}
+
+// AddMember adds the user to the group with the role. The first group of a user
+// becomes their default group.
+func (s *Service) AddMember(ctx context.Context, group GroupID, username string, role Role) (*Membership, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Create, authz.Resource{
+		Group: ResourceMemberships,
+		Value: map[string]any{
+			"group_id": group,
+			"role":     role,
+		},
+	})
+	if err != nil || !policy.Allow {
+		return nil, errors.Join(err, ErrUnauthorized)
+	}
+
+	var result *Membership
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.FindUserByName(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.repo.GetMembership(ctx, user.ID, group)
+		if err == nil {
+			return ErrAlreadyExists
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if user.GroupID.IsNil() {
+			user.GroupID = group
+			user.Role = role
+			if _, err := s.repo.SaveUser(ctx, user, authz.Clause{}); err != nil {
+				return err
+			}
+		} else if err := s.repo.SaveMembership(ctx, &Membership{UserID: user.ID, GroupID: group, Role: role}); err != nil {
+			return err
+		}
+
+		result, err = s.repo.GetMembership(ctx, user.ID, group)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditMemberAdd,
+		GroupID: group,
+		Target:  memberTarget(result),
+		Diff:    map[string]Change{"role": {New: result.Role}},
+	})
+	return result, nil
+}
+
+// UpdateMember changes the role of the user in the group.
+func (s *Service) UpdateMember(ctx context.Context, group GroupID, id UserID, role Role) (*Membership, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var (
+		result  *Membership
+		oldRole Role
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		membership, err := s.repo.GetMembership(ctx, id, group)
+		if err != nil {
+			return err
+		}
+		oldRole = membership.Role
+
+		policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.Resource{
+			Group: ResourceMemberships,
+			Value: map[string]any{
+				"group_id": group,
+				"user_id":  id,
+				"old_role": membership.Role,
+				"new_role": role,
+			},
+		})
+		if err != nil || !policy.Allow {
+			return errors.Join(err, ErrUnauthorized)
+		}
+
+		if membership.Role == Owner && role != Owner {
+			if err := s.keepOwner(ctx, group); err != nil {
+				return err
+			}
+		}
+
+		membership.Role = role
+		if err := s.saveMembership(ctx, membership); err != nil {
+			return err
+		}
+
+		result, err = s.repo.GetMembership(ctx, id, group)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditMemberUpdate,
+		GroupID: group,
+		Target:  memberTarget(result),
+		Diff:    map[string]Change{"role": {Old: oldRole, New: result.Role}},
+	})
+	return result, nil
+}
+
+// RemoveMember removes the user from the group. Users removed from their default
+// group are left without one.
+func (s *Service) RemoveMember(ctx context.Context, group GroupID, id UserID) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	var membership *Membership
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		membership, err = s.repo.GetMembership(ctx, id, group)
+		if err != nil {
+			return err
+		}
+
+		policy, err := s.enforcer.Can(ctx, principal, authz.Delete, authz.Resource{
+			Group: ResourceMemberships,
+			Value: map[string]any{
+				"group_id": group,
+				"user_id":  id,
+				"role":     membership.Role,
+			},
+		})
+		if err != nil || !policy.Allow {
+			return errors.Join(err, ErrUnauthorized)
+		}
+
+		if membership.Role == Owner {
+			if err := s.keepOwner(ctx, group); err != nil {
+				return err
+			}
+		}
+
+		user, err := s.repo.GetUser(ctx, id, authz.Clause{})
+		if err != nil {
+			return err
+		}
+		if user.GroupID != group {
+			return s.repo.DeleteMembership(ctx, id, group)
+		}
+
+		user.GroupID = GroupID{}
+		user.Role = Client
+		_, err = s.repo.SaveUser(ctx, user, authz.Clause{})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditMemberRemove,
+		GroupID: group,
+		Target:  memberTarget(membership),
+		Diff:    map[string]Change{"role": {Old: membership.Role}},
+	})
+	return nil
+}
+
+// saveMembership saves the membership, through the user when it is their default one.
+// It should be called in a transaction, after authorization.
+func (s *Service) saveMembership(ctx context.Context, membership *Membership) error {
+	user, err := s.repo.GetUser(ctx, membership.UserID, authz.Clause{})
+	if err != nil {
+		return err
+	}
+	if user.GroupID != membership.GroupID {
+		return s.repo.SaveMembership(ctx, membership)
+	}
+
+	user.Role = membership.Role
+	_, err = s.repo.SaveUser(ctx, user, authz.Clause{})
+	return err
+}
+
+func memberTarget(m *Membership) string {
+	return "groups/" + m.GroupID.String() + "/members/" + m.UserID.String()
+}
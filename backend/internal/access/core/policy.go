@@ -1,7 +1,10 @@
 package core
 
 import (
+	"context"
 	_ "embed"
+
+	"vpainless/internal/pkg/authz"
 )
 
 //go:embed policy/users.rego
@@ -10,9 +13,30 @@ var usersModule string
 //go:embed policy/groups.rego
 var groupsModule string
 
+//go:embed policy/memberships.rego
+var membershipsModule string
+
 func policies() map[string]string {
 	return map[string]string{
-		"access/users.rego":  usersModule,
-		"access/groups.rego": groupsModule,
+		"access/users.rego":       usersModule,
+		"access/groups.rego":      groupsModule,
+		"access/memberships.rego": membershipsModule,
 	}
 }
+
+// ReloadPolicies overrides or extends the embedded policies with the external
+// modules. A set that does not compile or define every policy is refused, and
+// the policies in effect are kept.
+func (s *Service) ReloadPolicies(ctx context.Context, modules map[string]string) error {
+	return s.enforcer.Reload(ctx, modules)
+}
+
+// CheckPolicies reports whether ReloadPolicies would accept the external modules.
+func (s *Service) CheckPolicies(ctx context.Context, modules map[string]string) error {
+	return s.enforcer.Check(ctx, modules)
+}
+
+// LogDecisions logs the policy decisions of access to the logger.
+func (s *Service) LogDecisions(logger *authz.DecisionLogger) {
+	s.enforcer.LogDecisions(logger)
+}
@@ -1,15 +1,11 @@
 package access.groups
 
+import data.authz.roles
 import rego.v1
 
 # Default deny
 default allow := false
 
-default partial := {
-	"condition": "",
-	"values": [],
-}
-
 # verbs := ["get", "list", "create", "update", "delete"]
 # TODO: move create user policy here when we add method and path varialbes to the request.
 
@@ -22,3 +18,10 @@ allow if {
 	input.principal.role == "client"
 	not input.principal.group_id
 }
+
+# managers should be able to run other groups, as their owners
+allow if {
+	input.action == "create"
+	input.principal.group_id
+	input.principal.role in roles.managers
+}
@@ -1,21 +1,11 @@
 package access_test.groups
 
 import data.access.groups.allow
-import data.access.groups.partial
-
-nil_partial := {
-	"condition": "",
-	"values": [],
-}
 
 test_default_allow if {
 	allow == false
 }
 
-test_default_partial if {
-	partial == nil_partial
-}
-
 ############# Action: create
 
 test_admins_should_not_be_able_to_create_groups if {
@@ -30,6 +20,36 @@ test_admins_should_not_be_able_to_create_groups if {
 	not allow with input as request
 }
 
+test_managers_of_a_group_should_be_able_to_create_other_groups if {
+	every role in ["owner", "admin"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "create",
+		}
+
+		allow with input as request
+	}
+}
+
+test_operators_and_viewers_should_not_be_able_to_create_groups if {
+	every role in ["operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "create",
+		}
+
+		not allow with input as request
+	}
+}
+
 test_clients_that_have_a_group_should_not_be_able_to_create_a_group if {
 	request := {
 		"principal": {
@@ -0,0 +1,73 @@
+package access.memberships
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+# input format
+# {
+#   "principal": {
+#     "group_id": "00000000-3a1c-4768-a723-cad22e955848",
+#     "id": "11000000-3e3c-49e7-852f-1b516782681d",
+#     "role": "admin"
+#   },
+#   "action": "update",
+#   "resource": {
+#     "group_id": "00000000-3a1c-4768-a723-cad22e955848",
+#     "user_id": "22000000-3e3c-49e7-852f-1b516782681d",
+#     "old_role": "client",
+#     "new_role": "operator"
+#   }
+# }
+
+################ LIST
+# Allow users to list the groups they are members of
+allow if {
+	input.action == "list"
+	input.principal.id
+	input.row.user_id = input.principal.id
+}
+
+# Allow staff to list the members of the group they act in
+allow if {
+	input.action == "list"
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.row.group_id = input.principal.group_id
+}
+
+################ CREATE
+# managers add users to the group they act in, with the roles they can grant
+allow if {
+	input.action == "create"
+	input.resource.group_id == input.principal.group_id
+	input.resource.role in roles.grantable[input.principal.role]
+}
+
+################ UPDATE
+# managers change the roles of the members of the group they act in, when they
+# could grant both their current and their new role. Owners giving up the
+# ownership of their group are checked to leave another owner behind.
+allow if {
+	input.action == "update"
+	input.resource.group_id == input.principal.group_id
+	input.resource.old_role in roles.grantable[input.principal.role]
+	input.resource.new_role in roles.grantable[input.principal.role]
+}
+
+################ DELETE
+# managers remove the members of the group they act in, when they could
+# grant their role
+allow if {
+	input.action == "delete"
+	input.resource.group_id == input.principal.group_id
+	input.resource.role in roles.grantable[input.principal.role]
+}
+
+# users leave the groups they are members of
+allow if {
+	input.action == "delete"
+	input.resource.user_id == input.principal.id
+}
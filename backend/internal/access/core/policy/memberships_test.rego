@@ -0,0 +1,153 @@
+package access_test.memberships
+
+import data.access.memberships.allow
+
+test_default_allow if {
+	allow == false
+}
+
+principal(role) := {
+	"id": "11000000-0000-0000-0000-000000000000",
+	"group_id": "00000000-0000-0000-0000-111111111111",
+	"role": role,
+}
+
+############# Action: list
+
+test_users_should_be_able_to_list_their_memberships if {
+	request := {"principal": principal("client"), "action": "list"}
+
+	allow with input as object.union(request, {"row": {"user_id": "11000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-222222222222"}})
+	not allow with input as object.union(request, {"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-111111111111"}})
+}
+
+test_staff_should_be_able_to_list_the_members_of_their_group if {
+	every role in ["owner", "admin", "operator", "viewer"] {
+		request := {"principal": principal(role), "action": "list"}
+
+		allow with input as object.union(request, {"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-111111111111"}})
+		not allow with input as object.union(request, {"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-222222222222"}})
+	}
+}
+
+############# Action: create
+
+test_managers_should_be_able_to_add_members_to_their_group if {
+	every role in ["owner", "admin"] {
+		request := {
+			"principal": principal(role),
+			"action": "create",
+			"resource": {"group_id": "00000000-0000-0000-0000-111111111111", "role": "operator"},
+		}
+
+		allow with input as request
+		not allow with input as object.union(request, {"resource": {"group_id": "00000000-0000-0000-0000-222222222222"}})
+	}
+}
+
+test_only_owners_should_be_able_to_add_owners if {
+	request := {
+		"action": "create",
+		"resource": {"group_id": "00000000-0000-0000-0000-111111111111", "role": "owner"},
+	}
+
+	allow with input as object.union(request, {"principal": principal("owner")})
+	not allow with input as object.union(request, {"principal": principal("admin")})
+}
+
+test_operators_viewers_and_clients_should_not_be_able_to_add_members if {
+	every role in ["operator", "viewer", "client"] {
+		request := {
+			"principal": principal(role),
+			"action": "create",
+			"resource": {"group_id": "00000000-0000-0000-0000-111111111111", "role": "client"},
+		}
+
+		not allow with input as request
+	}
+}
+
+############# Action: update
+
+test_admins_should_be_able_to_promote_members_of_their_group if {
+	request := {
+		"principal": principal("admin"),
+		"action": "update",
+		"resource": {
+			"group_id": "00000000-0000-0000-0000-111111111111",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"old_role": "client",
+			"new_role": "operator",
+		},
+	}
+
+	allow with input as request
+	not allow with input as object.union(request, {"resource": {"group_id": "00000000-0000-0000-0000-222222222222"}})
+	not allow with input as object.union(request, {"resource": {"new_role": "owner"}})
+	not allow with input as object.union(request, {"resource": {"old_role": "owner"}})
+}
+
+test_owners_should_be_able_to_demote_owners if {
+	request := {
+		"principal": principal("owner"),
+		"action": "update",
+		"resource": {
+			"group_id": "00000000-0000-0000-0000-111111111111",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"old_role": "owner",
+			"new_role": "admin",
+		},
+	}
+
+	allow with input as request
+}
+
+test_members_should_not_be_able_to_promote_themselves if {
+	every role in ["operator", "viewer", "client"] {
+		request := {
+			"principal": principal(role),
+			"action": "update",
+			"resource": {
+				"group_id": "00000000-0000-0000-0000-111111111111",
+				"user_id": "11000000-0000-0000-0000-000000000000",
+				"old_role": role,
+				"new_role": "admin",
+			},
+		}
+
+		not allow with input as request
+	}
+}
+
+############# Action: delete
+
+test_managers_should_be_able_to_remove_members_of_their_group if {
+	request := {
+		"principal": principal("admin"),
+		"action": "delete",
+		"resource": {
+			"group_id": "00000000-0000-0000-0000-111111111111",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"role": "client",
+		},
+	}
+
+	allow with input as request
+	not allow with input as object.union(request, {"resource": {"group_id": "00000000-0000-0000-0000-222222222222"}})
+	not allow with input as object.union(request, {"resource": {"role": "owner"}})
+}
+
+test_users_should_be_able_to_leave_groups if {
+	request := {
+		"principal": principal("client"),
+		"action": "delete",
+		"resource": {
+			"group_id": "00000000-0000-0000-0000-222222222222",
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"role": "viewer",
+		},
+	}
+
+	allow with input as request
+	not allow with input as object.union(request, {"resource": {"user_id": "22000000-0000-0000-0000-000000000000"}})
+}
@@ -1,15 +1,11 @@
 package access.users
 
+import data.authz.roles
 import rego.v1
 
 # Default deny
 default allow := false
 
-default partial := {
-	"condition": "",
-	"values": [],
-}
-
 # verbs := ["get", "list", "create", "update", "delete"]
 # TODO: move create user policy here when we add method and path varialbes to the request.
 
@@ -20,59 +16,31 @@ allow if {
 	input.principal.id == input.resource.id
 }
 
-# Allow admins view users of their own group
-# admins cannot view already existing clients
+# Allow staff view the members of the group they act in
+# staff cannot view already existing clients
 # if they are not in their group, but
-# they can create new clients and onboard them
+# managers can create new clients and onboard them
 allow if {
 	input.action = "get"
-	input.principal.role == "admin"
-	input.principal.id != input.resource.id
-}
-
-partial := clause if {
-	input.action = "get"
-	input.principal.role == "admin"
+	input.principal.role in roles.staff
 	input.principal.id != input.resource.id
-	clause := {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id],
-	}
+	input.principal.group_id in input.row.group_ids
 }
 
 ################ LIST
 # Allow clients to view themselves
-# TODO: write test
 allow if {
 	input.action = "list"
 	input.principal.role == "client"
+	input.row.id = input.principal.id
 }
 
-partial := clause if {
-	input.action = "list"
-	input.principal.role == "client"
-	clause := {
-		"condition": "id = ?",
-		"values": [input.principal.id],
-	}
-}
-
-# Allow admins to list users in ther group
-# TODO: write test
+# Allow staff to list the members of the group they act in
 allow if {
 	input.action = "list"
 	input.principal.group_id
-	input.principal.role == "admin"
-}
-
-partial := clause if {
-	input.action = "list"
-	input.principal.group_id
-	input.principal.role == "admin"
-	clause := {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id],
-	}
+	input.principal.role in roles.staff
+	input.principal.group_id in input.row.group_ids
 }
 
 ################ CREATE
@@ -83,10 +51,10 @@ allow if {
 	not input.principal
 }
 
-# admins should be able to create users in ther own group
+# managers should be able to create users in ther own group
 allow if {
 	input.action = "create"
-	input.principal.role = "admin"
+	input.principal.role in roles.managers
 	input.principal.group_id = input.resource.group_id
 }
 
@@ -109,37 +77,47 @@ allow if {
 #     "new_role": "client"
 #   }
 # }
-# admins editing themselves without changing their group
+# users editing themselves, without changing their role or group
 allow if {
 	input.action == "update"
-	input.principal.role == "admin"
-	input.resource.new_role in ["admin", "client"]
 	input.principal.id == input.resource.id
-	input.principal.group_id == input.resource.new_group_id
+	input.resource.new_role == input.principal.role
+	object.get(input, ["resource", "new_group_id"], "null") == object.get(input, ["principal", "group_id"], "null")
 }
 
-# clients editing themselves, without changing their role or group
+# clients leaving their group
 allow if {
 	input.action == "update"
 	input.principal.role == "client"
-	input.resource.new_role in ["client"]
 	input.principal.id == input.resource.id
-	object.get(input, ["resource", "new_group_id"], "null") in ["null", object.get(input, ["principal", "group_id"], "null")]
+	input.resource.new_role == "client"
+	not input.resource.new_group_id
+}
+
+# managers changing their own role, without changing their group.
+# Owners giving up the ownership of their group are checked to leave
+# another owner behind.
+allow if {
+	input.action == "update"
+	input.principal.id == input.resource.id
+	input.resource.new_role in roles.grantable[input.principal.role]
+	input.principal.group_id == input.resource.new_group_id
 }
 
-# admins are allowed editing other users in their group
+# managers are allowed editing other users in their group, when they
+# could grant both their current and their new role
 allow if {
 	input.action == "update"
-	input.principal.role == "admin"
-	input.resource.new_role in ["client", "admin"]
+	input.resource.old_role in roles.grantable[input.principal.role]
+	input.resource.new_role in roles.grantable[input.principal.role]
 	input.resource.old_group_id = input.principal.group_id
 	input.resource.new_group_id = input.principal.group_id
 }
 
-# admins are allowed to kick users out of their group
+# managers are allowed to kick users out of their group
 allow if {
 	input.action == "update"
-	input.principal.role == "admin"
+	input.resource.old_role in roles.grantable[input.principal.role]
 	input.resource.new_role in ["client"]
 	input.resource.old_group_id = input.principal.group_id
 	not input.resource.new_group_id
@@ -1,21 +1,11 @@
 package access_test.users
 
 import data.access.users.allow
-import data.access.users.partial
-
-nil_partial := {
-	"condition": "",
-	"values": [],
-}
 
 test_default_allow if {
 	allow == false
 }
 
-test_default_partial if {
-	partial == nil_partial
-}
-
 # TODO: write a test to ensure admins always should have a group id
 
 ############# Action: get
@@ -39,7 +29,6 @@ test_client_view_himself if {
 		"client",
 	)
 	allow with input as request
-	partial == nil_partial with input as request
 }
 
 test_client_cannot_view_others if {
@@ -49,7 +38,6 @@ test_client_cannot_view_others if {
 		"client",
 	)
 	not allow with input as request
-	partial == nil_partial with input as request
 }
 
 test_admin_partially_view_others if {
@@ -58,11 +46,8 @@ test_admin_partially_view_others if {
 		"22222222-3e3c-49e7-852f-1b516782681d",
 		"admin",
 	)
-	allow with input as request
-	partial == {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"group_ids": ["00000000-3a1c-4768-a723-cad22e955848"]}})
+	not allow with input as object.union(request, {"row": {"group_ids": ["00000000-0000-0000-0000-222222222222"]}})
 }
 
 ############# Action: list
@@ -76,12 +61,8 @@ test_clients_should_be_able_to_list_themselves if {
 		"action": "list"
 	}
 
-	allow with input as request
-
-	partial == {
-		"condition": "id = ?",
-		"values": [input.principal.id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"id": "11000000-0000-0000-0000-000000000000"}})
+	not allow with input as object.union(request, {"row": {"id": "22000000-0000-0000-0000-000000000000"}})
 }
 
 test_admins_should_be_able_to_list_their_users if {
@@ -94,11 +75,8 @@ test_admins_should_be_able_to_list_their_users if {
 		"action": "list"
 	}
 
-	allow with input as request
-	partial == {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id]
-	} with input as request
+	allow with input as object.union(request, {"row": {"group_ids": ["00000000-0000-0000-0000-111111111111"]}})
+	not allow with input as object.union(request, {"row": {"group_ids": ["00000000-0000-0000-0000-222222222222"]}})
 }
 
 ############# Action: create
@@ -368,3 +346,172 @@ test_admins_should_not_be_able_to_edit_clients_of_other_groups if {
 
 	not allow with input as request
 }
+
+############# Roles
+
+# admins should not be able to demote, or kick out, the owners of their group
+test_admins_should_not_be_able_to_edit_owners if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"id": "22000000-0000-0000-0000-000000000000",
+			"old_group_id": "00000000-0000-0000-0000-000000000011",
+			"new_group_id": "00000000-0000-0000-0000-000000000011",
+			"old_role": "owner",
+			"new_role": "client",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_admins_should_not_be_able_to_kick_owners_out if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"id": "22000000-0000-0000-0000-000000000000",
+			"old_group_id": "00000000-0000-0000-0000-000000000011",
+			"old_role": "owner",
+			"new_role": "client",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_admins_should_not_be_able_to_grant_ownership if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"id": "22000000-0000-0000-0000-000000000000",
+			"old_group_id": "00000000-0000-0000-0000-000000000011",
+			"new_group_id": "00000000-0000-0000-0000-000000000011",
+			"old_role": "admin",
+			"new_role": "owner",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_owners_should_be_able_to_grant_every_role if {
+	every role in ["owner", "admin", "operator", "viewer", "client"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": "owner",
+			},
+			"action": "update",
+			"resource": {
+				"id": "22000000-0000-0000-0000-000000000000",
+				"old_group_id": "00000000-0000-0000-0000-000000000011",
+				"new_group_id": "00000000-0000-0000-0000-000000000011",
+				"old_role": "owner",
+				"new_role": role,
+			},
+		}
+
+		allow with input as request
+	}
+}
+
+test_owners_should_be_able_to_give_up_their_ownership if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "owner",
+		},
+		"action": "update",
+		"resource": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"old_group_id": "00000000-0000-0000-0000-000000000011",
+			"new_group_id": "00000000-0000-0000-0000-000000000011",
+			"old_role": "owner",
+			"new_role": "admin",
+		},
+	}
+
+	allow with input as request
+}
+
+test_operators_and_viewers_should_not_be_able_to_manage_users if {
+	every role in ["operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "update",
+			"resource": {
+				"id": "22000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"old_group_id": "00000000-0000-0000-0000-000000000011",
+				"new_group_id": "00000000-0000-0000-0000-000000000011",
+				"old_role": "client",
+				"new_role": "client",
+			},
+		}
+
+		not allow with input as request
+		not allow with input as object.union(request, {"action": "create"})
+	}
+}
+
+test_operators_and_viewers_should_not_be_able_to_promote_themselves if {
+	every role in ["operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "update",
+			"resource": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"old_group_id": "00000000-0000-0000-0000-000000000011",
+				"new_group_id": "00000000-0000-0000-0000-000000000011",
+				"old_role": role,
+				"new_role": "admin",
+			},
+		}
+
+		not allow with input as request
+		allow with input as object.union(request, {"resource": {"new_role": role}})
+	}
+}
+
+test_staff_should_be_able_to_view_users_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"resource": {"id": "22000000-0000-0000-0000-000000000000"},
+			"row": {"group_ids": ["00000000-0000-0000-0000-000000000011"]},
+		}
+
+		allow with input as object.union(request, {"action": "get"})
+		allow with input as object.union(request, {"action": "list"})
+		not allow with input as object.union(request, {"action": "list", "row": {"group_ids": ["00000000-0000-0000-0000-000000000022"]}})
+	}
+}
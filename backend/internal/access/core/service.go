@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"vpainless/internal/pkg/authz"
 	"vpainless/internal/pkg/db"
@@ -15,6 +16,53 @@ type usersRepository interface {
 	ListUsers(ctx context.Context, partial authz.Clause) ([]*User, error)
 }
 
+type membershipsRepository interface {
+	// GetMembership returns ErrNotFound if the user is not a member of the group.
+	GetMembership(ctx context.Context, user UserID, group GroupID) (*Membership, error)
+	ListMemberships(ctx context.Context, partial authz.Clause) ([]*Membership, error)
+	SaveMembership(ctx context.Context, membership *Membership) error
+	DeleteMembership(ctx context.Context, user UserID, group GroupID) error
+}
+
+type twoFactorsRepository interface {
+	// GetTwoFactor returns ErrNotFound if the user has not enrolled.
+	GetTwoFactor(ctx context.Context, user UserID) (*TwoFactor, error)
+	SaveTwoFactor(ctx context.Context, twoFactor *TwoFactor) error
+	// DeleteTwoFactor deletes the secret and the recovery codes of the user.
+	DeleteTwoFactor(ctx context.Context, user UserID) error
+	// SaveRecoveryCodes replaces the recovery codes of the user with the hashes.
+	SaveRecoveryCodes(ctx context.Context, user UserID, hashes []string) error
+	// UseRecoveryCode deletes the recovery code, or returns ErrNotFound if the
+	// user does not have it.
+	UseRecoveryCode(ctx context.Context, user UserID, hash string) error
+	// UseTOTPStep records the step of an accepted TOTP code, or returns ErrNotFound
+	// if a code of the step, or of a later one, is already accepted.
+	UseTOTPStep(ctx context.Context, user UserID, step int64) error
+}
+
+type identitiesRepository interface {
+	// FindIdentity returns the user the identity is linked to, or ErrNotFound.
+	FindIdentity(ctx context.Context, issuer, subject string) (UserID, error)
+	SaveIdentity(ctx context.Context, issuer, subject string, user UserID) error
+}
+
+type attemptsRepository interface {
+	// GetAttempts returns ErrNotFound if there are no attempts with the key.
+	GetAttempts(ctx context.Context, key string) (*Attempts, error)
+	SaveAttempts(ctx context.Context, attempts *Attempts) error
+	DeleteAttempts(ctx context.Context, key string) error
+}
+
+type sessionsRepository interface {
+	// GetSession returns the session of the token hash, or ErrNotFound.
+	GetSession(ctx context.Context, hash string) (*Session, error)
+	SaveSession(ctx context.Context, hash string, session *Session) error
+	// DeleteSession returns ErrNotFound if there is no session with the token hash.
+	DeleteSession(ctx context.Context, hash string) error
+	// DeleteExpiredSessions deletes the sessions expired before the given time.
+	DeleteExpiredSessions(ctx context.Context, before time.Time) error
+}
+
 type groupsRepository interface {
 	GetGroup(ctx context.Context, id GroupID) (*Group, error)
 	SaveGroup(ctx context.Context, group *Group) (*Group, error)
@@ -22,11 +70,18 @@ type groupsRepository interface {
 
 type hostingAdapter interface {
 	NotifyGroupCreated(ctx context.Context, g *Group) error
+	RecordAudit(ctx context.Context, e *AuditEvent) error
+	RequiresTwoFactor(ctx context.Context, id GroupID) (bool, error)
 }
 
 type AccessRepository interface {
 	db.Transactor
 	usersRepository
+	membershipsRepository
+	twoFactorsRepository
+	identitiesRepository
+	attemptsRepository
+	sessionsRepository
 	groupsRepository
 }
 
@@ -34,6 +89,10 @@ type Service struct {
 	enforcer *authz.Validator
 	repo     AccessRepository
 	hosting  hostingAdapter
+	// identities is the external identity provider, if any. identityGroups
+	// maps the groups of the provider to the groups users are provisioned into.
+	identities     identityProvider
+	identityGroups map[string]GroupID
 }
 
 func NewService(repo AccessRepository, adapter hostingAdapter) *Service {
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/middleware"
+)
+
+// SessionTTL is how long a session authenticates the requests of the user.
+const SessionTTL = 8 * time.Hour
+
+// Session is a login of a user with their password, and their two factor code if
+// they enabled it. Its token authenticates the requests of the user as a Bearer
+// token until it expires, so the code is sent once per login, rather than with
+// every request.
+type Session struct {
+	UserID UserID
+	// TwoFactor reports whether the user logged in with a second factor.
+	TwoFactor bool
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// CreateSession returns the token of a new session of the principal, who is
+// authenticated with their password, and their two factor code if they enabled it.
+// Sessions are only created with a password; the id tokens of the identity provider
+// are sent as they are.
+func (s *Service) CreateSession(ctx context.Context, creds middleware.Credentials) (string, *Session, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return "", nil, ErrUnauthorized
+	}
+
+	if creds.Token != "" {
+		return "", nil, errors.Join(ErrBadRequest, errors.New("sessions are created with a username and password"))
+	}
+
+	token, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	session := &Session{
+		UserID:    UserID{principal.ID},
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionTTL),
+	}
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		// Users who enabled two factor authentication are only authenticated
+		// with their password along with a code.
+		twoFactor, err := s.repo.GetTwoFactor(ctx, session.UserID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		session.TwoFactor = err == nil && twoFactor.Enabled
+
+		if err := s.repo.DeleteExpiredSessions(ctx, now); err != nil {
+			return err
+		}
+		return s.repo.SaveSession(ctx, hashSessionToken(token), session)
+	}); err != nil {
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// DeleteSession logs the principal out of the session of the token.
+func (s *Service) DeleteSession(ctx context.Context, token string) error {
+	if _, err := authz.GetPrincipal(ctx); err != nil {
+		return ErrUnauthorized
+	}
+
+	if token == "" {
+		return errors.Join(ErrBadRequest, errors.New("not logged in with a session"))
+	}
+
+	return s.repo.DeleteSession(ctx, hashSessionToken(token))
+}
+
+// authenticateSession authenticates the user of the session token. It returns
+// ErrNotFound if the token is not of a session, which is then verified by the
+// identity provider.
+func (s *Service) authenticateSession(ctx context.Context, token string) (*User, bool, error) {
+	session, err := s.repo.GetSession(ctx, hashSessionToken(token))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !time.Now().Before(session.ExpiresAt) {
+		return nil, false, errors.Join(ErrUnauthorized, errors.New("the session is expired"))
+	}
+
+	user, err := s.repo.GetUser(ctx, session.UserID, authz.Clause{})
+	if err != nil {
+		return nil, false, err
+	}
+	if user == nil {
+		return nil, false, ErrUnauthorized
+	}
+
+	return user, session.TwoFactor, nil
+}
+
+// hashSessionToken hashes the token; only the hashes of the tokens are kept.
+func hashSessionToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/totp"
+)
+
+const (
+	// twoFactorIssuer is the name authenticator apps show the codes under.
+	twoFactorIssuer   = "Vpainless"
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+// TwoFactor is the TOTP secret of a user. It is only enforced once the user
+// verifies a code of it.
+type TwoFactor struct {
+	UserID  UserID
+	Secret  string
+	Enabled bool
+}
+
+// TwoFactorEnrollment is what authenticator apps are set up with. URI is the
+// provisioning uri, to be shown as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// EnrollTwoFactor generates a new secret for the principal. The secret is pending
+// until a code of it is verified. Enrolling again replaces a pending secret.
+func (s *Service) EnrollTwoFactor(ctx context.Context) (*TwoFactorEnrollment, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var result *TwoFactorEnrollment
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.GetUser(ctx, UserID{principal.ID}, authz.Clause{})
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrNotFound
+		}
+
+		twoFactor, err := s.repo.GetTwoFactor(ctx, user.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if err == nil && twoFactor.Enabled {
+			return errors.Join(ErrAlreadyExists, errors.New("two factor authentication is already enabled"))
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.SaveTwoFactor(ctx, &TwoFactor{UserID: user.ID, Secret: secret}); err != nil {
+			return err
+		}
+
+		result = &TwoFactorEnrollment{
+			Secret: secret,
+			URI:    totp.URI(twoFactorIssuer, user.Username, secret),
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// VerifyTwoFactor enables the pending secret of the principal, if the code is
+// valid. It returns the recovery codes, which are not shown again.
+func (s *Service) VerifyTwoFactor(ctx context.Context, code string) ([]string, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		twoFactor, err := s.repo.GetTwoFactor(ctx, UserID{principal.ID})
+		if err != nil {
+			return err
+		}
+		if twoFactor.Enabled {
+			return errors.Join(ErrAlreadyExists, errors.New("two factor authentication is already enabled"))
+		}
+
+		step, ok := totp.Match(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return errors.Join(ErrBadRequest, errors.New("invalid code"))
+		}
+
+		twoFactor.Enabled = true
+		if err := s.repo.SaveTwoFactor(ctx, twoFactor); err != nil {
+			return err
+		}
+
+		// The code is used up, it can not log in right after.
+		if err := s.repo.UseTOTPStep(ctx, twoFactor.UserID, step); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				err = errors.Join(ErrBadRequest, errors.New("code is already used"))
+			}
+			return err
+		}
+
+		return s.repo.SaveRecoveryCodes(ctx, twoFactor.UserID, hashes)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditTwoFactorEnable,
+		GroupID: GroupID{principal.GroupID},
+		Target:  "users/" + principal.ID.String(),
+	})
+	return codes, nil
+}
+
+// DisableTwoFactor removes the secret and the recovery codes of the principal.
+// Disabled users authenticate with their password only.
+func (s *Service) DisableTwoFactor(ctx context.Context) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		if _, err := s.repo.GetTwoFactor(ctx, UserID{principal.ID}); err != nil {
+			return err
+		}
+
+		return s.repo.DeleteTwoFactor(ctx, UserID{principal.ID})
+	}); err != nil {
+		return err
+	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditTwoFactorDisable,
+		GroupID: GroupID{principal.GroupID},
+		Target:  "users/" + principal.ID.String(),
+	})
+	return nil
+}
+
+// checkTwoFactor verifies the code of a user with two factor authentication
+// enabled. The code is either a TOTP code, or a recovery code, which is used up.
+// TOTP codes are accepted once; a code of the last accepted step, or of a step
+// before it, is rejected as a replay.
+func (s *Service) checkTwoFactor(ctx context.Context, twoFactor *TwoFactor, code string) error {
+	if code == "" {
+		return errors.Join(ErrUnauthorized, errors.New("two factor code required"))
+	}
+
+	if step, ok := totp.Match(twoFactor.Secret, code, time.Now()); ok {
+		err := s.repo.UseTOTPStep(ctx, twoFactor.UserID, step)
+		if errors.Is(err, ErrNotFound) {
+			return errors.Join(ErrUnauthorized, errors.New("two factor code is already used"))
+		}
+		return err
+	}
+
+	err := s.repo.UseRecoveryCode(ctx, twoFactor.UserID, hashRecoveryCode(code))
+	if errors.Is(err, ErrNotFound) {
+		return errors.Join(ErrUnauthorized, errors.New("invalid two factor code"))
+	}
+	return err
+}
+
+// generateRecoveryCodes returns the recovery codes, formatted as xxxx-xxxx-xxxx-xxxx,
+// and their hashes to be kept.
+func generateRecoveryCodes() (codes []string, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range recoveryCodeCount {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery codes: %w", err)
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(b))
+		code := raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes the code, ignoring its case and dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(normalized)))
+}
@@ -20,7 +20,10 @@ type Role string
 
 const (
 	Client        Role = "client"
+	Viewer        Role = "viewer"
+	Operator      Role = "operator"
 	Admin         Role = "admin"
+	Owner         Role = "owner"
 	ResourceUsers      = "users"
 )
 
@@ -37,6 +40,7 @@ var (
 	ErrNotFound      = errors.New("not found")
 	ErrUnauthorized  = errors.New("unauthorized")
 	ErrBadRequest    = errors.New("bad request")
+	ErrForbidden     = errors.New("forbidden")
 )
 
 func generatePassword(username, password string) string {
@@ -68,7 +72,10 @@ func (s *Service) GetUser(ctx context.Context, id UserID) (*User, error) {
 }
 
 func (s *Service) UpdateUser(ctx context.Context, newUser *User) (*User, error) {
-	var result *User
+	var (
+		result *User
+		diff   map[string]Change
+	)
 	principal, err := authz.GetPrincipal(ctx)
 	if err != nil {
 		return nil, ErrUnauthorized
@@ -110,16 +117,51 @@ func (s *Service) UpdateUser(ctx context.Context, newUser *User) (*User, error)
 			return errors.Join(err, ErrUnauthorized)
 		}
 
+		if oldUser.Role == Owner && (newUser.Role != Owner || newUser.GroupID != oldUser.GroupID) {
+			if err := s.keepOwner(ctx, oldUser.GroupID); err != nil {
+				return err
+			}
+		}
+
 		result, err = s.repo.SaveUser(ctx, newUser, policy.Partial)
-		return err
+		if err != nil {
+			return err
+		}
+
+		diff = userDiff(oldUser, result)
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditUserUpdate,
+		GroupID: GroupID{principal.GroupID},
+		Target:  "users/" + result.ID.String(),
+		Diff:    diff,
+	})
 	return result, nil
 }
 
+// keepOwner fails when the group has a single owner, who is about to leave the
+// group or give up their ownership. Every group should have at least one owner.
+func (s *Service) keepOwner(ctx context.Context, id GroupID) error {
+	owners, err := s.repo.ListMemberships(ctx, authz.Clause{
+		Condition: "m.group_id = ? and m.role = ?",
+		Values:    []any{id, Owner},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(owners) < 2 {
+		return errors.Join(ErrBadRequest, errors.New("the group should have another owner"))
+	}
+
+	return nil
+}
+
 func (s *Service) ListUsers(ctx context.Context) ([]*User, error) {
 	principal, err := authz.GetPrincipal(ctx)
 	if err != nil {
@@ -147,10 +189,20 @@ func (s *Service) ListUsers(ctx context.Context) ([]*User, error) {
 	return result, nil
 }
 
+// CreateUser creates a client. Anonymous registrations are limited per address.
 func (s *Service) CreateUser(ctx context.Context, u *User) (*User, error) {
 	var result *User
 	var exists bool
 
+	ip := middleware.GetClientIP(ctx)
+	_, err := authz.GetPrincipal(ctx)
+	anonymous := err != nil && ip != ""
+	if anonymous {
+		if _, err := s.checkLockout(ctx, registrationKey(ip)); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
 		var err error
 		result, err = s.repo.FindUserByName(ctx, u.Username)
@@ -187,31 +239,147 @@ func (s *Service) CreateUser(ctx context.Context, u *User) (*User, error) {
 		return nil, err
 	}
 
+	// Taken usernames count too, so they cannot be probed without a limit.
+	if anonymous {
+		s.recordAttempt(ctx, registrationKey(ip), RegistrationLockout, nil)
+	}
+
 	if exists {
 		return result, ErrAlreadyExists
 	}
+
+	s.audit(ctx, &AuditEvent{
+		Action:  AuditUserCreate,
+		GroupID: result.GroupID,
+		Target:  "users/" + result.ID.String(),
+		Diff:    userDiff(nil, result),
+	})
 	return result, nil
 }
 
+// Authenticate authenticates the user of the credentials. Addresses and usernames
+// with too many failed logins are locked out for a while, before the credentials
+// are checked.
 func (s *Service) Authenticate(ctx context.Context, creds middleware.Credentials) (authz.Principal, error) {
+	if ip := middleware.GetClientIP(ctx); ip != "" {
+		if _, err := s.checkLockout(ctx, ipKey(ip)); err != nil {
+			return authz.Principal{}, err
+		}
+	}
+
+	var (
+		user *User
+		// twoFactor reports whether the user authenticated with a second factor.
+		twoFactor bool
+		err       error
+	)
+	if creds.Token != "" {
+		user, twoFactor, err = s.authenticateSession(ctx, creds.Token)
+		if errors.Is(err, ErrNotFound) {
+			user, twoFactor, err = s.authenticateToken(ctx, creds.Token)
+		}
+	} else {
+		user, twoFactor, err = s.authenticatePassword(ctx, creds)
+	}
+	if err != nil {
+		return authz.Principal{}, err
+	}
+
+	principal := authz.Principal{
+		ID:      user.ID.UUID,
+		GroupID: user.GroupID.UUID,
+		Role:    authz.Role(user.Role),
+	}
+	if creds.Group != "" {
+		// Users act in their other groups with the role they have in them.
+		group, err := uuid.FromString(creds.Group)
+		if err != nil {
+			return authz.Principal{}, errors.Join(ErrUnauthorized, err)
+		}
+
+		membership, err := s.repo.GetMembership(ctx, user.ID, GroupID{group})
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				err = errors.Join(ErrUnauthorized, errors.New("not a member of the group"))
+			}
+			return authz.Principal{}, err
+		}
+
+		principal.GroupID = membership.GroupID.UUID
+		principal.Role = authz.Role(membership.Role)
+	}
+
+	// Admins of the groups that require two factor authentication act as clients
+	// until they enable it, which clients can still do.
+	if !twoFactor && (principal.Role == authz.Owner || principal.Role == authz.Admin) {
+		required, err := s.hosting.RequiresTwoFactor(ctx, GroupID{principal.GroupID})
+		if err != nil {
+			return authz.Principal{}, err
+		}
+		if required {
+			principal.Role = authz.Client
+		}
+	}
+
+	return principal, nil
+}
+
+// authenticatePassword authenticates the user with their password, and their two
+// factor code if they enabled it.
+func (s *Service) authenticatePassword(ctx context.Context, creds middleware.Credentials) (*User, bool, error) {
+	failed, err := s.checkLockout(ctx, userKey(creds.Username))
+	if err != nil {
+		return nil, false, err
+	}
+
 	user, err := s.repo.FindUserByName(ctx, creds.Username)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
+			s.audit(ctx, &AuditEvent{
+				Action: AuditLoginFailure,
+				Target: "users",
+				Diff:   map[string]Change{"username": {New: creds.Username}},
+			})
+			s.recordLoginFailure(ctx, nil)
 			err = ErrUnauthorized
 		}
-		return authz.Principal{}, err
+		return nil, false, err
 	}
 
 	passwordHash := generatePassword(creds.Username, creds.Password)
 	if passwordHash != user.Password {
 		// TODO: remove this
 		slog.DebugContext(ctx, "authorize failed", "hash", passwordHash)
-		return authz.Principal{}, ErrUnauthorized
+		s.audit(ctx, &AuditEvent{
+			Action:  AuditLoginFailure,
+			GroupID: user.GroupID,
+			Target:  "users/" + user.ID.String(),
+		})
+		s.recordLoginFailure(ctx, user)
+		return nil, false, ErrUnauthorized
 	}
 
-	return authz.Principal{
-		ID:      user.ID.UUID,
-		GroupID: user.GroupID.UUID,
-		Role:    authz.Role(user.Role),
-	}, nil
+	twoFactor, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+
+	enabled := err == nil && twoFactor.Enabled
+	if enabled {
+		if err := s.checkTwoFactor(ctx, twoFactor, creds.OTP); err != nil {
+			s.audit(ctx, &AuditEvent{
+				Action:  AuditLoginFailure,
+				GroupID: user.GroupID,
+				Target:  "users/" + user.ID.String(),
+			})
+			s.recordLoginFailure(ctx, user)
+			return nil, false, err
+		}
+	}
+
+	// The failed logins of the user are forgiven once they log in.
+	if failed {
+		s.clearAttempts(ctx, userKey(user.Username))
+	}
+	return user, enabled, nil
 }
@@ -19,6 +19,20 @@ type HostingService interface {
 	DeleteInstance(ctx context.Context, id core.InstanceID) error
 	CreateInstance(ctx context.Context) (*core.Instance, error)
 	ListInstances(ctx context.Context) ([]*core.Instance, error)
+	RotateCredentials(ctx context.Context, id core.InstanceID) (*core.Instance, error)
+	ListHealthChecks(ctx context.Context, id core.InstanceID) ([]*core.HealthCheck, error)
+	RenewInstance(ctx context.Context, id core.InstanceID) (*core.Instance, error)
+	ruleSetService
+	clientService
+	probeService
+	notificationService
+	usageService
+	quotaService
+	costService
+	rateLimitService
+	approvalService
+	auditService
+	decisionService
 }
 
 // NewAdapter creates a new rest adapter to interact with hosting core
@@ -38,6 +52,14 @@ func writeJSONError(w http.ResponseWriter, status int, err error) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 }
 
+func fromPointer[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
 func toPointer[T comparable](v T) *T {
 	var zero T
 	if v == zero {
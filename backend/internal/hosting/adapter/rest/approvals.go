@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type approvalService interface {
+	ListInstanceRequests(ctx context.Context, id core.GroupID) ([]*core.InstanceRequest, error)
+	ApproveInstanceRequest(ctx context.Context, id core.InstanceRequestID) (*core.Instance, error)
+	RejectInstanceRequest(ctx context.Context, id core.InstanceRequestID) (*core.InstanceRequest, error)
+}
+
+func (a *Adapter) ListInstanceRequests(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	requests, err := a.service.ListInstanceRequests(ctx, core.GroupID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error listing instance requests", err)
+		return
+	}
+
+	result := []api.InstanceRequest{}
+	for _, request := range requests {
+		result = append(result, mapAPIInstanceRequest(request))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) ApproveInstanceRequest(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	instance, err := a.service.ApproveInstanceRequest(ctx, core.InstanceRequestID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error approving instance request", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, api.Instance{
+		Id:     toPointer(instance.ID.UUID),
+		Owner:  toPointer(instance.Owner.UUID),
+		Ip:     toPointer(instance.IP.String()),
+		Status: toPointer(api.InstanceStatus(instance.Status)),
+		Shared: toPointer(instance.Shared),
+		Region: toPointer(instance.Region),
+	})
+}
+
+func (a *Adapter) RejectInstanceRequest(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	request, err := a.service.RejectInstanceRequest(ctx, core.InstanceRequestID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error rejecting instance request", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIInstanceRequest(request))
+}
+
+func mapAPIInstanceRequest(r *core.InstanceRequest) api.InstanceRequest {
+	result := api.InstanceRequest{
+		Id:        toPointer(r.ID.UUID),
+		UserId:    toPointer(r.UserID.UUID),
+		GroupId:   toPointer(r.GroupID.UUID),
+		Status:    toPointer(api.InstanceRequestStatus(r.Status)),
+		CreatedAt: toPointer(r.CreatedAt),
+		DecidedAt: r.DecidedAt,
+	}
+	if r.DecidedBy != nil {
+		result.DecidedBy = toPointer(r.DecidedBy.UUID)
+	}
+	if r.InstanceID != nil {
+		result.InstanceId = toPointer(r.InstanceID.UUID)
+	}
+	return result
+}
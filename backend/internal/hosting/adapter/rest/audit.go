@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+)
+
+type auditService interface {
+	ListAuditEntries(ctx context.Context, filter core.AuditFilter) ([]*core.AuditEntry, error)
+}
+
+func (a *Adapter) ListAuditEntries(w http.ResponseWriter, r *http.Request, params api.ListAuditEntriesParams) {
+	filter := core.AuditFilter{
+		Action: core.AuditAction(fromPointer(params.Action)),
+		Target: fromPointer(params.Target),
+		Since:  fromPointer(params.Since),
+		Until:  fromPointer(params.Until),
+		Limit:  fromPointer(params.Limit),
+	}
+	if params.PrincipalId != nil {
+		filter.PrincipalID = core.UserID{UUID: *params.PrincipalId}
+	}
+
+	ctx := r.Context()
+	entries, err := a.service.ListAuditEntries(ctx, filter)
+	if err != nil {
+		writeServiceError(ctx, w, "error listing audit entries", err)
+		return
+	}
+
+	result := []api.AuditEntry{}
+	for _, e := range entries {
+		result = append(result, mapAPIAuditEntry(e))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func mapAPIAuditEntry(e *core.AuditEntry) api.AuditEntry {
+	diff := map[string]api.AuditChange{}
+	for field, change := range e.Diff {
+		var c api.AuditChange
+		if change.Old != nil {
+			c.Old = toPointer(change.Old)
+		}
+		if change.New != nil {
+			c.New = toPointer(change.New)
+		}
+		diff[field] = c
+	}
+
+	result := api.AuditEntry{
+		Id:        toPointer(e.ID.UUID),
+		CreatedAt: toPointer(e.CreatedAt),
+		Action:    toPointer(string(e.Action)),
+		Target:    toPointer(e.Target),
+		Diff:      &diff,
+	}
+	if !e.RequestID.IsNil() {
+		result.RequestId = toPointer(e.RequestID)
+	}
+	if e.PrincipalID != nil {
+		result.PrincipalId = toPointer(e.PrincipalID.UUID)
+	}
+	if !e.GroupID.IsNil() {
+		result.GroupId = toPointer(e.GroupID.UUID)
+	}
+	return result
+}
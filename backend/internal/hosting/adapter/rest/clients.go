@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type clientService interface {
+	ListClients(ctx context.Context, id core.InstanceID) ([]*core.InstanceClient, error)
+	RevokeClient(ctx context.Context, id core.InstanceID, userID core.UserID) error
+	GetGroupSettings(ctx context.Context, id core.GroupID) (*core.GroupSettings, error)
+	UpdateGroupSettings(ctx context.Context, id core.GroupID, settings core.GroupSettings) (*core.GroupSettings, error)
+}
+
+func (a *Adapter) ListInstanceClients(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	clients, err := a.service.ListClients(ctx, core.InstanceID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error listing instance clients", err)
+		return
+	}
+
+	result := []api.InstanceClient{}
+	for _, c := range clients {
+		result = append(result, api.InstanceClient{
+			Id:         toPointer(c.ID.UUID),
+			InstanceId: toPointer(c.InstanceID.UUID),
+			UserId:     toPointer(c.UserID.UUID),
+			CreatedAt:  toPointer(c.CreatedAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) DeleteInstanceClient(w http.ResponseWriter, r *http.Request, id uuid.UUID, userID uuid.UUID) {
+	ctx := r.Context()
+	if err := a.service.RevokeClient(ctx, core.InstanceID{UUID: id}, core.UserID{UUID: userID}); err != nil {
+		writeServiceError(ctx, w, "error revoking instance client", err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func (a *Adapter) GetGroupSettings(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	settings, err := a.service.GetGroupSettings(ctx, core.GroupID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error getting group settings", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIGroupSettings(settings))
+}
+
+func (a *Adapter) PutGroupSettings(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req api.PutGroupSettingsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	settings := core.GroupSettings{
+		SharedInstances:       fromPointer(req.SharedInstances),
+		MaxClientsPerInstance: fromPointer(req.MaxClientsPerInstance),
+		SNIPool:               fromPointer(req.SniPool),
+		AutoRenew:             fromPointer(req.AutoRenew),
+		MaxRenewalsPerDay:     fromPointer(req.MaxRenewalsPerDay),
+		MinProbeReporters:     fromPointer(req.MinProbeReporters),
+		Regions:               fromPointer(req.Regions),
+		RotationInterval:      time.Duration(fromPointer(req.RotationIntervalHours)) * time.Hour,
+		TTL:                   time.Duration(fromPointer(req.TtlHours)) * time.Hour,
+		RenewalGracePeriod:    time.Duration(fromPointer(req.RenewalGraceMinutes)) * time.Minute,
+		Quota: core.Quota{
+			TrafficBytes: fromPointer(req.TrafficQuotaBytes),
+			ActiveDays:   fromPointer(req.ActiveDaysQuota),
+		},
+		MonthlyBudget:    fromPointer(req.MonthlyBudgetCents),
+		UserRateLimit:    mapCoreRateLimit(req.UserRateLimit),
+		GroupRateLimit:   mapCoreRateLimit(req.GroupRateLimit),
+		RequireApproval:  fromPointer(req.RequireApproval),
+		DecisionLogs:     fromPointer(req.DecisionLogs),
+		RequireTwoFactor: fromPointer(req.RequireTwoFactor),
+	}
+	if req.MaxClientsPerInstance == nil {
+		settings.MaxClientsPerInstance = core.DefaultMaxClientsPerInstance
+	}
+	if req.MaxRenewalsPerDay == nil {
+		settings.MaxRenewalsPerDay = core.DefaultMaxRenewalsPerDay
+	}
+	if req.MinProbeReporters == nil {
+		settings.MinProbeReporters = core.DefaultMinProbeReporters
+	}
+	if req.RenewalGraceMinutes == nil {
+		settings.RenewalGracePeriod = core.DefaultRenewalGracePeriod
+	}
+	if req.UserRateLimit == nil {
+		settings.UserRateLimit = core.DefaultUserRateLimit
+	}
+	if req.GroupRateLimit == nil {
+		settings.GroupRateLimit = core.DefaultGroupRateLimit
+	}
+
+	result, err := a.service.UpdateGroupSettings(ctx, core.GroupID{UUID: id}, settings)
+	if err != nil {
+		writeServiceError(ctx, w, "error updating group settings", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIGroupSettings(result))
+}
+
+func mapAPIGroupSettings(s *core.GroupSettings) api.GroupSettings {
+	return api.GroupSettings{
+		SharedInstances:       toPointer(s.SharedInstances),
+		MaxClientsPerInstance: toPointer(s.MaxClientsPerInstance),
+		SniPool:               toSlicePointer(s.SNIPool),
+		AutoRenew:             toPointer(s.AutoRenew),
+		MaxRenewalsPerDay:     toPointer(s.MaxRenewalsPerDay),
+		MinProbeReporters:     toPointer(s.MinProbeReporters),
+		Regions:               toSlicePointer(s.Regions),
+		RotationIntervalHours: toPointer(int(s.RotationInterval.Hours())),
+		TtlHours:              toPointer(int(s.TTL.Hours())),
+		RenewalGraceMinutes:   toPointer(int(s.RenewalGracePeriod.Minutes())),
+		TrafficQuotaBytes:     toPointer(s.Quota.TrafficBytes),
+		ActiveDaysQuota:       toPointer(s.Quota.ActiveDays),
+		MonthlyBudgetCents:    toPointer(s.MonthlyBudget),
+		RequireApproval:       toPointer(s.RequireApproval),
+		DecisionLogs:          toPointer(s.DecisionLogs),
+		RequireTwoFactor:      toPointer(s.RequireTwoFactor),
+		UserRateLimit:         &api.RateLimit{Burst: toPointer(s.UserRateLimit.Burst), PerHour: toPointer(s.UserRateLimit.PerHour)},
+		GroupRateLimit:        &api.RateLimit{Burst: toPointer(s.GroupRateLimit.Burst), PerHour: toPointer(s.GroupRateLimit.PerHour)},
+	}
+}
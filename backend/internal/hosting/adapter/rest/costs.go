@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type costService interface {
+	GetGroupCosts(ctx context.Context, id core.GroupID, month time.Time) (*core.CostReport, error)
+}
+
+func (a *Adapter) GetGroupCosts(w http.ResponseWriter, r *http.Request, id uuid.UUID, params api.GetGroupCostsParams) {
+	month := time.Now()
+	if params.Month != nil {
+		var err error
+		month, err = time.Parse("2006-01", *params.Month)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid month: %w", err))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	report, err := a.service.GetGroupCosts(ctx, core.GroupID{UUID: id}, month)
+	if err != nil {
+		writeServiceError(ctx, w, "error getting group costs", err)
+		return
+	}
+
+	users := []api.UserCost{}
+	for _, u := range report.Users {
+		users = append(users, api.UserCost{
+			UserId:    toPointer(u.UserID.UUID),
+			Instances: toPointer(u.Instances),
+			CostCents: toPointer(u.Cents),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, api.CostReport{
+		GroupId:     toPointer(report.GroupID.UUID),
+		PeriodStart: toPointer(report.PeriodStart),
+		PeriodEnd:   toPointer(report.PeriodEnd),
+		BudgetCents: toPointer(report.BudgetCents),
+		TotalCents:  toPointer(report.TotalCents),
+		Users:       &users,
+	})
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+)
+
+type decisionService interface {
+	ExplainDecision(ctx context.Context, replay core.DecisionReplay) (*authz.Explanation, error)
+}
+
+func (a *Adapter) ExplainDecision(w http.ResponseWriter, r *http.Request) {
+	var req api.ExplainDecisionJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	explanation, err := a.service.ExplainDecision(ctx, core.DecisionReplay{
+		UserID:        core.UserID{UUID: req.UserId},
+		Action:        authz.Verb(req.Action),
+		ResourceGroup: req.ResourceGroup,
+		Resource:      fromPointer(req.Resource),
+	})
+	if err != nil {
+		writeServiceError(ctx, w, "error explaining decision", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIExplanation(explanation))
+}
+
+func mapAPIExplanation(e *authz.Explanation) api.DecisionExplanation {
+	d := e.Decision
+	decision := api.Decision{
+		Id:            toPointer(d.ID),
+		Timestamp:     toPointer(d.Timestamp),
+		Package:       toPointer(d.Package),
+		ResourceGroup: toPointer(d.ResourceGroup),
+		Input:         &d.Input,
+		Allow:         toPointer(d.Result.Allow),
+		Partial:       toSlicePointer(d.Result.Partial.Residual()),
+		DurationNs:    toPointer(d.Duration.Nanoseconds()),
+	}
+	if d.Error != "" {
+		decision.Error = toPointer(d.Error)
+	}
+
+	return api.DecisionExplanation{
+		Decision: &decision,
+		Trace:    toSlicePointer(e.Trace),
+	}
+}
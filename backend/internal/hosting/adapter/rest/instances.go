@@ -1,12 +1,15 @@
 package rest
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
 	"vpainless/api"
 	"vpainless/internal/hosting/core"
+	"vpainless/pkg/qr"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -35,9 +38,54 @@ func (a *Adapter) GetInstance(w http.ResponseWriter, r *http.Request, id uuid.UU
 		Owner:            toPointer(instance.Owner.UUID),
 		Ip:               toPointer(instance.IP.String()),
 		Status:           toPointer(api.InstanceStatus(instance.Status)),
+		Shared:           toPointer(instance.Shared),
+		Region:           toPointer(instance.Region),
 	})
 }
 
+func (a *Adapter) GetInstanceQR(w http.ResponseWriter, r *http.Request, id uuid.UUID, params api.GetInstanceQRParams) {
+	ctx := r.Context()
+	opts := qr.Options{
+		Format: qr.Format(fromPointer(params.Format)),
+		Level:  qr.Level(fromPointer(params.Level)),
+		Size:   fromPointer(params.Size),
+	}
+
+	// The QR code is just another representation of the instance, so
+	// it is subject to the same access rules as getting the instance.
+	instance, err := a.service.GetInstance(ctx, core.InstanceID{UUID: id})
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			writeJSONError(w, http.StatusNotFound, err)
+			return
+		case errors.Is(err, core.ErrUnauthorized):
+			writeJSONError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		slog.ErrorContext(ctx, "error getting instance", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if instance.Config.ConnectionString == "" {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("instance has no connection string yet: %w", core.ErrNotFound))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := qr.Encode(&buf, instance.Config.ConnectionString, opts); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
 func (a *Adapter) DeleteInstance(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
 	ctx := r.Context()
 
@@ -54,22 +102,97 @@ func (a *Adapter) DeleteInstance(w http.ResponseWriter, r *http.Request, id uuid
 	case errors.Is(err, core.ErrUnauthorized):
 		writeJSONError(w, http.StatusUnauthorized, err)
 		return
+	case errors.Is(err, core.ErrRateLimited):
+		setRetryAfter(w, err)
+		writeJSONError(w, http.StatusTooManyRequests, err)
+		return
 	}
 
 	slog.ErrorContext(ctx, "error deleting instance", "error", err)
 	writeJSONError(w, http.StatusInternalServerError, err)
 }
 
+func (a *Adapter) ListInstanceHealthChecks(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	checks, err := a.service.ListHealthChecks(ctx, core.InstanceID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error listing instance health checks", err)
+		return
+	}
+
+	result := []api.HealthCheck{}
+	for _, c := range checks {
+		result = append(result, api.HealthCheck{
+			CheckedAt: toPointer(c.CheckedAt),
+			Tcp:       toPointer(c.TCP),
+			Reality:   toPointer(c.Reality),
+			Xray:      toPointer(c.Xray),
+			Error:     toPointer(c.Error),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) RotateInstanceCredentials(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	instance, err := a.service.RotateCredentials(ctx, core.InstanceID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error rotating instance credentials", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.Instance{
+		ConnectionString: toPointer(instance.Config.ConnectionString),
+		Id:               toPointer(instance.ID.UUID),
+		Owner:            toPointer(instance.Owner.UUID),
+		Ip:               toPointer(instance.IP.String()),
+		Status:           toPointer(api.InstanceStatus(instance.Status)),
+		Shared:           toPointer(instance.Shared),
+		Region:           toPointer(instance.Region),
+	})
+}
+
+func (a *Adapter) RenewInstance(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	instance, err := a.service.RenewInstance(ctx, core.InstanceID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error renewing instance", err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, api.Instance{
+		ConnectionString: toPointer(instance.Config.ConnectionString),
+		Id:               toPointer(instance.ID.UUID),
+		Owner:            toPointer(instance.Owner.UUID),
+		Ip:               toPointer(instance.IP.String()),
+		Status:           toPointer(api.InstanceStatus(instance.Status)),
+		Shared:           toPointer(instance.Shared),
+		Region:           toPointer(instance.Region),
+	})
+}
+
 func (a *Adapter) PostInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	instance, err := a.service.CreateInstance(ctx)
 	status := http.StatusCreated
+
+	var pending *core.ApprovalPendingError
+	if errors.As(err, &pending) {
+		writeJSON(w, http.StatusAccepted, mapAPIInstanceRequest(pending.Request))
+		return
+	}
 	if err != nil {
 		s := http.StatusInternalServerError
 		switch {
 		case errors.Is(err, core.ErrUnauthorized):
 			s = http.StatusUnauthorized
+		case errors.Is(err, core.ErrBudgetExceeded):
+			s = http.StatusPaymentRequired
+		case errors.Is(err, core.ErrRateLimited):
+			setRetryAfter(w, err)
+			s = http.StatusTooManyRequests
 		}
 		if !errors.Is(err, core.ErrAlreadyExists) {
 			slog.ErrorContext(ctx, "error creating instance", "error", err)
@@ -85,6 +208,8 @@ func (a *Adapter) PostInstance(w http.ResponseWriter, r *http.Request) {
 		Owner:            toPointer(instance.Owner.UUID),
 		Ip:               toPointer(instance.IP.String()),
 		Status:           toPointer(api.InstanceStatus(instance.Status)),
+		Shared:           toPointer(instance.Shared),
+		Region:           toPointer(instance.Region),
 	})
 }
 
@@ -111,6 +236,8 @@ func (a *Adapter) ListInstances(w http.ResponseWriter, r *http.Request) {
 			Owner:            toPointer(instance.Owner.UUID),
 			Ip:               toPointer(instance.IP.String()),
 			Status:           toPointer(api.InstanceStatus(instance.Status)),
+			Shared:           toPointer(instance.Shared),
+			Region:           toPointer(instance.Region),
 		})
 	}
 
@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+)
+
+type notificationService interface {
+	ListNotifications(ctx context.Context) ([]*core.Notification, error)
+}
+
+func (a *Adapter) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	notifications, err := a.service.ListNotifications(ctx)
+	if err != nil {
+		writeServiceError(ctx, w, "error listing notifications", err)
+		return
+	}
+
+	result := []api.Notification{}
+	for _, n := range notifications {
+		result = append(result, api.Notification{
+			Id:         toPointer(n.ID.UUID),
+			InstanceId: toPointer(n.InstanceID.UUID),
+			Kind:       toPointer(api.NotificationKind(n.Kind)),
+			Message:    toPointer(n.Message),
+			CreatedAt:  toPointer(n.CreatedAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+)
+
+type probeService interface {
+	SubmitProbeReports(ctx context.Context, reports []*core.ProbeReport) error
+	ListBlockedRanges(ctx context.Context) ([]*core.ProbeSummary, error)
+}
+
+func (a *Adapter) PostProbeReports(w http.ResponseWriter, r *http.Request) {
+	var req api.PostProbeReportsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reports := make([]*core.ProbeReport, 0, len(req))
+	for _, report := range req {
+		reports = append(reports, &core.ProbeReport{
+			InstanceID: core.InstanceID{UUID: report.InstanceId},
+			TCP:        fromPointer(report.Tcp),
+			TLS:        fromPointer(report.Tls),
+			Latency:    time.Duration(fromPointer(report.LatencyMs)) * time.Millisecond,
+			Error:      fromPointer(report.Error),
+		})
+	}
+
+	ctx := r.Context()
+	if err := a.service.SubmitProbeReports(ctx, reports); err != nil {
+		writeServiceError(ctx, w, "error submitting probe reports", err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func (a *Adapter) ListProbeRanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ranges, err := a.service.ListBlockedRanges(ctx)
+	if err != nil {
+		writeServiceError(ctx, w, "error listing probe ranges", err)
+		return
+	}
+
+	result := []api.ProbeRange{}
+	for _, s := range ranges {
+		result = append(result, api.ProbeRange{
+			Range:   toPointer(s.Range.String()),
+			Reports: toPointer(s.Reports),
+			Failed:  toPointer(s.Failed),
+			Blocked: toPointer(s.Blocked),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
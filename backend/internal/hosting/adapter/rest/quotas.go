@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type quotaService interface {
+	GetQuota(ctx context.Context, id core.UserID) (*core.QuotaStatus, error)
+	UpdateQuota(ctx context.Context, id core.UserID, quota *core.Quota) (*core.QuotaStatus, error)
+	LiftSuspension(ctx context.Context, id core.UserID) error
+}
+
+func (a *Adapter) GetUserQuota(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	status, err := a.service.GetQuota(ctx, core.UserID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error getting user quota", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIQuotaStatus(status))
+}
+
+func (a *Adapter) PutUserQuota(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req api.PutUserQuotaJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// An empty quota restores the quota of the group.
+	var quota *core.Quota
+	if req.TrafficQuotaBytes != nil || req.ActiveDaysQuota != nil {
+		quota = &core.Quota{
+			TrafficBytes: fromPointer(req.TrafficQuotaBytes),
+			ActiveDays:   fromPointer(req.ActiveDaysQuota),
+		}
+	}
+
+	ctx := r.Context()
+	status, err := a.service.UpdateQuota(ctx, core.UserID{UUID: id}, quota)
+	if err != nil {
+		writeServiceError(ctx, w, "error updating user quota", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIQuotaStatus(status))
+}
+
+func (a *Adapter) DeleteUserSuspension(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	if err := a.service.LiftSuspension(ctx, core.UserID{UUID: id}); err != nil {
+		writeServiceError(ctx, w, "error lifting user suspension", err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func mapAPIQuotaStatus(s *core.QuotaStatus) api.QuotaStatus {
+	return api.QuotaStatus{
+		UserId:            toPointer(s.UserID.UUID),
+		TrafficQuotaBytes: toPointer(s.Quota.TrafficBytes),
+		ActiveDaysQuota:   toPointer(s.Quota.ActiveDays),
+		Overridden:        toPointer(s.Override != nil),
+		TrafficBytes:      toPointer(s.TrafficBytes),
+		ActiveDays:        toPointer(s.ActiveDays),
+		PeriodStart:       toPointer(s.PeriodStart),
+		PeriodEnd:         toPointer(s.PeriodEnd),
+		Suspended:         toPointer(s.Suspended(time.Now())),
+		SuspendedUntil:    s.SuspendedUntil,
+		LiftedUntil:       s.LiftedUntil,
+	}
+}
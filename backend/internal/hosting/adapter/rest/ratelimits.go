@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type rateLimitService interface {
+	GetRateLimit(ctx context.Context, id core.UserID) (*core.RateLimitStatus, error)
+	UpdateRateLimit(ctx context.Context, id core.UserID, limit *core.RateLimit) (*core.RateLimitStatus, error)
+}
+
+func (a *Adapter) GetUserRateLimit(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	status, err := a.service.GetRateLimit(ctx, core.UserID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error getting user rate limit", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIRateLimit(status))
+}
+
+func (a *Adapter) PutUserRateLimit(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req api.PutUserRateLimitJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// An empty limit restores the limit of the group.
+	var limit *core.RateLimit
+	if req.Burst != nil || req.PerHour != nil {
+		l := mapCoreRateLimit(&req)
+		limit = &l
+	}
+
+	ctx := r.Context()
+	status, err := a.service.UpdateRateLimit(ctx, core.UserID{UUID: id}, limit)
+	if err != nil {
+		writeServiceError(ctx, w, "error updating user rate limit", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIRateLimit(status))
+}
+
+// setRetryAfter sets the Retry-After header, in seconds, if the error is a rate limit error.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var rle *core.RateLimitError
+	if errors.As(err, &rle) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rle.RetryAfter.Seconds()))))
+	}
+}
+
+func mapAPIRateLimit(s *core.RateLimitStatus) *api.RateLimit {
+	return &api.RateLimit{
+		Burst:      toPointer(s.Burst),
+		PerHour:    toPointer(s.PerHour),
+		Overridden: toPointer(s.Overridden),
+	}
+}
+
+func mapCoreRateLimit(l *api.RateLimit) core.RateLimit {
+	if l == nil {
+		return core.RateLimit{}
+	}
+
+	return core.RateLimit{
+		Burst:   fromPointer(l.Burst),
+		PerHour: fromPointer(l.PerHour),
+	}
+}
@@ -0,0 +1,213 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type ruleSetService interface {
+	ListPresets() []core.Preset
+	GetRuleSet(ctx context.Context, id core.RuleSetID) (*core.RuleSet, error)
+	ListRuleSets(ctx context.Context) ([]*core.RuleSet, error)
+	CreateRuleSet(ctx context.Context, ruleset *core.RuleSet) (*core.RuleSet, error)
+	UpdateRuleSet(ctx context.Context, ruleset *core.RuleSet) (*core.RuleSet, error)
+	DeleteRuleSet(ctx context.Context, id core.RuleSetID) error
+}
+
+func (a *Adapter) ListRoutingPresets(w http.ResponseWriter, r *http.Request) {
+	result := []api.RoutingPreset{}
+	for _, p := range a.service.ListPresets() {
+		result = append(result, api.RoutingPreset{
+			Country: toPointer(p.Country),
+			Name:    toPointer(p.Name),
+			Block:   mapAPIRules(p.Block),
+			Direct:  mapAPIRules(p.Direct),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) ListRuleSets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rulesets, err := a.service.ListRuleSets(ctx)
+	if err != nil {
+		writeServiceError(ctx, w, "error listing rule sets", err)
+		return
+	}
+
+	result := []api.RuleSet{}
+	for _, rs := range rulesets {
+		result = append(result, mapAPIRuleSet(rs))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) GetRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	ruleset, err := a.service.GetRuleSet(ctx, core.RuleSetID{UUID: id})
+	if err != nil {
+		writeServiceError(ctx, w, "error getting rule set", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIRuleSet(ruleset))
+}
+
+func (a *Adapter) PostRuleSet(w http.ResponseWriter, r *http.Request) {
+	var req api.PostRuleSetJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	ruleset := mapCoreRuleSet(req)
+	if req.Enabled == nil {
+		ruleset.Enabled = true
+	}
+
+	result, err := a.service.CreateRuleSet(ctx, &ruleset)
+	if err != nil {
+		writeServiceError(ctx, w, "error creating rule set", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapAPIRuleSet(result))
+}
+
+func (a *Adapter) PutRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req api.PutRuleSetJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Id = toPointer(id)
+
+	ctx := r.Context()
+	ruleset := mapCoreRuleSet(req)
+	result, err := a.service.UpdateRuleSet(ctx, &ruleset)
+	if err != nil {
+		writeServiceError(ctx, w, "error updating rule set", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapAPIRuleSet(result))
+}
+
+func (a *Adapter) DeleteRuleSet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	if err := a.service.DeleteRuleSet(ctx, core.RuleSetID{UUID: id}); err != nil {
+		writeServiceError(ctx, w, "error deleting rule set", err)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
+
+func writeServiceError(ctx context.Context, w http.ResponseWriter, msg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, core.ErrBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, core.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, core.ErrRenewalCap):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, core.ErrBudgetExceeded):
+		status = http.StatusPaymentRequired
+	case errors.Is(err, core.ErrRateLimited):
+		setRetryAfter(w, err)
+		status = http.StatusTooManyRequests
+	}
+
+	slog.ErrorContext(ctx, msg, "error", err)
+	writeJSONError(w, status, err)
+}
+
+func mapAPIRules(r core.Rules) *api.RoutingRules {
+	return &api.RoutingRules{
+		Domains: toSlicePointer(r.Domains),
+		Ips:     toSlicePointer(r.IPs),
+	}
+}
+
+func mapCoreRules(r *api.RoutingRules) core.Rules {
+	if r == nil {
+		return core.Rules{}
+	}
+
+	return core.Rules{
+		Domains: fromPointer(r.Domains),
+		IPs:     fromPointer(r.Ips),
+	}
+}
+
+func mapAPIRuleSet(rs *core.RuleSet) api.RuleSet {
+	result := api.RuleSet{
+		Id:      toPointer(rs.ID.UUID),
+		GroupId: toPointer(rs.GroupID.UUID),
+		Name:    toPointer(rs.Name),
+		Preset:  toPointer(rs.Preset),
+		Enabled: toPointer(rs.Enabled),
+		Block:   mapAPIRules(rs.Block),
+		Direct:  mapAPIRules(rs.Direct),
+	}
+
+	// Secret key is write only, and never leaves the system.
+	if rs.Warp != nil {
+		result.Warp = &api.WarpOutbound{
+			Addresses:     toSlicePointer(rs.Warp.Addresses),
+			PeerPublicKey: toPointer(rs.Warp.PeerPublicKey),
+			Endpoint:      toPointer(rs.Warp.Endpoint),
+			Reserved:      toSlicePointer(rs.Warp.Reserved),
+			Mtu:           toPointer(rs.Warp.MTU),
+			Rules:         mapAPIRules(rs.Warp.Rules),
+		}
+	}
+
+	return result
+}
+
+func mapCoreRuleSet(rs api.RuleSet) core.RuleSet {
+	result := core.RuleSet{
+		ID:      core.RuleSetID{UUID: fromPointer(rs.Id)},
+		Name:    fromPointer(rs.Name),
+		Preset:  fromPointer(rs.Preset),
+		Enabled: fromPointer(rs.Enabled),
+		Block:   mapCoreRules(rs.Block),
+		Direct:  mapCoreRules(rs.Direct),
+	}
+
+	if rs.Warp != nil {
+		result.Warp = &core.WarpOutbound{
+			SecretKey:     fromPointer(rs.Warp.SecretKey),
+			Addresses:     fromPointer(rs.Warp.Addresses),
+			PeerPublicKey: fromPointer(rs.Warp.PeerPublicKey),
+			Endpoint:      fromPointer(rs.Warp.Endpoint),
+			Reserved:      fromPointer(rs.Warp.Reserved),
+			MTU:           fromPointer(rs.Warp.Mtu),
+			Rules:         mapCoreRules(rs.Warp.Rules),
+		}
+	}
+
+	return result
+}
+
+func toSlicePointer[T any](s []T) *[]T {
+	if s == nil {
+		s = []T{}
+	}
+	return &s
+}
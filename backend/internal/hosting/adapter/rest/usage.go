@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"vpainless/api"
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+type usageService interface {
+	ListUserUsage(ctx context.Context, id core.UserID, since time.Time) ([]*core.Usage, error)
+	SummarizeGroupUsage(ctx context.Context, id core.GroupID, since time.Time) ([]*core.Usage, error)
+}
+
+func (a *Adapter) ListUserUsage(w http.ResponseWriter, r *http.Request, id uuid.UUID, params api.ListUserUsageParams) {
+	ctx := r.Context()
+	usages, err := a.service.ListUserUsage(ctx, core.UserID{UUID: id}, usageSince(params.Since))
+	if err != nil {
+		writeServiceError(ctx, w, "error listing user usage", err)
+		return
+	}
+
+	result := []api.Usage{}
+	for _, u := range usages {
+		result = append(result, api.Usage{
+			UserId:        toPointer(u.UserID.UUID),
+			InstanceId:    toPointer(u.InstanceID.UUID),
+			Date:          &openapi_types.Date{Time: u.CollectedAt},
+			UplinkBytes:   toPointer(u.Uplink),
+			DownlinkBytes: toPointer(u.Downlink),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Adapter) ListGroupUsage(w http.ResponseWriter, r *http.Request, id uuid.UUID, params api.ListGroupUsageParams) {
+	ctx := r.Context()
+	usages, err := a.service.SummarizeGroupUsage(ctx, core.GroupID{UUID: id}, usageSince(params.Since))
+	if err != nil {
+		writeServiceError(ctx, w, "error summarizing group usage", err)
+		return
+	}
+
+	result := []api.Usage{}
+	for _, u := range usages {
+		result = append(result, api.Usage{
+			UserId:        toPointer(u.UserID.UUID),
+			UplinkBytes:   toPointer(u.Uplink),
+			DownlinkBytes: toPointer(u.Downlink),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func usageSince(since *time.Time) time.Time {
+	if since == nil {
+		return time.Now().Add(-core.DefaultUsagePeriod)
+	}
+	return *since
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const instanceRequestColumns = `id, user_id, group_id, status, created_at, decided_at, decided_by, instance_id`
+
+func (r *Repository) GetInstanceRequest(ctx context.Context, id core.InstanceRequestID) (*core.InstanceRequest, error) {
+	return r.getInstanceRequest(ctx, querybuilder.New(`
+		select `+instanceRequestColumns+`
+		from instance_requests
+		where id = ?;
+	`, id))
+}
+
+func (r *Repository) FindPendingRequest(ctx context.Context, id core.UserID, group core.GroupID) (*core.InstanceRequest, error) {
+	return r.getInstanceRequest(ctx, querybuilder.New(`
+		select `+instanceRequestColumns+`
+		from instance_requests
+		where user_id = ? and group_id = ? and status = ?;
+	`, id, group, core.RequestPending))
+}
+
+func (r *Repository) getInstanceRequest(ctx context.Context, qb *querybuilder.Builder) (*core.InstanceRequest, error) {
+	var result *core.InstanceRequest
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanInstanceRequest(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) ListPendingRequests(ctx context.Context, id core.GroupID) ([]*core.InstanceRequest, error) {
+	var result []*core.InstanceRequest
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select `+instanceRequestColumns+`
+			from instance_requests
+			where group_id = ? and status = ?
+			order by created_at;
+		`, id, core.RequestPending)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			request, err := scanInstanceRequest(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, request)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SaveInstanceRequest(ctx context.Context, request *core.InstanceRequest) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		var decidedBy, instanceID sql.NullString
+		if request.DecidedBy != nil {
+			decidedBy = uuidOrNull(request.DecidedBy.UUID)
+		}
+		if request.InstanceID != nil {
+			instanceID = uuidOrNull(request.InstanceID.UUID)
+		}
+
+		qb := querybuilder.New(`
+			insert into instance_requests (`+instanceRequestColumns+`)
+			values (?, ?, ?, ?, ?, ?, ?, ?)
+			on conflict (id) do update set
+				status = excluded.status,
+				decided_at = excluded.decided_at,
+				decided_by = excluded.decided_by,
+				instance_id = excluded.instance_id;
+		`, request.ID, request.UserID, request.GroupID, request.Status,
+			request.CreatedAt.UTC().Format(time.DateTime), nullTime(request.DecidedAt),
+			decidedBy, instanceID)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func scanInstanceRequest(row Scanner) (*core.InstanceRequest, error) {
+	var (
+		result                core.InstanceRequest
+		createdAt             string
+		decidedAt             sql.NullString
+		decidedBy, instanceID sql.NullString
+	)
+	if err := row.Scan(&result.ID, &result.UserID, &result.GroupID, &result.Status,
+		&createdAt, &decidedAt, &decidedBy, &instanceID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if result.CreatedAt, err = time.Parse(time.DateTime, createdAt); err != nil {
+		return nil, err
+	}
+	if result.DecidedAt, err = parseNullTime(decidedAt); err != nil {
+		return nil, err
+	}
+	if decidedBy.Valid {
+		result.DecidedBy = &core.UserID{UUID: uuid.FromStringOrNil(decidedBy.String)}
+	}
+	if instanceID.Valid {
+		result.InstanceID = &core.InstanceID{UUID: uuid.FromStringOrNil(instanceID.String)}
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_InstanceRequest() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	_, err := repo.FindPendingRequest(ctx, clientID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find a request for users without one")
+
+	expected := &core.InstanceRequest{
+		ID:        core.InstanceRequestID{UUID: uuid.Must(uuid.NewV4())},
+		UserID:    clientID,
+		GroupID:   groupID,
+		Status:    core.RequestPending,
+		CreatedAt: now,
+	}
+	s.Require().NoError(repo.SaveInstanceRequest(ctx, expected), "should save request without any error")
+
+	actual, err := repo.FindPendingRequest(ctx, clientID, groupID)
+	s.Require().NoError(err, "should find the pending request without any error")
+	s.Require().Equal(expected, actual, "should find the saved request")
+
+	decidedAt := now.Add(time.Hour)
+	expected.Status = core.RequestApproved
+	expected.DecidedAt = &decidedAt
+	expected.DecidedBy = &adminID
+	expected.InstanceID = &core.InstanceID{UUID: uuid.Must(uuid.NewV4())}
+	s.Require().NoError(repo.SaveInstanceRequest(ctx, expected), "should update request without any error")
+
+	actual, err = repo.GetInstanceRequest(ctx, expected.ID)
+	s.Require().NoError(err, "should get request without any error")
+	s.Require().Equal(expected, actual, "should get the updated request")
+
+	_, err = repo.FindPendingRequest(ctx, clientID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the approved request as pending")
+}
+
+func (s *RepositoryTestSuite) Test_ListPendingRequests() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	repo := NewRepository(s.db)
+
+	newRequest := func(user string, group core.GroupID, status core.InstanceRequestStatus, created time.Time) *core.InstanceRequest {
+		return &core.InstanceRequest{
+			ID:        core.InstanceRequestID{UUID: uuid.Must(uuid.NewV4())},
+			UserID:    core.UserID{UUID: uuid.FromStringOrNil(user)},
+			GroupID:   group,
+			Status:    status,
+			CreatedAt: created,
+		}
+	}
+
+	first := newRequest("22000000-0000-0000-0000-000000000000", groupID, core.RequestPending, now)
+	second := newRequest("11000000-0000-0000-0000-000000000000", groupID, core.RequestPending, now.Add(time.Minute))
+	for _, request := range []*core.InstanceRequest{
+		second,
+		first,
+		newRequest("22000000-0000-0000-0000-000000000000", groupID, core.RequestRejected, now.Add(-time.Hour)),
+		newRequest("33000000-0000-0000-0000-000000000000", otherGroupID, core.RequestPending, now),
+	} {
+		s.Require().NoError(repo.SaveInstanceRequest(ctx, request), "should save request without any error")
+	}
+
+	actual, err := repo.ListPendingRequests(ctx, groupID)
+	s.Require().NoError(err, "should list pending requests without any error")
+	s.Require().Equal([]*core.InstanceRequest{first, second}, actual, "should only list the pending requests of the group, oldest first")
+}
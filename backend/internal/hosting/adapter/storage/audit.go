@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// auditColumns are the columns of the audit log entries the policies filter on.
+var auditColumns = authz.Columns{
+	"group_id": "a.group_id",
+}
+
+func (r *Repository) SaveAuditEntry(ctx context.Context, entry *core.AuditEntry) error {
+	diff, err := json.Marshal(entry.Diff)
+	if err != nil {
+		return err
+	}
+
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		var principalID sql.NullString
+		if entry.PrincipalID != nil {
+			principalID = uuidOrNull(entry.PrincipalID.UUID)
+		}
+
+		qb := querybuilder.New(`
+			insert into audit_log (id, created_at, request_id, principal_id, group_id, action, target, diff)
+			values (?, ?, ?, ?, ?, ?, ?, ?);
+		`, entry.ID, entry.CreatedAt.UTC().Format(time.DateTime), uuidOrNull(entry.RequestID),
+			principalID, uuidOrNull(entry.GroupID.UUID), entry.Action, entry.Target, string(diff))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListAuditEntries(ctx context.Context, filter core.AuditFilter, partial authz.Clause) ([]*core.AuditEntry, error) {
+	var result []*core.AuditEntry
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select a.id, a.created_at, a.request_id, a.principal_id, a.group_id, a.action, a.target, a.diff
+			from audit_log a
+		`)
+
+		var conds []querybuilder.Cond
+		if !partial.IsNil() {
+			cond, err := partial.Cond(auditColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		if filter.Action != "" {
+			conds = append(conds, querybuilder.Cond{Text: "a.action = ?", Args: []any{filter.Action}})
+		}
+		if !filter.PrincipalID.IsNil() {
+			conds = append(conds, querybuilder.Cond{Text: "a.principal_id = ?", Args: []any{filter.PrincipalID}})
+		}
+		if filter.Target != "" {
+			conds = append(conds, querybuilder.Cond{Text: "a.target = ?", Args: []any{filter.Target}})
+		}
+		if !filter.Since.IsZero() {
+			conds = append(conds, querybuilder.Cond{Text: "a.created_at >= ?", Args: []any{filter.Since.UTC().Format(time.DateTime)}})
+		}
+		if !filter.Until.IsZero() {
+			conds = append(conds, querybuilder.Cond{Text: "a.created_at < ?", Args: []any{filter.Until.UTC().Format(time.DateTime)}})
+		}
+		qb.Where(conds...)
+		qb.Append(` order by a.created_at desc limit ?`, filter.Limit)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			entry, err := scanAuditEntry(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, entry)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanAuditEntry(row Scanner) (*core.AuditEntry, error) {
+	var (
+		result                          core.AuditEntry
+		createdAt, diff                 string
+		requestID, principalID, groupID sql.NullString
+	)
+	if err := row.Scan(&result.ID, &createdAt, &requestID, &principalID, &groupID,
+		&result.Action, &result.Target, &diff); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if result.CreatedAt, err = time.Parse(time.DateTime, createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(diff), &result.Diff); err != nil {
+		return nil, err
+	}
+
+	result.RequestID = uuid.FromStringOrNil(requestID.String)
+	result.GroupID = core.GroupID{UUID: uuid.FromStringOrNil(groupID.String)}
+	if principalID.Valid {
+		result.PrincipalID = &core.UserID{UUID: uuid.FromStringOrNil(principalID.String)}
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_List_AuditEntries() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	deleted := &core.AuditEntry{
+		ID:          core.AuditEntryID{UUID: uuid.Must(uuid.NewV4())},
+		CreatedAt:   now.Add(time.Hour),
+		RequestID:   uuid.Must(uuid.NewV4()),
+		PrincipalID: &adminID,
+		GroupID:     groupID,
+		Action:      core.AuditInstanceDelete,
+		Target:      "instances/00000000-1111-0000-0000-000000000000",
+		Diff:        map[string]core.AuditChange{"region": {Old: "fra"}},
+	}
+	promoted := &core.AuditEntry{
+		ID:          core.AuditEntryID{UUID: uuid.Must(uuid.NewV4())},
+		CreatedAt:   now,
+		RequestID:   uuid.Must(uuid.NewV4()),
+		PrincipalID: &adminID,
+		GroupID:     groupID,
+		Action:      core.AuditUserUpdate,
+		Target:      "users/22000000-0000-0000-0000-000000000000",
+		Diff:        map[string]core.AuditChange{"role": {Old: "client", New: "admin"}},
+	}
+	failed := &core.AuditEntry{
+		ID:        core.AuditEntryID{UUID: uuid.Must(uuid.NewV4())},
+		CreatedAt: now,
+		GroupID:   otherGroupID,
+		Action:    core.AuditLoginFailure,
+		Target:    "users/33000000-0000-0000-0000-000000000000",
+		Diff:      map[string]core.AuditChange{},
+	}
+	for _, entry := range []*core.AuditEntry{promoted, deleted, failed} {
+		s.Require().NoError(repo.SaveAuditEntry(ctx, entry), "should save entry without any error")
+	}
+
+	groupClause := authz.Clause{Condition: "a.group_id = ?", Values: []any{groupID}}
+	actual, err := repo.ListAuditEntries(ctx, core.AuditFilter{Limit: 10}, groupClause)
+	s.Require().NoError(err, "should list entries without any error")
+	s.Require().Equal([]*core.AuditEntry{deleted, promoted}, actual, "should list the entries of the group, newest first")
+
+	actual, err = repo.ListAuditEntries(ctx, core.AuditFilter{Action: core.AuditUserUpdate, Limit: 10}, groupClause)
+	s.Require().NoError(err, "should list entries without any error")
+	s.Require().Equal([]*core.AuditEntry{promoted}, actual, "should filter the entries by action")
+
+	actual, err = repo.ListAuditEntries(ctx, core.AuditFilter{Since: now.Add(time.Minute), PrincipalID: adminID, Limit: 10}, authz.Clause{})
+	s.Require().NoError(err, "should list entries without any error")
+	s.Require().Equal([]*core.AuditEntry{deleted}, actual, "should filter the entries by time and principal")
+
+	_, err = s.db.ExecContext(ctx, `delete from audit_log`)
+	s.Require().Error(err, "should not delete the entries")
+}
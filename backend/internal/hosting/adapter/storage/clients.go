@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// clientColumns are the columns of the instance clients the policies filter on.
+var clientColumns = authz.Columns{
+	"user_id":  "c.user_id",
+	"group_id": "(select group_id from instances where id = c.instance_id)",
+}
+
+// Clients of deleted instances are not active anymore, even if they are not revoked.
+const activeClientCondition = "c.revoked_at is null and c.instance_id in (select id from instances where deleted_at is null)"
+
+func (r *Repository) ListClients(ctx context.Context, id core.InstanceID, partial authz.Clause) ([]*core.InstanceClient, error) {
+	var result []*core.InstanceClient
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select c.id, c.instance_id, c.user_id, c.created_at, c.revoked_at
+			from instance_clients c
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("c.instance_id = ?", []any{id}),
+			querybuilder.Condition(activeClientCondition, nil),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(clientColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		qb.Append(" order by c.created_at, c.id;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		result, err = scanClients(rows)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) FindClient(ctx context.Context, id core.UserID, group core.GroupID) (*core.InstanceClient, error) {
+	var result *core.InstanceClient
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select c.id, c.instance_id, c.user_id, c.created_at, c.revoked_at
+			from instance_clients c
+		`)
+		qb.Where(
+			querybuilder.Condition("c.user_id = ?", []any{id}),
+			querybuilder.Condition("c.instance_id in (select id from instances where group_id = ?)", []any{group}),
+			querybuilder.Condition(activeClientCondition, nil),
+		)
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanClient(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SaveClient(ctx context.Context, client *core.InstanceClient) (*core.InstanceClient, error) {
+	if err := r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		var revokedAt sql.NullString
+		if client.RevokedAt != nil {
+			revokedAt = sql.NullString{String: client.RevokedAt.UTC().Format(time.DateTime), Valid: true}
+		}
+
+		qb := querybuilder.New(`
+			insert into instance_clients (id, instance_id, user_id, created_at, revoked_at)
+			values (?, ?, ?, ?, ?)
+			on conflict (id) do update set
+				instance_id = excluded.instance_id,
+				revoked_at = excluded.revoked_at;
+		`, client.ID, client.InstanceID, client.UserID, client.CreatedAt.UTC().Format(time.DateTime), revokedAt)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func scanClient(row Scanner) (*core.InstanceClient, error) {
+	var (
+		result    core.InstanceClient
+		createdAt string
+		revokedAt sql.NullString
+	)
+
+	if err := row.Scan(&result.ID, &result.InstanceID, &result.UserID, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	result.CreatedAt, err = time.Parse(time.DateTime, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		t, err := time.Parse(time.DateTime, revokedAt.String)
+		if err != nil {
+			return nil, err
+		}
+		result.RevokedAt = &t
+	}
+
+	return &result, nil
+}
+
+func scanClients(rows *sql.Rows) ([]*core.InstanceClient, error) {
+	var result []*core.InstanceClient
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Find_Revoke_Client() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, adminID, now)
+	instance.Shared = true
+	instance.Status = core.StatusOK
+	instance.Config = core.XrayConfig{
+		Reality: core.XrayTemplate{
+			ID:                   core.XrayTemplateID{UUID: uuid.Must(uuid.NewV4())},
+			FakeURL:              "www.speedtest.net",
+			Curve25519PrivateKey: "private",
+			Curve25519PublicKey:  "public",
+			ShortID:              "abcd",
+		},
+	}
+
+	repo := NewRepository(s.db)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	actual, err := repo.GetInstance(ctx, instance.ID, authz.Clause{})
+	s.Require().NoError(err, "should get instance without any error")
+	s.Require().Equal(instance, actual, "fetched instance should keep the reality parameters")
+
+	_, err = repo.FindClient(ctx, clientID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find any client")
+
+	clients := []*core.InstanceClient{
+		{ID: core.ClientID{UUID: uuid.Must(uuid.NewV4())}, InstanceID: instance.ID, UserID: adminID, CreatedAt: now},
+		{ID: core.ClientID{UUID: uuid.Must(uuid.NewV4())}, InstanceID: instance.ID, UserID: clientID, CreatedAt: now.Add(time.Second)},
+	}
+	for _, c := range clients {
+		_, err := repo.SaveClient(ctx, c)
+		s.Require().NoError(err, "should save client without any error")
+	}
+
+	found, err := repo.FindClient(ctx, clientID, groupID)
+	s.Require().NoError(err, "should find the client without any error")
+	s.Require().Equal(clients[1], found, "found client should match the saved one")
+
+	_, err = repo.FindClient(ctx, clientID, core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")})
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the client on the instances of other groups")
+
+	shared, err := repo.FindSharedInstance(ctx, groupID, 3)
+	s.Require().NoError(err, "should find the shared instance with capacity")
+	s.Require().Equal(instance.ID, shared.ID, "should find the shared instance of the group")
+
+	_, err = repo.FindSharedInstance(ctx, groupID, 2)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find full instances")
+
+	revoked := now.Add(time.Hour)
+	clients[1].RevokedAt = &revoked
+	_, err = repo.SaveClient(ctx, clients[1])
+	s.Require().NoError(err, "should revoke client without any error")
+
+	_, err = repo.FindClient(ctx, clientID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find revoked clients")
+
+	active, err := repo.ListClients(ctx, instance.ID, authz.Clause{})
+	s.Require().NoError(err, "should list clients without any error")
+	s.Require().Equal(clients[:1], active, "should only list active clients")
+
+	s.Require().NoError(repo.DeleteInstance(ctx, instance.ID, authz.Clause{}), "should delete instance without any error")
+
+	_, err = repo.FindClient(ctx, adminID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "clients of deleted instances should not be active")
+}
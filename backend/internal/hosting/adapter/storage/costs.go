@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) ListInstanceCosts(ctx context.Context, id core.GroupID, start, end time.Time) ([]*core.InstanceCost, error) {
+	var result []*core.InstanceCost
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select i.id, i.user_id, i.plan, i.monthly_cost_cents, i.created_at, i.deleted_at, i.retire_at
+			from instances i
+			where i.group_id = ? and i.created_at < ? and (i.deleted_at is null or i.deleted_at >= ? or i.retire_at >= ?)
+			order by i.created_at;
+		`, id, end.UTC().Format(time.DateTime), start.UTC().Format(time.DateTime), start.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				cost      core.InstanceCost
+				createdAt string
+				deletedAt sql.NullString
+				retireAt  sql.NullString
+			)
+			if err := rows.Scan(&cost.InstanceID, &cost.UserID, &cost.Price.Plan, &cost.Price.MonthlyCents,
+				&createdAt, &deletedAt, &retireAt); err != nil {
+				return err
+			}
+
+			cost.CreatedAt, err = time.Parse(time.DateTime, createdAt)
+			if err != nil {
+				return err
+			}
+			if cost.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+				return err
+			}
+			if cost.RetireAt, err = parseNullTime(retireAt); err != nil {
+				return err
+			}
+			result = append(result, &cost)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_ListInstanceCosts() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Date(1984, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	otherID := core.UserID{UUID: uuid.FromStringOrNil("33000000-0000-0000-0000-000000000000")}
+	price := core.PlanPrice{Plan: "vc2-1c-1gb", MonthlyCents: 500}
+	repo := NewRepository(s.db)
+
+	running := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, adminID, start.Add(96*time.Hour))
+	deleted := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, clientID, start.AddDate(0, -1, 0))
+	later := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, clientID, end.Add(time.Hour))
+	other := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, otherID, start)
+	for _, instance := range []*core.Instance{running, deleted} {
+		instance.Price = price
+		_, err := repo.SaveInstance(ctx, instance)
+		s.Require().NoError(err, "should save instance without any error")
+	}
+	s.Require().NoError(repo.DeleteInstance(ctx, deleted.ID, authz.Clause{}), "should delete instance without any error")
+	retireAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	s.Require().NoError(repo.RetireInstance(ctx, deleted.ID, retireAt), "should retire instance without any error")
+	for _, instance := range []*core.Instance{later, other} {
+		instance.Price = price
+		_, err := repo.SaveInstance(ctx, instance)
+		s.Require().NoError(err, "should save instance without any error")
+	}
+
+	actual, err := repo.ListInstanceCosts(ctx, groupID, start, end)
+	s.Require().NoError(err, "should list instance costs without any error")
+	s.Require().Len(actual, 2, "should only list the instances of the group that ran in the period")
+
+	s.Require().Equal(deleted.ID, actual[0].InstanceID)
+	s.Require().Equal(clientID, actual[0].UserID)
+	s.Require().Equal(price, actual[0].Price)
+	s.Require().NotNil(actual[0].DeletedAt, "should have the deletion time of deleted instances")
+	s.Require().Equal(&retireAt, actual[0].RetireAt, "should have the retirement time of retired instances")
+
+	s.Require().Equal(&core.InstanceCost{
+		InstanceID: running.ID,
+		UserID:     adminID,
+		Price:      price,
+		CreatedAt:  running.CreatedAt,
+	}, actual[1], "should list the running instances without deletion time")
+}
+
+func (s *RepositoryTestSuite) Test_ListInstanceCosts_NonUTC() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Times are stored in UTC, whatever the zone of the host is.
+	local := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	defer func() { time.Local = local }()
+
+	start := time.Date(1984, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	// Created an hour after the end of the period, which is the day before in the local zone.
+	later := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, adminID, end.Add(time.Hour).Local())
+	// Created before the period, and deleted now, which is hours earlier on the
+	// wall clock of the local zone.
+	deleted := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, clientID, start.Local())
+	for _, instance := range []*core.Instance{later, deleted} {
+		_, err := repo.SaveInstance(ctx, instance)
+		s.Require().NoError(err, "should save instance without any error")
+	}
+	s.Require().NoError(repo.DeleteInstance(ctx, deleted.ID, authz.Clause{}), "should delete instance without any error")
+
+	actual, err := repo.ListInstanceCosts(ctx, groupID, start, end)
+	s.Require().NoError(err, "should list instance costs without any error")
+	s.Require().Len(actual, 1, "should not list the instances created after the period in UTC")
+	s.Require().Equal(deleted.ID, actual[0].InstanceID)
+	s.Require().True(deleted.CreatedAt.Equal(actual[0].CreatedAt), "should read the creation time back as it was saved")
+
+	now := time.Now()
+	actual, err = repo.ListInstanceCosts(ctx, groupID, now.Add(-time.Hour), now.Add(time.Hour))
+	s.Require().NoError(err, "should list instance costs without any error")
+	s.Require().Len(actual, 2, "should list the instances deleted within the period in UTC")
+	s.Require().NotNil(actual[0].DeletedAt)
+	s.Require().WithinDuration(now, *actual[0].DeletedAt, time.Minute, "should read the deletion time back in UTC")
+}
@@ -3,9 +3,12 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/url"
+	"time"
 
 	"vpainless/internal/hosting/core"
 	"vpainless/internal/pkg/db"
@@ -75,7 +78,12 @@ type groupGetQuery struct {
 func (q groupGetQuery) SQL() (string, []any) {
 	qb := querybuilder.New(`
 		select
-			g.id, g.name, g.provider_name, g.provider_url, g.provider_apikey, g.default_xray_template, g.default_ssh_key, g.default_startup_script
+			g.id, g.name, g.provider_name, g.provider_url, g.provider_apikey, g.default_xray_template, g.default_ssh_key, g.default_startup_script,
+			g.shared_instances, g.max_clients_per_instance, g.sni_pool,
+			g.auto_renew, g.max_renewals_per_day, g.min_probe_reporters, g.regions, g.rotation_interval_hours, g.ttl_hours,
+			g.renewal_grace_minutes, g.traffic_quota_bytes, g.active_days_quota, g.monthly_budget_cents,
+			g.user_rate_burst, g.user_rate_per_hour, g.group_rate_burst, g.group_rate_per_hour, g.require_approval, g.decision_logs,
+			g.require_two_factor
 		from groups g
 		where g.id = ?`, q.groupID,
 	)
@@ -84,7 +92,8 @@ func (q groupGetQuery) SQL() (string, []any) {
 
 func scanGroup(row *sql.Row) (*core.Group, error) {
 	var group core.Group
-	var u string
+	var u, pool, regions string
+	var rotationHours, ttlHours, graceMinutes int
 	if err := row.Scan(
 		&group.ID,
 		&group.Name,
@@ -94,6 +103,26 @@ func scanGroup(row *sql.Row) (*core.Group, error) {
 		&group.DefaultXrayTemplate,
 		&group.DefaultSSHKey.ID,
 		&group.DefaultStartUpScript.ID,
+		&group.Settings.SharedInstances,
+		&group.Settings.MaxClientsPerInstance,
+		&pool,
+		&group.Settings.AutoRenew,
+		&group.Settings.MaxRenewalsPerDay,
+		&group.Settings.MinProbeReporters,
+		&regions,
+		&rotationHours,
+		&ttlHours,
+		&graceMinutes,
+		&group.Settings.Quota.TrafficBytes,
+		&group.Settings.Quota.ActiveDays,
+		&group.Settings.MonthlyBudget,
+		&group.Settings.UserRateLimit.Burst,
+		&group.Settings.UserRateLimit.PerHour,
+		&group.Settings.GroupRateLimit.Burst,
+		&group.Settings.GroupRateLimit.PerHour,
+		&group.Settings.RequireApproval,
+		&group.Settings.DecisionLogs,
+		&group.Settings.RequireTwoFactor,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, core.ErrNotFound
@@ -107,6 +136,25 @@ func scanGroup(row *sql.Row) (*core.Group, error) {
 	}
 
 	group.Host.Base = *parsed
+	group.Settings.RotationInterval = time.Duration(rotationHours) * time.Hour
+	group.Settings.TTL = time.Duration(ttlHours) * time.Hour
+	group.Settings.RenewalGracePeriod = time.Duration(graceMinutes) * time.Minute
+
+	var sniPool []string
+	if err := json.Unmarshal([]byte(pool), &sniPool); err != nil {
+		return nil, fmt.Errorf("error parsing sni pool of group %s: %w", group.ID, err)
+	}
+	if len(sniPool) > 0 {
+		group.Settings.SNIPool = sniPool
+	}
+
+	var regionList []string
+	if err := json.Unmarshal([]byte(regions), &regionList); err != nil {
+		return nil, fmt.Errorf("error parsing regions of group %s: %w", group.ID, err)
+	}
+	if len(regionList) > 0 {
+		group.Settings.Regions = regionList
+	}
 	return &group, nil
 }
 
@@ -202,6 +250,16 @@ func scanStartupScript(row *sql.Row) (core.StartUpScript, error) {
 
 func (r *Repository) SaveGroup(ctx context.Context, group *core.Group) (*core.Group, error) {
 	logger := slog.With("group_id", group.ID)
+	pool, err := marshalList(group.Settings.SNIPool)
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := marshalList(group.Settings.Regions)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := r.db.InTxDo(ctx, sql.LevelLinearizable, func(ctx context.Context, tx *db.Tx) error {
 		logger.InfoContext(ctx, "DB: insert xray templates...")
 		xrayquery := `
@@ -267,9 +325,29 @@ func (r *Repository) SaveGroup(ctx context.Context, group *core.Group) (*core.Gr
 				provider_apikey,
 				default_xray_template,
 				default_ssh_key,
-				default_startup_script
+				default_startup_script,
+				shared_instances,
+				max_clients_per_instance,
+				sni_pool,
+				auto_renew,
+				max_renewals_per_day,
+				min_probe_reporters,
+				regions,
+				rotation_interval_hours,
+				ttl_hours,
+				renewal_grace_minutes,
+				traffic_quota_bytes,
+				active_days_quota,
+				monthly_budget_cents,
+				user_rate_burst,
+				user_rate_per_hour,
+				group_rate_burst,
+				group_rate_per_hour,
+				require_approval,
+				decision_logs,
+				require_two_factor
 			)
-			values (?, ?, ?, ?, ?, ?, ?, ?)
+			values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			on conflict (id) do update set
 				name = excluded.name,
 				provider_name = excluded.provider_name,
@@ -281,7 +359,16 @@ func (r *Repository) SaveGroup(ctx context.Context, group *core.Group) (*core.Gr
 		`,
 			group.ID, group.Name, group.Host.Name, group.Host.Base.String(),
 			group.Host.APIKey, group.DefaultXrayTemplate, group.DefaultSSHKey.ID,
-			group.DefaultStartUpScript.ID,
+			group.DefaultStartUpScript.ID, group.Settings.SharedInstances, group.Settings.MaxClientsPerInstance,
+			string(pool), group.Settings.AutoRenew, group.Settings.MaxRenewalsPerDay, group.Settings.MinProbeReporters, string(regions),
+			int(group.Settings.RotationInterval.Hours()), int(group.Settings.TTL.Hours()),
+			int(group.Settings.RenewalGracePeriod.Minutes()),
+			group.Settings.Quota.TrafficBytes, group.Settings.Quota.ActiveDays,
+			group.Settings.MonthlyBudget,
+			group.Settings.UserRateLimit.Burst, group.Settings.UserRateLimit.PerHour,
+			group.Settings.GroupRateLimit.Burst, group.Settings.GroupRateLimit.PerHour,
+			group.Settings.RequireApproval, group.Settings.DecisionLogs,
+			group.Settings.RequireTwoFactor,
 		)
 
 		query, args := qb.SQL()
@@ -293,3 +380,108 @@ func (r *Repository) SaveGroup(ctx context.Context, group *core.Group) (*core.Gr
 
 	return group, nil
 }
+
+func (r *Repository) SaveGroupSettings(ctx context.Context, id core.GroupID, settings core.GroupSettings) error {
+	pool, err := marshalList(settings.SNIPool)
+	if err != nil {
+		return err
+	}
+
+	regions, err := marshalList(settings.Regions)
+	if err != nil {
+		return err
+	}
+
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			update groups set
+				shared_instances = ?,
+				max_clients_per_instance = ?,
+				sni_pool = ?,
+				auto_renew = ?,
+				max_renewals_per_day = ?,
+				min_probe_reporters = ?,
+				regions = ?,
+				rotation_interval_hours = ?,
+				ttl_hours = ?,
+				renewal_grace_minutes = ?,
+				traffic_quota_bytes = ?,
+				active_days_quota = ?,
+				monthly_budget_cents = ?,
+				user_rate_burst = ?,
+				user_rate_per_hour = ?,
+				group_rate_burst = ?,
+				group_rate_per_hour = ?,
+				require_approval = ?,
+				decision_logs = ?,
+				require_two_factor = ?
+			where id = ?;
+		`, settings.SharedInstances, settings.MaxClientsPerInstance, string(pool),
+			settings.AutoRenew, settings.MaxRenewalsPerDay, settings.MinProbeReporters, string(regions),
+			int(settings.RotationInterval.Hours()), int(settings.TTL.Hours()),
+			int(settings.RenewalGracePeriod.Minutes()),
+			settings.Quota.TrafficBytes, settings.Quota.ActiveDays,
+			settings.MonthlyBudget,
+			settings.UserRateLimit.Burst, settings.UserRateLimit.PerHour,
+			settings.GroupRateLimit.Burst, settings.GroupRateLimit.PerHour,
+			settings.RequireApproval, settings.DecisionLogs, settings.RequireTwoFactor, id)
+		query, args := qb.SQL()
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return core.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// ListDecisionLogGroups returns the groups that log their policy decisions.
+func (r *Repository) ListDecisionLogGroups(ctx context.Context) ([]core.GroupID, error) {
+	var result []core.GroupID
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`select id from groups where decision_logs;`)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id core.GroupID
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			result = append(result, id)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func marshalList(pool []string) ([]byte, error) {
+	if pool == nil {
+		pool = []string{}
+	}
+
+	b, err := json.Marshal(pool)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling list: %w", err)
+	}
+	return b, nil
+}
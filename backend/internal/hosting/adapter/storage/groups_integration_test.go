@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"vpainless/internal/hosting/core"
 
@@ -81,3 +82,51 @@ func (s *RepositoryTestSuite) Test_Get_Save_Group() {
 	s.Require().NoError(err, "should fetch newest group successfully")
 	s.Require().Equal(group, newest, "saved group should match newest")
 }
+
+func (s *RepositoryTestSuite) Test_Save_GroupSettings() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	repo := NewRepository(s.db)
+
+	group, err := repo.GetGroup(ctx, groupID)
+	s.Require().NoError(err, "should fetch group successfully")
+	s.Require().Equal(core.GroupSettings{
+		MaxClientsPerInstance: core.DefaultMaxClientsPerInstance,
+		MaxRenewalsPerDay:     core.DefaultMaxRenewalsPerDay,
+		MinProbeReporters:     core.DefaultMinProbeReporters,
+		RenewalGracePeriod:    core.DefaultRenewalGracePeriod,
+		UserRateLimit:         core.DefaultUserRateLimit,
+		GroupRateLimit:        core.DefaultGroupRateLimit,
+	}, group.Settings, "should have default settings")
+
+	settings := core.GroupSettings{
+		SharedInstances:       true,
+		MaxClientsPerInstance: 3,
+		SNIPool:               []string{"www.speedtest.net", "www.microsoft.com"},
+		AutoRenew:             true,
+		MaxRenewalsPerDay:     5,
+		Regions:               []string{"fra", "ams"},
+		RotationInterval:      7 * 24 * time.Hour,
+		TTL:                   30 * 24 * time.Hour,
+		MinProbeReporters:     1,
+		RenewalGracePeriod:    30 * time.Minute,
+		UserRateLimit:         core.RateLimit{Burst: 5, PerHour: 2},
+		RequireApproval:       true,
+		DecisionLogs:          true,
+		RequireTwoFactor:      true,
+	}
+	s.Require().NoError(repo.SaveGroupSettings(ctx, groupID, settings), "should save settings successfully")
+
+	group, err = repo.GetGroup(ctx, groupID)
+	s.Require().NoError(err, "should fetch group successfully")
+	s.Require().Equal(settings, group.Settings, "fetched settings should match the saved ones")
+
+	groups, err := repo.ListDecisionLogGroups(ctx)
+	s.Require().NoError(err, "should list decision log groups successfully")
+	s.Require().Equal([]core.GroupID{groupID}, groups, "should list the groups that log decisions")
+
+	err = repo.SaveGroupSettings(ctx, core.GroupID{UUID: uuid.Must(uuid.NewV4())}, settings)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not save settings of unknown groups")
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) SaveHealthCheck(ctx context.Context, check *core.HealthCheck) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into health_checks (instance_id, checked_at, tcp, reality, xray, error)
+			values (?, ?, ?, ?, ?, nullif(?, ''));
+		`, check.InstanceID, check.CheckedAt.UTC().Format(time.DateTime), check.TCP, check.Reality, check.Xray, check.Error)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListHealthChecks(ctx context.Context, id core.InstanceID, limit int) ([]*core.HealthCheck, error) {
+	var result []*core.HealthCheck
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select instance_id, checked_at, tcp, reality, xray, error
+			from health_checks
+			where instance_id = ?
+			order by checked_at desc
+			limit ?;
+		`, id, limit)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				check     core.HealthCheck
+				checkedAt string
+				errText   sql.NullString
+			)
+			if err := rows.Scan(&check.InstanceID, &checkedAt, &check.TCP, &check.Reality, &check.Xray, &errText); err != nil {
+				return err
+			}
+
+			check.CheckedAt, err = time.Parse(time.DateTime, checkedAt)
+			if err != nil {
+				return err
+			}
+			check.Error = errText.String
+			result = append(result, &check)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) DeleteHealthChecks(ctx context.Context, before time.Time) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`delete from health_checks where checked_at < ?;`, before.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
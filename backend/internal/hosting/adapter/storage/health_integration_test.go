@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_List_Delete_HealthChecks() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	userID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+
+	repo := NewRepository(s.db)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	old := &core.HealthCheck{
+		InstanceID: instance.ID,
+		CheckedAt:  now,
+		TCP:        true,
+		Reality:    true,
+		Xray:       true,
+	}
+	latest := &core.HealthCheck{
+		InstanceID: instance.ID,
+		CheckedAt:  now.Add(time.Hour),
+		TCP:        true,
+		Error:      "reality: tls handshake failed",
+	}
+	for _, c := range []*core.HealthCheck{old, latest} {
+		s.Require().NoError(repo.SaveHealthCheck(ctx, c), "should save health check without any error")
+	}
+
+	actual, err := repo.ListHealthChecks(ctx, instance.ID, 10)
+	s.Require().NoError(err, "should list health checks without any error")
+	s.Require().Equal([]*core.HealthCheck{latest, old}, actual, "should list health checks newest first")
+
+	actual, err = repo.ListHealthChecks(ctx, instance.ID, 1)
+	s.Require().NoError(err, "should list health checks without any error")
+	s.Require().Equal([]*core.HealthCheck{latest}, actual, "should limit the health checks")
+
+	s.Require().NoError(repo.DeleteHealthChecks(ctx, now.Add(time.Minute)), "should delete old health checks without any error")
+
+	actual, err = repo.ListHealthChecks(ctx, instance.ID, 10)
+	s.Require().NoError(err, "should list health checks without any error")
+	s.Require().Equal([]*core.HealthCheck{latest}, actual, "should only keep the recent health checks")
+}
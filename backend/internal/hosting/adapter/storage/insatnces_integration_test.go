@@ -17,12 +17,14 @@ func (s *RepositoryTestSuite) Test_Get_Save_Instance() {
 
 	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
 	userID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
 	instanceID := core.InstanceID{UUID: uuid.Must(uuid.NewV4())}
 
 	instance := &core.Instance{
 		ID:         instanceID,
 		RemoteID:   instanceID,
 		Owner:      userID,
+		GroupID:    groupID,
 		CreatedAt:  now,
 		Status:     core.StatusInitializing,
 		Config:     core.XrayConfig{},
@@ -62,12 +64,14 @@ func (s *RepositoryTestSuite) Test_Find_Save_Instance() {
 
 	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
 	userID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
 	instanceID := core.InstanceID{UUID: uuid.Must(uuid.NewV4())}
 
 	instance := &core.Instance{
 		ID:        instanceID,
 		RemoteID:  instanceID,
 		Owner:     userID,
+		GroupID:   groupID,
 		IP:        net.ParseIP("192.168.0.1"),
 		CreatedAt: now,
 		Status:    core.StatusInitializing,
@@ -81,23 +85,62 @@ func (s *RepositoryTestSuite) Test_Find_Save_Instance() {
 	repo.now = func() time.Time {
 		return now
 	}
-	_, err := repo.FindInstance(ctx, userID)
+	_, err := repo.FindInstance(ctx, userID, groupID)
 	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the instance")
 
 	actual, err := repo.SaveInstance(ctx, instance)
 	s.Require().NoError(err, "should save instance without any error")
 	s.Require().Equal(instance, actual, "saved instance should match the original one")
 
-	actual, err = repo.FindInstance(ctx, userID)
+	actual, err = repo.FindInstance(ctx, userID, groupID)
 	s.Require().NoError(err, "should get instance without any error")
 	s.Require().Equal(instance, actual, "fetched instance should match the original one")
 }
 
+func (s *RepositoryTestSuite) Test_Find_Instance_Per_Group() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	userID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	repo := NewRepository(s.db)
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	_, err = repo.FindInstance(ctx, userID, otherGroupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the instance in other groups")
+
+	other := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+	other.GroupID = otherGroupID
+	_, err = repo.SaveInstance(ctx, other)
+	s.Require().NoError(err, "should save an instance of the user in another group")
+
+	actual, err := repo.FindInstance(ctx, userID, instance.GroupID)
+	s.Require().NoError(err, "should find the instance without any error")
+	s.Require().Equal(instance, actual, "should find the instance of the group")
+
+	actual, err = repo.FindInstance(ctx, userID, otherGroupID)
+	s.Require().NoError(err, "should find the instance without any error")
+	s.Require().Equal(other, actual, "should find the instance of the other group")
+}
+
+// userGroups are the groups of the users inserted by SetupTest.
+var userGroups = map[string]string{
+	"11000000-0000-0000-0000-000000000000": "00000000-0000-0000-0000-111111111111",
+	"22000000-0000-0000-0000-000000000000": "00000000-0000-0000-0000-111111111111",
+	"33000000-0000-0000-0000-000000000000": "00000000-0000-0000-0000-222222222222",
+}
+
+// fakeInstance returns an instance of the owner, in their group.
 func fakeInstance(id core.InstanceID, owner core.UserID, created time.Time) *core.Instance {
 	return &core.Instance{
 		ID:        id,
 		RemoteID:  id,
 		Owner:     owner,
+		GroupID:   core.GroupID{UUID: uuid.FromStringOrNil(userGroups[owner.String()])},
 		IP:        net.ParseIP("192.168.0.1"),
 		CreatedAt: created,
 		Status:    core.StatusInitializing,
@@ -143,7 +186,7 @@ func (s *RepositoryTestSuite) Test_List_Instances() {
 	s.Require().ElementsMatch(actual, instances, "should list all instances")
 
 	actual, err = repo.ListInstances(ctx, authz.Clause{
-		Condition: "group_id = ?",
+		Condition: "i.group_id = ?",
 		Values:    []any{uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")},
 	})
 	s.Require().NoError(err, "should list instance without any error")
@@ -163,12 +206,14 @@ func (s *RepositoryTestSuite) Test_Delete_Instance() {
 
 	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
 	userID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
 	instanceID := core.InstanceID{UUID: uuid.Must(uuid.NewV4())}
 
 	instance := &core.Instance{
 		ID:         instanceID,
 		RemoteID:   instanceID,
 		Owner:      userID,
+		GroupID:    groupID,
 		CreatedAt:  now,
 		Status:     core.StatusInitializing,
 		Config:     core.XrayConfig{},
@@ -199,7 +244,7 @@ func (s *RepositoryTestSuite) Test_Delete_Instance() {
 	})
 	s.Require().ErrorIs(err, core.ErrNotFound, "should not get instance after delettion")
 
-	_, err = repo.FindInstance(ctx, userID)
+	_, err = repo.FindInstance(ctx, userID, groupID)
 	s.Require().ErrorIs(err, core.ErrNotFound, "should not find instance after delettion")
 }
 
@@ -270,3 +315,57 @@ func (s *RepositoryTestSuite) Test_Delete_Instance_Partials() {
 	})
 	s.Require().NoError(err, "should be able to delete the instance with group partial")
 }
+
+const sharedInstancesModule = `package hosting.instances
+
+import rego.v1
+
+allow if {
+	input.action = "list"
+	input.row.user_id = input.principal.id
+}
+
+allow if {
+	input.action = "list"
+	input.principal.id in input.row.client_ids
+}
+`
+
+func (s *RepositoryTestSuite) Test_List_Instances_Residual() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	adminID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	firstClient := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	secondClient := core.UserID{UUID: uuid.FromStringOrNil("33000000-0000-0000-0000-000000000000")}
+
+	instances := []*core.Instance{
+		fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, firstClient, now),
+		fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, adminID, now),
+		fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, secondClient, now),
+	}
+
+	repo := NewRepository(s.db)
+	for _, instance := range instances {
+		_, err := repo.SaveInstance(ctx, instance)
+		s.Require().NoError(err, "should save instance without any error")
+	}
+
+	_, err := repo.SaveClient(ctx, &core.InstanceClient{
+		ID:         core.ClientID{UUID: uuid.Must(uuid.NewV4())},
+		InstanceID: instances[1].ID,
+		UserID:     firstClient,
+		CreatedAt:  now,
+	})
+	s.Require().NoError(err, "should save client without any error")
+
+	validator := authz.NewValidator("hosting", authz.WithRegoModule("instances.rego", sharedInstancesModule))
+	policy, err := validator.Can(ctx, authz.Principal{ID: firstClient.UUID, Role: "client"}, authz.List, authz.Resource{Group: "instances"})
+	s.Require().NoError(err, "should evaluate the policy without any error")
+	s.Require().True(policy.Allow, "clients should be allowed to list instances")
+
+	actual, err := repo.ListInstances(ctx, policy.Partial)
+	s.Require().NoError(err, "should list instance without any error")
+	s.Require().ElementsMatch(instances[:2], actual, "should list owned and shared instances")
+}
@@ -12,22 +12,39 @@ import (
 	"vpainless/internal/pkg/authz"
 	"vpainless/internal/pkg/db"
 	"vpainless/pkg/querybuilder"
+
+	"github.com/gofrs/uuid/v5"
 )
 
+// instanceColumns are the columns of the instances the policies filter on.
+var instanceColumns = authz.Columns{
+	"id":         "i.id",
+	"user_id":    "i.user_id",
+	"group_id":   "i.group_id",
+	"client_ids": "(select user_id from instance_clients where instance_id = i.id and revoked_at is null)",
+}
+
 func (r *Repository) GetInstance(ctx context.Context, id core.InstanceID, partial authz.Clause) (*core.Instance, error) {
 	var result *core.Instance
 	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
 		qb := querybuilder.New(`
-			select id, user_id, remote_id, ip, status, connection_str, private_key, created_at
-			from instances
+			select
+				i.id, i.user_id, i.remote_id, i.ip, i.status, i.connection_str, i.private_key, i.created_at,
+				i.shared, i.client_id, i.fake_url, i.reality_private_key, i.reality_public_key, i.short_id, i.region, i.replaces,
+				i.plan, i.monthly_cost_cents, i.group_id
+			from instances i
 		`)
 
 		conds := []querybuilder.Cond{
-			{Text: "id = ?", Args: []any{id}},
-			{Text: "deleted_at is null"},
+			{Text: "i.id = ?", Args: []any{id}},
+			{Text: "i.deleted_at is null"},
 		}
 		if !partial.IsNil() {
-			conds = append(conds, querybuilder.Condition(partial.Condition, partial.Values))
+			cond, err := partial.Cond(instanceColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
 		}
 		qb.Where(conds...)
 		query, args := qb.SQL()
@@ -55,13 +72,41 @@ func scanInstance(row Scanner) (*core.Instance, error) {
 		ip               sql.NullString
 		createdAt        string
 		connectionString sql.NullString
+		clientID         sql.NullString
+		fakeURL          sql.NullString
+		realityPrivate   sql.NullString
+		realityPublic    sql.NullString
+		shortID          sql.NullString
+		region           sql.NullString
+		replaces         sql.NullString
+		groupID          sql.NullString
 	)
 
-	err := row.Scan(&result.ID, &result.Owner, &result.RemoteID, &ip, &result.Status, &connectionString, &result.PrivateKey, &createdAt)
+	err := row.Scan(
+		&result.ID, &result.Owner, &result.RemoteID, &ip, &result.Status, &connectionString, &result.PrivateKey, &createdAt,
+		&result.Shared, &clientID, &fakeURL, &realityPrivate, &realityPublic, &shortID, &region, &replaces,
+		&result.Price.Plan, &result.Price.MonthlyCents, &groupID,
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	if clientID.Valid {
+		result.Config.Reality = core.XrayTemplate{
+			ID:                   core.XrayTemplateID{UUID: uuid.FromStringOrNil(clientID.String)},
+			FakeURL:              fakeURL.String,
+			Curve25519PrivateKey: realityPrivate.String,
+			Curve25519PublicKey:  realityPublic.String,
+			ShortID:              shortID.String,
+		}
+	}
+
+	if replaces.Valid {
+		result.Replaces = &core.InstanceID{UUID: uuid.FromStringOrNil(replaces.String)}
+	}
+
+	result.Region = region.String
+	result.GroupID = core.GroupID{UUID: uuid.FromStringOrNil(groupID.String)}
 	if ip.Valid {
 		result.IP = net.ParseIP(ip.String)
 	}
@@ -77,8 +122,17 @@ func scanInstance(row Scanner) (*core.Instance, error) {
 
 func (r *Repository) SaveInstance(ctx context.Context, instance *core.Instance) (*core.Instance, error) {
 	if err := r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
-		createdAt := instance.CreatedAt.Format(time.DateTime)
-		updatedAt := time.Now().Format(time.DateTime)
+		createdAt := instance.CreatedAt.UTC().Format(time.DateTime)
+		updatedAt := time.Now().UTC().Format(time.DateTime)
+
+		var clientID, replaces any
+		reality := instance.Config.Reality
+		if !reality.IsNil() {
+			clientID = reality.ID
+		}
+		if instance.Replaces != nil {
+			replaces = *instance.Replaces
+		}
 
 		qb := querybuilder.New(`
 			insert into instances (
@@ -90,16 +144,35 @@ func (r *Repository) SaveInstance(ctx context.Context, instance *core.Instance)
 				connection_str,
 				private_key,
 				created_at,
-				updated_at
-			) values (?, ?, ?, nullif(?, ''), ?, nullif(?, ''), ?, ?, ?)
+				updated_at,
+				shared,
+				client_id,
+				fake_url,
+				reality_private_key,
+				reality_public_key,
+				short_id,
+				region,
+				replaces,
+				plan,
+				monthly_cost_cents,
+				group_id
+			) values (?, ?, ?, nullif(?, ''), ?, nullif(?, ''), ?, ?, ?, ?, ?, nullif(?, ''), nullif(?, ''), nullif(?, ''), nullif(?, ''), nullif(?, ''), ?, ?, ?, ?)
 			on conflict (id) do update set
 				ip = excluded.ip,
 				status = excluded.status,
 				connection_str = excluded.connection_str,
+				client_id = excluded.client_id,
+				fake_url = excluded.fake_url,
+				reality_private_key = excluded.reality_private_key,
+				reality_public_key = excluded.reality_public_key,
+				short_id = excluded.short_id,
+				replaces = excluded.replaces,
 				updated_at = ?
 			where deleted_at is null;
 		`, instance.ID, instance.Owner, instance.RemoteID, instance.IP.String(), string(instance.Status),
-			instance.Config.ConnectionString, instance.PrivateKey, createdAt, updatedAt, updatedAt,
+			instance.Config.ConnectionString, instance.PrivateKey, createdAt, updatedAt,
+			instance.Shared, clientID, reality.FakeURL, reality.Curve25519PrivateKey, reality.Curve25519PublicKey, reality.ShortID,
+			instance.Region, replaces, instance.Price.Plan, instance.Price.MonthlyCents, uuidOrNull(instance.GroupID.UUID), updatedAt,
 		)
 		query, args := qb.SQL()
 		_, err := tx.ExecContext(ctx, query, args...)
@@ -111,14 +184,17 @@ func (r *Repository) SaveInstance(ctx context.Context, instance *core.Instance)
 	return instance, nil
 }
 
-func (r *Repository) FindInstance(ctx context.Context, id core.UserID) (*core.Instance, error) {
+func (r *Repository) FindInstance(ctx context.Context, id core.UserID, group core.GroupID) (*core.Instance, error) {
 	var result *core.Instance
 	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
 		qb := querybuilder.New(`
-			select id, user_id, remote_id, ip, status, connection_str, private_key, created_at
+			select
+				id, user_id, remote_id, ip, status, connection_str, private_key, created_at,
+				shared, client_id, fake_url, reality_private_key, reality_public_key, short_id, region, replaces,
+				plan, monthly_cost_cents, group_id
 			from instances
-			where user_id = ? and deleted_at is null;
-		`, id)
+			where user_id = ? and group_id = ? and deleted_at is null and replaces is null;
+		`, id, group)
 		query, args := qb.SQL()
 		row := tx.QueryRowContext(ctx, query, args...)
 		var err error
@@ -142,7 +218,10 @@ func (r *Repository) ListInstances(ctx context.Context, partial authz.Clause) ([
 	var result []*core.Instance
 	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
 		qb := querybuilder.New(`
-			select i.id, i.user_id, i.remote_id, i.ip, i.status, i.connection_str, i.private_key, i.created_at
+			select
+				i.id, i.user_id, i.remote_id, i.ip, i.status, i.connection_str, i.private_key, i.created_at,
+				i.shared, i.client_id, i.fake_url, i.reality_private_key, i.reality_public_key, i.short_id, i.region, i.replaces,
+				i.plan, i.monthly_cost_cents, i.group_id
 			from instances i
 			inner join users u on u.id = i.user_id
 		`)
@@ -151,7 +230,11 @@ func (r *Repository) ListInstances(ctx context.Context, partial authz.Clause) ([
 			querybuilder.Condition("i.deleted_at is null", nil),
 		}
 		if !partial.IsNil() {
-			conds = append(conds, querybuilder.Condition(partial.Condition, partial.Values))
+			cond, err := partial.Cond(instanceColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
 		}
 		qb.Where(conds...)
 		query, args := qb.SQL()
@@ -168,6 +251,34 @@ func (r *Repository) ListInstances(ctx context.Context, partial authz.Clause) ([
 	return result, nil
 }
 
+func (r *Repository) FindSharedInstance(ctx context.Context, id core.GroupID, max int) (*core.Instance, error) {
+	var result *core.Instance
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select
+				i.id, i.user_id, i.remote_id, i.ip, i.status, i.connection_str, i.private_key, i.created_at,
+				i.shared, i.client_id, i.fake_url, i.reality_private_key, i.reality_public_key, i.short_id, i.region, i.replaces,
+				i.plan, i.monthly_cost_cents, i.group_id
+			from instances i
+			where i.shared and i.deleted_at is null and i.status = ? and i.group_id = ?
+				and (select count(*) from instance_clients c where c.instance_id = i.id and c.revoked_at is null) < ?
+			order by i.created_at
+			limit 1;
+		`, string(core.StatusOK), id, max)
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanInstance(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func scanInstances(rows *sql.Rows) ([]*core.Instance, error) {
 	var instances []*core.Instance
 
@@ -189,8 +300,8 @@ func scanInstances(rows *sql.Rows) ([]*core.Instance, error) {
 
 func (r *Repository) DeleteInstance(ctx context.Context, id core.InstanceID, partial authz.Clause) error {
 	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
-		now := time.Now().Format(time.DateTime)
-		qb := querybuilder.New(`update instances set deleted_at = ?`, now)
+		now := time.Now().UTC().Format(time.DateTime)
+		qb := querybuilder.New(`update instances as i set deleted_at = ?`, now)
 
 		conds := []querybuilder.Cond{
 			querybuilder.Condition("id = ?", []any{id}),
@@ -198,7 +309,11 @@ func (r *Repository) DeleteInstance(ctx context.Context, id core.InstanceID, par
 		}
 
 		if !partial.IsNil() {
-			conds = append(conds, querybuilder.Condition(partial.Condition, partial.Values))
+			cond, err := partial.Cond(instanceColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
 		}
 
 		qb.Where(conds...)
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// notificationColumns are the columns of the notifications the policies filter on.
+var notificationColumns = authz.Columns{
+	"user_id": "n.user_id",
+}
+
+func (r *Repository) SaveNotification(ctx context.Context, notification *core.Notification) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into notifications (id, user_id, instance_id, kind, message, created_at)
+			values (?, ?, ?, ?, ?, ?);
+		`, notification.ID, notification.UserID, notification.InstanceID, string(notification.Kind),
+			notification.Message, notification.CreatedAt.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListNotifications(ctx context.Context, partial authz.Clause, limit int) ([]*core.Notification, error) {
+	var result []*core.Notification
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select n.id, n.user_id, n.instance_id, n.kind, n.message, n.created_at
+			from notifications n
+		`)
+		if !partial.IsNil() {
+			cond, err := partial.Cond(notificationColumns)
+			if err != nil {
+				return err
+			}
+			qb.Where(cond)
+		}
+		qb.Append(`order by n.created_at desc limit ?`, limit)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				notification core.Notification
+				createdAt    string
+			)
+			if err := rows.Scan(&notification.ID, &notification.UserID, &notification.InstanceID,
+				&notification.Kind, &notification.Message, &createdAt); err != nil {
+				return err
+			}
+
+			notification.CreatedAt, err = time.Parse(time.DateTime, createdAt)
+			if err != nil {
+				return err
+			}
+			result = append(result, &notification)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
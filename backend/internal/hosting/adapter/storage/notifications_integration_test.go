@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_List_Notifications() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, ownerID, now)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	older := &core.Notification{
+		ID:         core.NotificationID{UUID: uuid.Must(uuid.NewV4())},
+		UserID:     ownerID,
+		InstanceID: instance.ID,
+		Kind:       core.NotificationReplaced,
+		Message:    "replaced",
+		CreatedAt:  now.Add(-time.Hour),
+	}
+	newer := &core.Notification{
+		ID:         core.NotificationID{UUID: uuid.Must(uuid.NewV4())},
+		UserID:     ownerID,
+		InstanceID: instance.ID,
+		Kind:       core.NotificationExpired,
+		Message:    "expired",
+		CreatedAt:  now,
+	}
+	other := &core.Notification{
+		ID:         core.NotificationID{UUID: uuid.Must(uuid.NewV4())},
+		UserID:     clientID,
+		InstanceID: instance.ID,
+		Kind:       core.NotificationExpired,
+		Message:    "expired",
+		CreatedAt:  now,
+	}
+	for _, n := range []*core.Notification{older, newer, other} {
+		s.Require().NoError(repo.SaveNotification(ctx, n), "should save notification without any error")
+	}
+
+	partial := authz.Clause{Condition: "n.user_id = ?", Values: []any{ownerID}}
+	actual, err := repo.ListNotifications(ctx, partial, 10)
+	s.Require().NoError(err, "should list notifications without any error")
+	s.Require().Equal([]*core.Notification{newer, older}, actual, "should only list the notifications of the user, newest first")
+
+	actual, err = repo.ListNotifications(ctx, partial, 1)
+	s.Require().NoError(err, "should list notifications without any error")
+	s.Require().Equal([]*core.Notification{newer}, actual, "should only list the latest notifications")
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// probeColumns are the columns of the probe reports the policies filter on. Reports
+// are filtered by the instances they are on.
+var probeColumns = authz.Columns{
+	"instance_id": "r.instance_id",
+	"group_id":    "i.group_id",
+}
+
+func (r *Repository) SaveProbeReport(ctx context.Context, report *core.ProbeReport) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		var ip sql.NullString
+		if report.IP != nil {
+			ip = sql.NullString{String: report.IP.String(), Valid: true}
+		}
+
+		qb := querybuilder.New(`
+			insert into probe_reports (instance_id, user_id, ip, reported_at, tcp, tls, latency_ms, error)
+			values (?, ?, ?, ?, ?, ?, ?, nullif(?, ''));
+		`, report.InstanceID, report.ReporterID, ip, report.ReportedAt.UTC().Format(time.DateTime),
+			report.TCP, report.TLS, report.Latency.Milliseconds(), report.Error,
+		)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) SummarizeProbeReports(ctx context.Context, since time.Time, partial authz.Clause) ([]*core.ProbeSummary, error) {
+	var result []*core.ProbeSummary
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select r.instance_id, r.ip, count(*), sum(case when r.tcp and r.tls then 0 else 1 end),
+				count(distinct r.user_id), count(distinct case when r.tcp and r.tls then null else r.user_id end)
+			from probe_reports r
+			inner join instances i on i.id = r.instance_id
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("r.reported_at >= ?", []any{since.UTC().Format(time.DateTime)}),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(probeColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		qb.Append(" group by r.instance_id, r.ip order by r.instance_id, r.ip;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				summary core.ProbeSummary
+				ip      sql.NullString
+			)
+			if err := rows.Scan(&summary.InstanceID, &ip, &summary.Reports, &summary.Failed, &summary.Reporters, &summary.FailedReporters); err != nil {
+				return err
+			}
+
+			if ip.Valid {
+				summary.IP = net.ParseIP(ip.String)
+			}
+			result = append(result, &summary)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SummarizeProbeRanges groups the reports on IPv4 addresses by their /24 range,
+// which is the address with its last octet trimmed. Reporters are counted once per
+// range, however many instances of it they probe.
+func (r *Repository) SummarizeProbeRanges(ctx context.Context, since time.Time, partial authz.Clause) ([]*core.ProbeSummary, error) {
+	var result []*core.ProbeSummary
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select rtrim(r.ip, '0123456789') || '0' as ip_range, count(*), sum(case when r.tcp and r.tls then 0 else 1 end),
+				count(distinct r.user_id), count(distinct case when r.tcp and r.tls then null else r.user_id end)
+			from probe_reports r
+			inner join instances i on i.id = r.instance_id
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("r.reported_at >= ?", []any{since.UTC().Format(time.DateTime)}),
+			querybuilder.Condition("r.ip like '%.%.%.%' and r.ip not like '%:%'", nil),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(probeColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		qb.Append(" group by ip_range order by ip_range;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				summary core.ProbeSummary
+				ip      string
+			)
+			if err := rows.Scan(&ip, &summary.Reports, &summary.Failed, &summary.Reporters, &summary.FailedReporters); err != nil {
+				return err
+			}
+
+			summary.Range = &net.IPNet{IP: net.ParseIP(ip).To4(), Mask: net.CIDRMask(24, 32)}
+			result = append(result, &summary)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Summarize_ProbeReports() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	userID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+
+	repo := NewRepository(s.db)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	reports := []*core.ProbeReport{
+		{ReportedAt: now.Add(-2 * time.Hour), TCP: true, TLS: true},
+		{ReportedAt: now, TCP: true, TLS: true, Latency: 120 * time.Millisecond},
+		{ReportedAt: now, TCP: true, Error: "tls: connection reset"},
+		{ReportedAt: now},
+	}
+	for _, r := range reports {
+		r.InstanceID = instance.ID
+		r.ReporterID = userID
+		r.IP = instance.IP
+		s.Require().NoError(repo.SaveProbeReport(ctx, r), "should save probe report without any error")
+	}
+
+	actual, err := repo.SummarizeProbeReports(ctx, now.Add(-time.Hour), authz.Clause{
+		Condition: "i.user_id in (select id from users where group_id = ?)",
+		Values:    []any{groupID},
+	})
+	s.Require().NoError(err, "should summarize probe reports without any error")
+	s.Require().Equal([]*core.ProbeSummary{{
+		InstanceID: instance.ID,
+		IP:         net.ParseIP("192.168.0.1"),
+		Reports:    3,
+		Failed:     2,
+		// Reporters are counted once, however many reports they send.
+		Reporters:       1,
+		FailedReporters: 1,
+	}}, actual, "should summarize the recent reports")
+
+	actual, err = repo.SummarizeProbeReports(ctx, now.Add(-time.Hour), authz.Clause{
+		Condition: "i.user_id in (select id from users where group_id = ?)",
+		Values:    []any{otherGroupID},
+	})
+	s.Require().NoError(err, "should summarize probe reports without any error")
+	s.Require().Empty(actual, "should not summarize reports of other groups")
+}
+
+func (s *RepositoryTestSuite) Test_Summarize_ProbeRanges() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	userID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	otherUserID := core.UserID{UUID: uuid.FromStringOrNil("33000000-0000-0000-0000-000000000000")}
+
+	repo := NewRepository(s.db)
+	reports := []struct {
+		ip       string
+		reporter core.UserID
+		ok       bool
+	}{
+		{"10.0.1.1", userID, false},
+		{"10.0.1.20", userID, false},
+		{"10.0.1.254", userID, false},
+		{"10.0.2.1", userID, false},
+		{"10.0.2.2", otherUserID, true},
+		{"2001:db8::1", userID, false},
+	}
+	// Each instance replaces the previous one, as the user may only have one.
+	var previous *core.InstanceID
+	for _, r := range reports {
+		instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+		instance.IP = net.ParseIP(r.ip)
+		instance.Replaces = previous
+		_, err := repo.SaveInstance(ctx, instance)
+		s.Require().NoError(err, "should save instance without any error")
+		previous = &instance.ID
+
+		s.Require().NoError(repo.SaveProbeReport(ctx, &core.ProbeReport{
+			InstanceID: instance.ID,
+			ReporterID: r.reporter,
+			IP:         instance.IP,
+			ReportedAt: now,
+			TCP:        r.ok,
+			TLS:        r.ok,
+		}), "should save probe report without any error")
+	}
+
+	actual, err := repo.SummarizeProbeRanges(ctx, now.Add(-time.Hour), authz.Clause{})
+	s.Require().NoError(err, "should summarize probe ranges without any error")
+	s.Require().Equal([]*core.ProbeSummary{
+		{
+			Range:   &net.IPNet{IP: net.ParseIP("10.0.1.0").To4(), Mask: net.CIDRMask(24, 32)},
+			Reports: 3,
+			Failed:  3,
+			// A reporter probing several instances of the range counts once.
+			Reporters:       1,
+			FailedReporters: 1,
+		},
+		{
+			Range:           &net.IPNet{IP: net.ParseIP("10.0.2.0").To4(), Mask: net.CIDRMask(24, 32)},
+			Reports:         2,
+			Failed:          1,
+			Reporters:       2,
+			FailedReporters: 1,
+		},
+	}, actual, "should summarize the ipv4 ranges")
+}
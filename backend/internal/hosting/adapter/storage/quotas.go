@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) GetUserQuota(ctx context.Context, id core.UserID) (*core.UserQuota, error) {
+	var result *core.UserQuota
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select user_id, traffic_bytes, active_days, suspended_until, lifted_until
+			from quotas
+			where user_id = ?;
+		`, id)
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanUserQuota(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			result = &core.UserQuota{UserID: id}
+			return nil
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SaveUserQuota(ctx context.Context, quota *core.UserQuota) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		var trafficBytes, activeDays sql.NullInt64
+		if quota.Override != nil {
+			trafficBytes = sql.NullInt64{Int64: quota.Override.TrafficBytes, Valid: true}
+			activeDays = sql.NullInt64{Int64: int64(quota.Override.ActiveDays), Valid: true}
+		}
+
+		qb := querybuilder.New(`
+			insert into quotas (user_id, traffic_bytes, active_days, suspended_until, lifted_until)
+			values (?, ?, ?, ?, ?)
+			on conflict (user_id) do update set
+				traffic_bytes = excluded.traffic_bytes,
+				active_days = excluded.active_days,
+				suspended_until = excluded.suspended_until,
+				lifted_until = excluded.lifted_until;
+		`, quota.UserID, trafficBytes, activeDays, nullTime(quota.SuspendedUntil), nullTime(quota.LiftedUntil))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListExpiredSuspensions(ctx context.Context, before time.Time) ([]*core.UserQuota, error) {
+	var result []*core.UserQuota
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select user_id, traffic_bytes, active_days, suspended_until, lifted_until
+			from quotas
+			where suspended_until is not null and suspended_until <= ?
+			order by suspended_until;
+		`, before.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			quota, err := scanUserQuota(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, quota)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanUserQuota(row Scanner) (*core.UserQuota, error) {
+	var (
+		result                      core.UserQuota
+		trafficBytes, activeDays    sql.NullInt64
+		suspendedUntil, liftedUntil sql.NullString
+	)
+	if err := row.Scan(&result.UserID, &trafficBytes, &activeDays, &suspendedUntil, &liftedUntil); err != nil {
+		return nil, err
+	}
+
+	if trafficBytes.Valid || activeDays.Valid {
+		result.Override = &core.Quota{
+			TrafficBytes: trafficBytes.Int64,
+			ActiveDays:   int(activeDays.Int64),
+		}
+	}
+
+	var err error
+	if result.SuspendedUntil, err = parseNullTime(suspendedUntil); err != nil {
+		return nil, err
+	}
+	if result.LiftedUntil, err = parseNullTime(liftedUntil); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(time.DateTime), Valid: true}
+}
+
+func parseNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.DateTime, s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_UserQuota() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	actual, err := repo.GetUserQuota(ctx, ownerID)
+	s.Require().NoError(err, "should get quota without any error")
+	s.Require().Equal(&core.UserQuota{UserID: ownerID}, actual, "should return an empty quota for users without one")
+
+	until := time.Date(1984, 12, 1, 0, 0, 0, 0, time.UTC)
+	expected := &core.UserQuota{
+		UserID:         ownerID,
+		Override:       &core.Quota{TrafficBytes: 1 << 30, ActiveDays: 10},
+		SuspendedUntil: &until,
+	}
+	s.Require().NoError(repo.SaveUserQuota(ctx, expected), "should save quota without any error")
+
+	actual, err = repo.GetUserQuota(ctx, ownerID)
+	s.Require().NoError(err, "should get quota without any error")
+	s.Require().Equal(expected, actual, "should get the saved quota")
+
+	expected.Override = nil
+	expected.SuspendedUntil = nil
+	expected.LiftedUntil = &until
+	s.Require().NoError(repo.SaveUserQuota(ctx, expected), "should update quota without any error")
+
+	actual, err = repo.GetUserQuota(ctx, ownerID)
+	s.Require().NoError(err, "should get quota without any error")
+	s.Require().Equal(expected, actual, "should get the updated quota")
+}
+
+func (s *RepositoryTestSuite) Test_ListExpiredSuspensions() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 12, 1, 0, 0, 0, 0, time.UTC)
+	later := now.AddDate(0, 1, 0)
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	otherID := core.UserID{UUID: uuid.FromStringOrNil("33000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	expired := &core.UserQuota{UserID: ownerID, SuspendedUntil: &now}
+	for _, q := range []*core.UserQuota{
+		expired,
+		{UserID: clientID, SuspendedUntil: &later},
+		{UserID: otherID, Override: &core.Quota{TrafficBytes: 1}},
+	} {
+		s.Require().NoError(repo.SaveUserQuota(ctx, q), "should save quota without any error")
+	}
+
+	actual, err := repo.ListExpiredSuspensions(ctx, now)
+	s.Require().NoError(err, "should list expired suspensions without any error")
+	s.Require().Equal([]*core.UserQuota{expired}, actual, "should only list the suspensions that ended")
+}
+
+func (s *RepositoryTestSuite) Test_SumUsage() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	traffic, days, err := repo.SumUsage(ctx, ownerID, now)
+	s.Require().NoError(err, "should sum usage without any error")
+	s.Require().Zero(traffic, "should not have any traffic")
+	s.Require().Zero(days, "should not have any active days")
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, ownerID, now)
+	_, err = repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	for _, u := range []*core.Usage{
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(-48 * time.Hour), Uplink: 1, Downlink: 2},
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(time.Hour), Uplink: 10, Downlink: 20},
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(2 * time.Hour), Uplink: 100, Downlink: 200},
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(24 * time.Hour), Uplink: 1000, Downlink: 2000},
+	} {
+		s.Require().NoError(repo.SaveUsage(ctx, u), "should save usage without any error")
+	}
+
+	traffic, days, err = repo.SumUsage(ctx, ownerID, now)
+	s.Require().NoError(err, "should sum usage without any error")
+	s.Require().Equal(int64(3330), traffic, "should sum the traffic since the given time")
+	s.Require().Equal(2, days, "should count the days with traffic since the given time")
+}
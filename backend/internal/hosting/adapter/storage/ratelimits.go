@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) GetBucket(ctx context.Context, key string) (*core.Bucket, error) {
+	var result core.Bucket
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select key, tokens, updated_at
+			from rate_limit_buckets
+			where key = ?;
+		`, key)
+		query, args := qb.SQL()
+
+		var updatedAt string
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&result.Key, &result.Tokens, &updatedAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrNotFound
+			}
+			return err
+		}
+
+		var err error
+		result.UpdatedAt, err = time.Parse(time.DateTime, updatedAt)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *Repository) SaveBucket(ctx context.Context, bucket *core.Bucket) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into rate_limit_buckets (key, tokens, updated_at)
+			values (?, ?, ?)
+			on conflict (key) do update set
+				tokens = excluded.tokens,
+				updated_at = excluded.updated_at;
+		`, bucket.Key, bucket.Tokens, bucket.UpdatedAt.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) GetRateLimitOverride(ctx context.Context, id core.UserID) (*core.RateLimit, error) {
+	var result core.RateLimit
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select burst, per_hour
+			from rate_limit_overrides
+			where user_id = ?;
+		`, id)
+		query, args := qb.SQL()
+
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&result.Burst, &result.PerHour); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *Repository) SaveRateLimitOverride(ctx context.Context, id core.UserID, limit *core.RateLimit) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`delete from rate_limit_overrides where user_id = ?;`, id)
+		if limit != nil {
+			qb = querybuilder.New(`
+				insert into rate_limit_overrides (user_id, burst, per_hour)
+				values (?, ?, ?)
+				on conflict (user_id) do update set
+					burst = excluded.burst,
+					per_hour = excluded.per_hour;
+			`, id, limit.Burst, limit.PerHour)
+		}
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_Get_Bucket() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	repo := NewRepository(s.db)
+
+	_, err := repo.GetBucket(ctx, "user:11000000-0000-0000-0000-000000000000")
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find an unused bucket")
+
+	expected := &core.Bucket{
+		Key:       "user:11000000-0000-0000-0000-000000000000",
+		Tokens:    2.5,
+		UpdatedAt: time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC),
+	}
+	s.Require().NoError(repo.SaveBucket(ctx, expected), "should save bucket without any error")
+
+	expected.Tokens = 0.25
+	expected.UpdatedAt = expected.UpdatedAt.Add(time.Minute)
+	s.Require().NoError(repo.SaveBucket(ctx, expected), "should update bucket without any error")
+
+	actual, err := repo.GetBucket(ctx, expected.Key)
+	s.Require().NoError(err, "should get bucket without any error")
+	s.Require().Equal(expected, actual, "should get the updated bucket")
+}
+
+func (s *RepositoryTestSuite) Test_Save_Get_RateLimitOverride() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	_, err := repo.GetRateLimitOverride(ctx, ownerID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find an override for users without one")
+
+	expected := &core.RateLimit{Burst: 5, PerHour: 1}
+	s.Require().NoError(repo.SaveRateLimitOverride(ctx, ownerID, expected), "should save override without any error")
+
+	actual, err := repo.GetRateLimitOverride(ctx, ownerID)
+	s.Require().NoError(err, "should get override without any error")
+	s.Require().Equal(expected, actual, "should get the saved override")
+
+	s.Require().NoError(repo.SaveRateLimitOverride(ctx, ownerID, nil), "should remove override without any error")
+
+	_, err = repo.GetRateLimitOverride(ctx, ownerID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the removed override")
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+func (r *Repository) SaveRenewal(ctx context.Context, renewal *core.Renewal) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into renewals (id, group_id, instance_id, replacement_id, reason, created_at)
+			values (?, ?, ?, ?, ?, ?);
+		`, renewal.ID, renewal.GroupID, renewal.InstanceID, renewal.ReplacementID, string(renewal.Reason),
+			renewal.CreatedAt.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) CountRenewals(ctx context.Context, id core.GroupID, since time.Time) (int, error) {
+	var count int
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select count(*)
+			from renewals
+			where group_id = ? and created_at >= ?;
+		`, id, since.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		return tx.QueryRowContext(ctx, query, args...).Scan(&count)
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Repository) RetireInstance(ctx context.Context, id core.InstanceID, at time.Time) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			update instances set retire_at = ?
+			where id = ? and deleted_at is not null;
+		`, at.UTC().Format(time.DateTime), id)
+		query, args := qb.SQL()
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return core.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) ListRetiredInstances(ctx context.Context, before time.Time) ([]*core.Instance, error) {
+	var result []*core.Instance
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select
+				i.id, i.user_id, i.remote_id, i.ip, i.status, i.connection_str, i.private_key, i.created_at,
+				i.shared, i.client_id, i.fake_url, i.reality_private_key, i.reality_public_key, i.short_id, i.region, i.replaces,
+				i.plan, i.monthly_cost_cents, i.group_id
+			from instances i
+			where i.deleted_at is not null and i.retire_at <= ?;
+		`, before.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		result, err = scanInstances(rows)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Repository) ClearRetirement(ctx context.Context, id core.InstanceID) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`update instances set retire_at = null where id = ?;`, id)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Replace_Instance_Renewals() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	userID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+	instance.Region = "fra"
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	replacement := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+	replacement.Region = "ams"
+	replacement.Replaces = &instance.ID
+	_, err = repo.SaveInstance(ctx, replacement)
+	s.Require().NoError(err, "should save the replacement alongside the instance")
+
+	second := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, userID, now)
+	second.Replaces = &instance.ID
+	_, err = repo.SaveInstance(ctx, second)
+	s.Require().Error(err, "should not save a second live replacement of the instance")
+
+	actual, err := repo.GetInstance(ctx, replacement.ID, authz.Clause{})
+	s.Require().NoError(err, "should get the replacement without any error")
+	s.Require().Equal(replacement, actual, "fetched replacement should match the saved one")
+
+	actual, err = repo.FindInstance(ctx, userID, groupID)
+	s.Require().NoError(err, "should find the instance without any error")
+	s.Require().Equal(instance, actual, "should ignore the replacement until swapped in")
+
+	count, err := repo.CountRenewals(ctx, groupID, now.Add(-24*time.Hour))
+	s.Require().NoError(err, "should count renewals without any error")
+	s.Require().Zero(count, "should not have any renewals")
+
+	for _, createdAt := range []time.Time{now.Add(-25 * time.Hour), now.Add(-time.Hour)} {
+		s.Require().NoError(repo.SaveRenewal(ctx, &core.Renewal{
+			ID:            core.RenewalID{UUID: uuid.Must(uuid.NewV4())},
+			GroupID:       groupID,
+			InstanceID:    instance.ID,
+			ReplacementID: replacement.ID,
+			Reason:        core.RenewalBlocked,
+			CreatedAt:     createdAt,
+		}), "should save renewal without any error")
+	}
+
+	count, err = repo.CountRenewals(ctx, groupID, now.Add(-24*time.Hour))
+	s.Require().NoError(err, "should count renewals without any error")
+	s.Require().Equal(1, count, "should only count the renewals of the last day")
+
+	count, err = repo.CountRenewals(ctx, core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}, now.Add(-24*time.Hour))
+	s.Require().NoError(err, "should count renewals without any error")
+	s.Require().Zero(count, "should not count the renewals of other groups")
+
+	s.Require().NoError(repo.DeleteInstance(ctx, instance.ID, authz.Clause{}), "should delete the replaced instance")
+	replacement.Replaces = nil
+	_, err = repo.SaveInstance(ctx, replacement)
+	s.Require().NoError(err, "should swap in the replacement without any error")
+
+	actual, err = repo.FindInstance(ctx, userID, groupID)
+	s.Require().NoError(err, "should find the replacement without any error")
+	s.Require().Equal(replacement, actual, "should find the replacement after the swap")
+
+	err = repo.RetireInstance(ctx, replacement.ID, now)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not retire instances that are not deleted")
+	s.Require().NoError(repo.RetireInstance(ctx, instance.ID, now), "should retire the replaced instance")
+
+	retired, err := repo.ListRetiredInstances(ctx, now.Add(-time.Minute))
+	s.Require().NoError(err, "should list retired instances without any error")
+	s.Require().Empty(retired, "should not list the instances in their grace period")
+
+	retired, err = repo.ListRetiredInstances(ctx, now)
+	s.Require().NoError(err, "should list retired instances without any error")
+	s.Require().Equal([]*core.Instance{instance}, retired, "should list the instances after their grace period")
+
+	s.Require().NoError(repo.ClearRetirement(ctx, instance.ID), "should clear the retirement")
+	retired, err = repo.ListRetiredInstances(ctx, now)
+	s.Require().NoError(err, "should list retired instances without any error")
+	s.Require().Empty(retired, "should not list the instances whose server is deleted")
+}
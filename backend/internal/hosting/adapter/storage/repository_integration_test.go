@@ -61,6 +61,7 @@ func (s *RepositoryTestSuite) SetupTest() {
 		delete from startup_scripts;
 		delete from ssh_keys;
 		delete from xray_templates;
+		delete from members;
 		delete from users;
 		delete from groups;
 
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// ruleSetColumns are the columns of the rule sets the policies filter on.
+var ruleSetColumns = authz.Columns{
+	"id":       "id",
+	"group_id": "group_id",
+}
+
+// warpOutbound is the stored format of core.WarpOutbound.
+type warpOutbound struct {
+	SecretKey     string     `json:"secret_key"`
+	Addresses     []string   `json:"addresses"`
+	PeerPublicKey string     `json:"peer_public_key"`
+	Endpoint      string     `json:"endpoint"`
+	Reserved      []int      `json:"reserved,omitempty"`
+	MTU           int        `json:"mtu,omitempty"`
+	Rules         core.Rules `json:"rules"`
+}
+
+func (r *Repository) GetRuleSet(ctx context.Context, id core.RuleSetID, partial authz.Clause) (*core.RuleSet, error) {
+	var result *core.RuleSet
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select id, group_id, name, preset, enabled, block, direct, warp, created_at
+			from rule_sets
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("id = ?", []any{id}),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(ruleSetColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		query, args := qb.SQL()
+
+		var err error
+		result, err = scanRuleSet(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) ListRuleSets(ctx context.Context, partial authz.Clause) ([]*core.RuleSet, error) {
+	var result []*core.RuleSet
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select id, group_id, name, preset, enabled, block, direct, warp, created_at
+			from rule_sets
+		`)
+		if !partial.IsNil() {
+			cond, err := partial.Cond(ruleSetColumns)
+			if err != nil {
+				return err
+			}
+			qb.Where(cond)
+		}
+		qb.Append(" order by created_at, id;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		result, err = scanRuleSets(rows)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) FindRuleSets(ctx context.Context, id core.GroupID) ([]*core.RuleSet, error) {
+	return r.ListRuleSets(ctx, authz.Clause{
+		Condition: "group_id = ? and enabled",
+		Values:    []any{id},
+	})
+}
+
+func (r *Repository) SaveRuleSet(ctx context.Context, ruleset *core.RuleSet) (*core.RuleSet, error) {
+	block, err := json.Marshal(ruleset.Block)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling block rules: %w", err)
+	}
+
+	direct, err := json.Marshal(ruleset.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling direct rules: %w", err)
+	}
+
+	var warp sql.NullString
+	if ruleset.Warp != nil {
+		b, err := json.Marshal(warpOutbound(*ruleset.Warp))
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling warp outbound: %w", err)
+		}
+		warp = sql.NullString{String: string(b), Valid: true}
+	}
+
+	if err := r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		createdAt := ruleset.CreatedAt.UTC().Format(time.DateTime)
+		updatedAt := r.now().UTC().Format(time.DateTime)
+
+		qb := querybuilder.New(`
+			insert into rule_sets (id, group_id, name, preset, enabled, block, direct, warp, created_at, updated_at)
+			values (?, ?, ?, nullif(?, ''), ?, ?, ?, ?, ?, ?)
+			on conflict (id) do update set
+				name = excluded.name,
+				preset = excluded.preset,
+				enabled = excluded.enabled,
+				block = excluded.block,
+				direct = excluded.direct,
+				warp = excluded.warp,
+				updated_at = excluded.updated_at;
+		`, ruleset.ID, ruleset.GroupID, ruleset.Name, ruleset.Preset, ruleset.Enabled,
+			string(block), string(direct), warp, createdAt, updatedAt,
+		)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return ruleset, nil
+}
+
+func (r *Repository) DeleteRuleSet(ctx context.Context, id core.RuleSetID, partial authz.Clause) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`delete from rule_sets`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("id = ?", []any{id}),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(ruleSetColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		query, args := qb.SQL()
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return core.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func scanRuleSet(row Scanner) (*core.RuleSet, error) {
+	var (
+		result        core.RuleSet
+		preset, warp  sql.NullString
+		block, direct string
+		createdAt     string
+	)
+
+	err := row.Scan(&result.ID, &result.GroupID, &result.Name, &preset, &result.Enabled, &block, &direct, &warp, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Preset = preset.String
+	if err := json.Unmarshal([]byte(block), &result.Block); err != nil {
+		return nil, fmt.Errorf("error parsing block rules of %s: %w", result.ID, err)
+	}
+	if err := json.Unmarshal([]byte(direct), &result.Direct); err != nil {
+		return nil, fmt.Errorf("error parsing direct rules of %s: %w", result.ID, err)
+	}
+
+	if warp.Valid {
+		var w warpOutbound
+		if err := json.Unmarshal([]byte(warp.String), &w); err != nil {
+			return nil, fmt.Errorf("error parsing warp outbound of %s: %w", result.ID, err)
+		}
+		result.Warp = (*core.WarpOutbound)(&w)
+	}
+
+	result.CreatedAt, err = time.Parse(time.DateTime, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func scanRuleSets(rows *sql.Rows) ([]*core.RuleSet, error) {
+	var result []*core.RuleSet
+	for rows.Next() {
+		ruleset, err := scanRuleSet(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ruleset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Get_Save_Delete_RuleSet() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	ruleset := &core.RuleSet{
+		ID:      core.RuleSetID{UUID: uuid.Must(uuid.NewV4())},
+		GroupID: groupID,
+		Name:    "my rules",
+		Preset:  "ru",
+		Enabled: true,
+		Block: core.Rules{
+			Domains: []string{"domain:ru"},
+			IPs:     []string{"geoip:ru"},
+		},
+		Direct: core.Rules{
+			Domains: []string{"domain:example.ru"},
+		},
+		Warp: &core.WarpOutbound{
+			SecretKey:     "secret",
+			Addresses:     []string{"172.16.0.2/32"},
+			PeerPublicKey: "public",
+			Endpoint:      "engage.cloudflareclient.com:2408",
+			Reserved:      []int{1, 2, 3},
+			MTU:           1280,
+			Rules:         core.Rules{Domains: []string{"geosite:openai"}},
+		},
+		CreatedAt: now,
+	}
+
+	repo := NewRepository(s.db)
+	repo.now = func() time.Time {
+		return now
+	}
+
+	_, err := repo.GetRuleSet(ctx, ruleset.ID, authz.Clause{})
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the rule set")
+
+	actual, err := repo.SaveRuleSet(ctx, ruleset)
+	s.Require().NoError(err, "should save rule set without any error")
+	s.Require().Equal(ruleset, actual, "saved rule set should match the original one")
+
+	actual, err = repo.GetRuleSet(ctx, ruleset.ID, authz.Clause{
+		Condition: "group_id = ?",
+		Values:    []any{groupID},
+	})
+	s.Require().NoError(err, "should get rule set without any error")
+	s.Require().Equal(ruleset, actual, "fetched rule set should match the original one")
+
+	_, err = repo.GetRuleSet(ctx, ruleset.ID, authz.Clause{
+		Condition: "group_id = ?",
+		Values:    []any{otherGroupID},
+	})
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not get rule sets of other groups")
+
+	ruleset.Warp = nil
+	ruleset.Enabled = false
+	_, err = repo.SaveRuleSet(ctx, ruleset)
+	s.Require().NoError(err, "should save modified rule set without any error")
+
+	actual, err = repo.GetRuleSet(ctx, ruleset.ID, authz.Clause{})
+	s.Require().NoError(err, "should get modified rule set without any error")
+	s.Require().Equal(ruleset, actual, "fetched rule set should match the modified one")
+
+	err = repo.DeleteRuleSet(ctx, ruleset.ID, authz.Clause{
+		Condition: "group_id = ?",
+		Values:    []any{otherGroupID},
+	})
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not delete rule sets of other groups")
+
+	err = repo.DeleteRuleSet(ctx, ruleset.ID, authz.Clause{})
+	s.Require().NoError(err, "should delete rule set without any error")
+
+	_, err = repo.GetRuleSet(ctx, ruleset.ID, authz.Clause{})
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the deleted rule set")
+}
+
+func (s *RepositoryTestSuite) Test_Find_RuleSets() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")}
+	otherGroupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+
+	enabled, err := core.NewRuleSetFromPreset(groupID, "ir")
+	s.Require().NoError(err, "should create rule set from preset")
+	disabled, err := core.NewRuleSetFromPreset(groupID, "ru")
+	s.Require().NoError(err, "should create rule set from preset")
+	disabled.Enabled = false
+	other, err := core.NewRuleSetFromPreset(otherGroupID, "cn")
+	s.Require().NoError(err, "should create rule set from preset")
+
+	repo := NewRepository(s.db)
+	for _, rs := range []*core.RuleSet{enabled, disabled, other} {
+		rs.CreatedAt = rs.CreatedAt.Truncate(time.Second).UTC()
+		_, err := repo.SaveRuleSet(ctx, rs)
+		s.Require().NoError(err, "should save rule set without any error")
+	}
+
+	actual, err := repo.FindRuleSets(ctx, groupID)
+	s.Require().NoError(err, "should find rule sets without any error")
+	s.Require().Equal([]*core.RuleSet{enabled}, actual, "should only find enabled rule sets of the group")
+
+	actual, err = repo.ListRuleSets(ctx, authz.Clause{
+		Condition: "group_id = ?",
+		Values:    []any{groupID},
+	})
+	s.Require().NoError(err, "should list rule sets without any error")
+	s.Require().ElementsMatch([]*core.RuleSet{enabled, disabled}, actual, "should list all rule sets of the group")
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+	"vpainless/internal/pkg/db"
+	"vpainless/pkg/querybuilder"
+)
+
+// usageColumns are the columns of the usage samples the policies filter on. The
+// traffic belongs to the group of the instance it went through.
+var usageColumns = authz.Columns{
+	"user_id":  "s.user_id",
+	"group_id": "i.group_id",
+}
+
+func (r *Repository) SaveUsage(ctx context.Context, usage *core.Usage) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into usage (user_id, instance_id, collected_at, uplink, downlink)
+			values (?, ?, ?, ?, ?);
+		`, usage.UserID, usage.InstanceID, usage.CollectedAt.UTC().Format(time.DateTime),
+			usage.Uplink, usage.Downlink)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListUsageCounters(ctx context.Context, id core.InstanceID) (map[core.UserID]*core.Usage, error) {
+	result := map[core.UserID]*core.Usage{}
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select user_id, instance_id, uplink, downlink
+			from usage_counters
+			where instance_id = ?;
+		`, id)
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var counter core.Usage
+			if err := rows.Scan(&counter.UserID, &counter.InstanceID, &counter.Uplink, &counter.Downlink); err != nil {
+				return err
+			}
+			result[counter.UserID] = &counter
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SaveUsageCounter(ctx context.Context, counter *core.Usage) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into usage_counters (instance_id, user_id, uplink, downlink)
+			values (?, ?, ?, ?)
+			on conflict (instance_id, user_id) do update set
+				uplink = excluded.uplink,
+				downlink = excluded.downlink;
+		`, counter.InstanceID, counter.UserID, counter.Uplink, counter.Downlink)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+func (r *Repository) ListDailyUsage(ctx context.Context, id core.UserID, since time.Time, partial authz.Clause) ([]*core.Usage, error) {
+	var result []*core.Usage
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select s.user_id, s.instance_id, date(s.collected_at), sum(s.uplink), sum(s.downlink)
+			from usage s
+			inner join instances i on i.id = s.instance_id
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("s.user_id = ?", []any{id}),
+			querybuilder.Condition("s.collected_at >= ?", []any{since.UTC().Format(time.DateTime)}),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(usageColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		qb.Append(" group by s.user_id, s.instance_id, date(s.collected_at) order by date(s.collected_at), s.instance_id;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				usage core.Usage
+				day   string
+			)
+			if err := rows.Scan(&usage.UserID, &usage.InstanceID, &day, &usage.Uplink, &usage.Downlink); err != nil {
+				return err
+			}
+
+			usage.CollectedAt, err = time.Parse(time.DateOnly, day)
+			if err != nil {
+				return err
+			}
+			result = append(result, &usage)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SummarizeUsage(ctx context.Context, id core.GroupID, since time.Time, partial authz.Clause) ([]*core.Usage, error) {
+	var result []*core.Usage
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select s.user_id, sum(s.uplink), sum(s.downlink)
+			from usage s
+			inner join instances i on i.id = s.instance_id
+		`)
+
+		conds := []querybuilder.Cond{
+			querybuilder.Condition("i.group_id = ?", []any{id}),
+			querybuilder.Condition("s.collected_at >= ?", []any{since.UTC().Format(time.DateTime)}),
+		}
+		if !partial.IsNil() {
+			cond, err := partial.Cond(usageColumns)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		qb.Where(conds...)
+		qb.Append(" group by s.user_id order by s.user_id;")
+		query, args := qb.SQL()
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var usage core.Usage
+			if err := rows.Scan(&usage.UserID, &usage.Uplink, &usage.Downlink); err != nil {
+				return err
+			}
+			result = append(result, &usage)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SumUsage(ctx context.Context, id core.UserID, since time.Time) (int64, int, error) {
+	var (
+		traffic int64
+		days    int
+	)
+	if err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select coalesce(sum(s.uplink + s.downlink), 0), count(distinct date(s.collected_at))
+			from usage s
+			where s.user_id = ? and s.collected_at >= ?;
+		`, id, since.UTC().Format(time.DateTime))
+		query, args := qb.SQL()
+		return tx.QueryRowContext(ctx, query, args...).Scan(&traffic, &days)
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	return traffic, days, nil
+}
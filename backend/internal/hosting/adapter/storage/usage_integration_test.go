@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"vpainless/internal/hosting/core"
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func (s *RepositoryTestSuite) Test_Save_List_Usage() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	day := time.Date(1984, 11, 5, 0, 0, 0, 0, time.UTC)
+	groupID := uuid.FromStringOrNil("00000000-0000-0000-0000-111111111111")
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	otherID := core.UserID{UUID: uuid.FromStringOrNil("33000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, ownerID, now)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	other := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, otherID, now)
+	_, err = repo.SaveInstance(ctx, other)
+	s.Require().NoError(err, "should save instance of the other group without any error")
+
+	samples := []*core.Usage{
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(-48 * time.Hour), Uplink: 1, Downlink: 2},
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now.Add(-time.Hour), Uplink: 10, Downlink: 20},
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: now, Uplink: 100, Downlink: 200},
+		{UserID: clientID, InstanceID: instance.ID, CollectedAt: now, Uplink: 5, Downlink: 7},
+		{UserID: otherID, InstanceID: other.ID, CollectedAt: now, Uplink: 3, Downlink: 4},
+	}
+	for _, u := range samples {
+		s.Require().NoError(repo.SaveUsage(ctx, u), "should save usage without any error")
+	}
+
+	since := now.Add(-24 * time.Hour)
+	actual, err := repo.ListDailyUsage(ctx, ownerID, since, authz.Clause{})
+	s.Require().NoError(err, "should list usage without any error")
+	s.Require().Equal([]*core.Usage{
+		{UserID: ownerID, InstanceID: instance.ID, CollectedAt: day, Uplink: 110, Downlink: 220},
+	}, actual, "should sum the usage of the user per day since the given time")
+
+	partial := authz.Clause{Condition: "i.group_id = ?", Values: []any{groupID}}
+	actual, err = repo.ListDailyUsage(ctx, otherID, since, partial)
+	s.Require().NoError(err, "should list usage without any error")
+	s.Require().Empty(actual, "should not list the usage on the instances of other groups")
+
+	actual, err = repo.SummarizeUsage(ctx, core.GroupID{UUID: groupID}, since, authz.Clause{})
+	s.Require().NoError(err, "should summarize usage without any error")
+	s.Require().Equal([]*core.Usage{
+		{UserID: ownerID, Uplink: 110, Downlink: 220},
+		{UserID: clientID, Uplink: 5, Downlink: 7},
+	}, actual, "should sum the usage of the users of the group")
+
+	actual, err = repo.SummarizeUsage(ctx, core.GroupID{UUID: uuid.Must(uuid.NewV4())}, since, authz.Clause{})
+	s.Require().NoError(err, "should summarize usage without any error")
+	s.Require().Empty(actual, "should not sum the usage of the users of another group")
+}
+
+func (s *RepositoryTestSuite) Test_Save_List_UsageCounters() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(1984, 11, 5, 4, 32, 15, 0, time.UTC)
+	ownerID := core.UserID{UUID: uuid.FromStringOrNil("11000000-0000-0000-0000-000000000000")}
+	clientID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	repo := NewRepository(s.db)
+
+	instance := fakeInstance(core.InstanceID{UUID: uuid.Must(uuid.NewV4())}, ownerID, now)
+	_, err := repo.SaveInstance(ctx, instance)
+	s.Require().NoError(err, "should save instance without any error")
+
+	counters, err := repo.ListUsageCounters(ctx, instance.ID)
+	s.Require().NoError(err, "should list usage counters without any error")
+	s.Require().Empty(counters, "should have no counters before the first collection")
+
+	for _, c := range []*core.Usage{
+		{UserID: ownerID, InstanceID: instance.ID, Uplink: 1, Downlink: 2},
+		{UserID: clientID, InstanceID: instance.ID, Uplink: 5, Downlink: 7},
+		{UserID: ownerID, InstanceID: instance.ID, Uplink: 10, Downlink: 20},
+	} {
+		s.Require().NoError(repo.SaveUsageCounter(ctx, c), "should save usage counter without any error")
+	}
+
+	counters, err = repo.ListUsageCounters(ctx, instance.ID)
+	s.Require().NoError(err, "should list usage counters without any error")
+	s.Require().Equal(map[core.UserID]*core.Usage{
+		ownerID:  {UserID: ownerID, InstanceID: instance.ID, Uplink: 10, Downlink: 20},
+		clientID: {UserID: clientID, InstanceID: instance.ID, Uplink: 5, Downlink: 7},
+	}, counters, "should keep the last counters of each user")
+}
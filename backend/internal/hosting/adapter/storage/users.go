@@ -59,3 +59,45 @@ func (r *Repository) SaveUser(ctx context.Context, user *core.User) (*core.User,
 
 	return &result, nil
 }
+
+// GetMember returns the user as a member of the group, with their role in it.
+func (r *Repository) GetMember(ctx context.Context, id core.UserID, group core.GroupID) (*core.User, error) {
+	var result core.User
+
+	err := r.db.InTxDo(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			select user_id, group_id, role from members where user_id = ? and group_id = ?;
+		`, id, group)
+		query, args := qb.SQL()
+		row := tx.QueryRowContext(ctx, query, args...)
+		if err := row.Scan(&result.ID, &result.GroupID, &result.Role); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrNotFound
+			}
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SaveMember saves the user as a member of their group, with their role in it.
+func (r *Repository) SaveMember(ctx context.Context, user *core.User) error {
+	return r.db.InTxDo(ctx, sql.LevelSerializable, func(ctx context.Context, tx *db.Tx) error {
+		qb := querybuilder.New(`
+			insert into members (user_id, group_id, role)
+			values (?, ?, ?)
+			on conflict (user_id, group_id) do update set
+				role = excluded.role;
+		`, user.ID, user.GroupID, user.Role)
+		query, args := qb.SQL()
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
@@ -33,6 +33,36 @@ func (s *RepositoryTestSuite) Test_Get_Create_FindUser() {
 	s.Require().Equal(user, actual, "return user should match")
 }
 
+func (s *RepositoryTestSuite) Test_Get_Save_Member() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	userID := core.UserID{UUID: uuid.FromStringOrNil("22000000-0000-0000-0000-000000000000")}
+	groupID := core.GroupID{UUID: uuid.FromStringOrNil("00000000-0000-0000-0000-222222222222")}
+	repo := NewRepository(s.db)
+
+	_, err := repo.GetMember(ctx, userID, groupID)
+	s.Require().ErrorIs(err, core.ErrNotFound, "should not find the user in a group they do not act in")
+
+	member := &core.User{ID: userID, GroupID: groupID, Role: core.Operator}
+	s.Require().NoError(repo.SaveMember(ctx, member), "should save the member without any error")
+
+	actual, err := repo.GetMember(ctx, userID, groupID)
+	s.Require().NoError(err, "should get the member without any error")
+	s.Require().Equal(member, actual, "member should match")
+
+	member.Role = core.Viewer
+	s.Require().NoError(repo.SaveMember(ctx, member), "should update the role without any error")
+
+	actual, err = repo.GetMember(ctx, userID, groupID)
+	s.Require().NoError(err, "should get the member without any error")
+	s.Require().Equal(core.Viewer, actual.Role, "should have the updated role")
+
+	user, err := repo.GetUser(ctx, userID)
+	s.Require().NoError(err, "should get the user without any error")
+	s.Require().NotEqual(groupID, user.GroupID, "should keep the default group of the user")
+}
+
 func TestUUIDorNull(t *testing.T) {
 	t.Parallel()
 
@@ -5,10 +5,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log/slog"
+	"math"
 	"net"
 	"net/url"
 	"slices"
 	"strings"
+	"time"
 
 	"vpainless/internal/hosting/core"
 	"vpainless/pkg/collect"
@@ -23,12 +25,21 @@ const (
 	tagVpainless        = "vpainless"
 	VpainlessScriptName = "vpainless-script"
 	VpainlessKeyName    = "vpainless-publickey"
+
+	// priceTTL is how long the plan prices are cached for.
+	priceTTL = 24 * time.Hour
 )
 
+type cachedPrice struct {
+	price     core.PlanPrice
+	fetchedAt time.Time
+}
+
 type Vultr struct {
 	client         *vultr.Client
 	sshKeys        collect.Map[Key, []vultr.SSHKeyID]
 	startupScripts collect.Map[Key, vultr.StartupScript]
+	prices         collect.Map[Key, cachedPrice]
 }
 
 func NewVultr(host url.URL) *Vultr {
@@ -36,6 +47,7 @@ func NewVultr(host url.URL) *Vultr {
 		client:         vultr.NewClient(host),
 		sshKeys:        collect.Map[Key, []vultr.SSHKeyID]{},
 		startupScripts: collect.Map[Key, vultr.StartupScript]{},
+		prices:         collect.Map[Key, cachedPrice]{},
 	}
 }
 
@@ -131,8 +143,13 @@ func (v *Vultr) CreateInstance(ctx context.Context, apikey Key, param core.Creat
 		return nil, err
 	}
 
+	region := vultr.RegionID(param.Region)
+	if region == "" {
+		region = vultr.Frankfurt
+	}
+
 	req := vultr.CreateInstanceRequest{
-		Region:   vultr.Frankfurt,
+		Region:   region,
 		Plan:     vultr.BasicPlan,
 		OS:       vultr.Debian12,
 		Label:    param.Label,
@@ -148,11 +165,65 @@ func (v *Vultr) CreateInstance(ctx context.Context, apikey Key, param core.Creat
 	}
 
 	return &core.RemoteInstance{
-		ID: core.InstanceID{UUID: instance.ID},
-		IP: net.ParseIP(instance.MainIP),
+		ID:     core.InstanceID{UUID: instance.ID},
+		IP:     net.ParseIP(instance.MainIP),
+		Region: string(instance.Region),
+		Price:  v.knownPrice(ctx, apikey),
 	}, nil
 }
 
+// knownPrice returns the last known price of the plan, to record the cost of the
+// instances. It does not fail the creation of instances when vultr can not be
+// asked for the price; the instances are then recorded as free.
+func (v *Vultr) knownPrice(ctx context.Context, apikey Key) core.PlanPrice {
+	if cached, ok := v.prices.Load(apikey); ok {
+		return cached.price
+	}
+
+	price, err := v.PlanPrice(ctx, apikey)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to fetch plan price", "plan", vultr.BasicPlan, "error", err)
+		return core.PlanPrice{Plan: string(vultr.BasicPlan)}
+	}
+	return *price
+}
+
+// PlanPrice returns the price of the plan instances are created with. Prices are
+// fetched from vultr, and cached per account for a day. The cached price is kept
+// past the day if vultr can not be asked for it again.
+func (v *Vultr) PlanPrice(ctx context.Context, apikey Key) (*core.PlanPrice, error) {
+	cached, ok := v.prices.Load(apikey)
+	if ok && time.Since(cached.fetchedAt) < priceTTL {
+		return &cached.price, nil
+	}
+
+	ctx = v.client.WithAPIKey(ctx, apikey)
+	resp, err := v.client.ListPlans(ctx)
+	if err != nil && ok {
+		slog.WarnContext(ctx, "unable to fetch plan price, using the last known one", "plan", cached.price.Plan, "error", err)
+		return &cached.price, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(resp.Plans, func(p vultr.Plan) bool {
+		return p.ID == vultr.BasicPlan
+	})
+	if index < 0 {
+		return nil, fmt.Errorf("plan %s is not offered by vultr", vultr.BasicPlan)
+	}
+
+	price := core.PlanPrice{
+		Plan:         string(vultr.BasicPlan),
+		MonthlyCents: int64(math.Round(resp.Plans[index].MonthlyCost * 100)),
+	}
+	v.prices.Store(apikey, cachedPrice{price: price, fetchedAt: time.Now()})
+	slog.InfoContext(ctx, "fetched plan price", "plan", price.Plan, "monthly_cents", price.MonthlyCents)
+
+	return &price, nil
+}
+
 // GetInstance returns an information about an instance from vultr.
 // The only reliable information are instance's ip and creation date.
 // Everything else should be retrived from somewhere else.
@@ -164,8 +235,9 @@ func (v *Vultr) GetInstance(ctx context.Context, apikey Key, id core.InstanceID)
 	}
 
 	return &core.RemoteInstance{
-		ID: core.InstanceID{UUID: instance.ID},
-		IP: net.ParseIP(instance.MainIP),
+		ID:     core.InstanceID{UUID: instance.ID},
+		IP:     net.ParseIP(instance.MainIP),
+		Region: string(instance.Region),
 	}, nil
 }
 
@@ -175,6 +247,19 @@ func (v *Vultr) DeleteInstance(ctx context.Context, apikey Key, id core.Instance
 	return v.client.DeleteInstance(ctx, vultr.InstanceID(id.UUID))
 }
 
+// Regions returns the regions instances are created in, when not chosen by the group.
+// They are close to the users, and not known to be blocked as a whole.
+func (v *Vultr) Regions() []string {
+	return []string{
+		string(vultr.Frankfurt),
+		string(vultr.Amsterdam),
+		string(vultr.Warsaw),
+		string(vultr.Stockholm),
+		string(vultr.Paris),
+		string(vultr.London),
+	}
+}
+
 // loadSSHKeyID adds the ssh key if not already present to vultr account, otherwise, it returns it's id
 func (v *Vultr) loadSSHKeyID(ctx context.Context, apikey Key, param core.CreateInstanceParam) (vultr.SSHKeyID, error) {
 	keyIDs, ok := v.sshKeys.Load(apikey)
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type (
+	InstanceRequestID     struct{ uuid.UUID }
+	InstanceRequestStatus string
+)
+
+const (
+	RequestPending  InstanceRequestStatus = "pending"
+	RequestApproved InstanceRequestStatus = "approved"
+	RequestRejected InstanceRequestStatus = "rejected"
+
+	// verbApprove is the action of provisioning an instance for a user. The instance
+	// policy allows it for the admins of the group, and for the users themselves
+	// unless their group requires approval.
+	verbApprove authz.Verb = "approve"
+)
+
+var ErrApprovalPending = errors.New("instance is pending approval")
+
+// ApprovalPendingError is returned when an instance is requested instead of created.
+// It wraps ErrApprovalPending.
+type ApprovalPendingError struct {
+	Request *InstanceRequest
+}
+
+func (e *ApprovalPendingError) Error() string {
+	return fmt.Sprintf("%s, request %s", ErrApprovalPending, e.Request.ID)
+}
+
+func (e *ApprovalPendingError) Unwrap() error {
+	return ErrApprovalPending
+}
+
+// InstanceRequest is an instance a user asked for in a group that requires approval.
+// The instance is only created once an admin of the group approves it.
+type InstanceRequest struct {
+	ID        InstanceRequestID
+	UserID    UserID
+	GroupID   GroupID
+	Status    InstanceRequestStatus
+	CreatedAt time.Time
+	// DecidedAt and DecidedBy are set once the request is approved or rejected.
+	DecidedAt *time.Time
+	DecidedBy *UserID
+	// InstanceID is the instance created on approval.
+	InstanceID *InstanceID
+}
+
+// ListInstanceRequests returns the pending instance requests of a group, oldest first.
+func (s *Service) ListInstanceRequests(ctx context.Context, id GroupID) ([]*InstanceRequest, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, verbApprove, authz.ResourceFunc(func() (string, any) {
+		return ResourceInstances, map[string]any{
+			"group_id": id,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListPendingRequests(ctx, id)
+}
+
+// ApproveInstanceRequest creates the instance of a pending request.
+func (s *Service) ApproveInstanceRequest(ctx context.Context, id InstanceRequestID) (*Instance, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var (
+		result *Instance
+		group  *Group
+		param  CreateInstanceParam
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		request, err := s.decidableRequest(ctx, principal, id)
+		if err != nil {
+			return err
+		}
+
+		group, err = s.repo.GetGroup(ctx, request.GroupID)
+		if err != nil {
+			return errors.Join(ErrGroups, err)
+		}
+
+		if _, err := s.userInstance(ctx, request.UserID, request.GroupID); !errors.Is(err, ErrNotFound) {
+			if err != nil {
+				return err
+			}
+			return errors.Join(ErrBadRequest, errors.New("user already has an instance"))
+		}
+
+		slog.InfoContext(ctx, "core: approving instance request...", "request_id", id, "user_id", request.UserID)
+		result, param, err = s.provisionInstance(ctx, group, request.UserID)
+		if err != nil {
+			return err
+		}
+
+		request.decide(RequestApproved, principal)
+		request.InstanceID = &result.ID
+		if err := s.repo.SaveInstanceRequest(ctx, request); err != nil {
+			return errors.Join(err, s.vps.DeleteInstance(ctx, group.Host.APIKey, result.RemoteID))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	go s.SetupInstance(group.Host.APIKey, result, param)
+	return result, nil
+}
+
+// RejectInstanceRequest rejects a pending request. The user can request again.
+func (s *Service) RejectInstanceRequest(ctx context.Context, id InstanceRequestID) (*InstanceRequest, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var result *InstanceRequest
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		request, err := s.decidableRequest(ctx, principal, id)
+		if err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "core: rejecting instance request...", "request_id", id, "user_id", request.UserID)
+		request.decide(RequestRejected, principal)
+		if err := s.repo.SaveInstanceRequest(ctx, request); err != nil {
+			return err
+		}
+
+		result = request
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// decidableRequest returns a pending request the principal can approve or reject.
+func (s *Service) decidableRequest(ctx context.Context, principal authz.Principal, id InstanceRequestID) (*InstanceRequest, error) {
+	request, err := s.repo.GetInstanceRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, verbApprove, authz.ResourceFunc(func() (string, any) {
+		return ResourceInstances, map[string]any{
+			"user_id":          request.UserID,
+			"group_id":         request.GroupID,
+			"require_approval": true,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	if request.Status != RequestPending {
+		return nil, errors.Join(ErrBadRequest, fmt.Errorf("request is already %s", request.Status))
+	}
+	return request, nil
+}
+
+func (r *InstanceRequest) decide(status InstanceRequestStatus, principal authz.Principal) {
+	now := time.Now()
+	r.Status = status
+	r.DecidedAt = &now
+	r.DecidedBy = &UserID{principal.ID}
+}
+
+// canApprove reports whether the principal can provision an instance for the user
+// without the approval of an admin.
+func (s *Service) canApprove(ctx context.Context, principal authz.Principal, group *Group, userID UserID) bool {
+	policy, err := s.enforcer.Can(ctx, principal, verbApprove, authz.ResourceFunc(func() (string, any) {
+		return ResourceInstances, map[string]any{
+			"user_id":          userID,
+			"group_id":         group.ID,
+			"require_approval": group.Settings.RequireApproval,
+		}
+	}))
+	return err == nil && policy.Allow
+}
+
+// requestInstance returns the pending request of the user, or creates one.
+func (s *Service) requestInstance(ctx context.Context, group *Group, userID UserID) (*InstanceRequest, error) {
+	request, err := s.repo.FindPendingRequest(ctx, userID, group.ID)
+	if !errors.Is(err, ErrNotFound) {
+		return request, err
+	}
+
+	request = &InstanceRequest{
+		ID:        InstanceRequestID{uuid.Must(uuid.NewV4())},
+		UserID:    userID,
+		GroupID:   group.ID,
+		Status:    RequestPending,
+		CreatedAt: time.Now(),
+	}
+
+	slog.InfoContext(ctx, "core: requesting instance approval...", "user_id", userID, "request_id", request.ID)
+	if err := s.repo.SaveInstanceRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
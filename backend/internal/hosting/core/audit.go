@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/middleware"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type (
+	AuditEntryID struct{ uuid.UUID }
+	AuditAction  string
+)
+
+const (
+	ResourceAudit = "audit"
+
+	AuditUserCreate     AuditAction = "user.create"
+	AuditUserUpdate     AuditAction = "user.update"
+	AuditUserUnlock     AuditAction = "user.unlock"
+	AuditGroupCreate    AuditAction = "group.create"
+	AuditMemberAdd      AuditAction = "member.add"
+	AuditMemberUpdate   AuditAction = "member.update"
+	AuditMemberRemove   AuditAction = "member.remove"
+	AuditInstanceCreate AuditAction = "instance.create"
+	AuditInstanceDelete AuditAction = "instance.delete"
+	AuditInstanceRenew  AuditAction = "instance.renew"
+	AuditLoginFailure   AuditAction = "login.failure"
+	AuditLoginLockout   AuditAction = "login.lockout"
+
+	AuditTwoFactorEnable  AuditAction = "two_factor.enable"
+	AuditTwoFactorDisable AuditAction = "two_factor.disable"
+
+	// DefaultAuditLimit is the number of the latest entries returned, unless asked otherwise.
+	DefaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditChange is the value of a field before and after an action. Old is nil
+// for created fields, and New for removed ones.
+type AuditChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// AuditEntry records who changed what. Entries are append-only; they are never
+// updated or deleted.
+type AuditEntry struct {
+	ID        AuditEntryID
+	CreatedAt time.Time
+	// RequestID is the id of the api request the action is taken in.
+	RequestID uuid.UUID
+	// PrincipalID is nil for anonymous actions, like signing up or failing to log in.
+	PrincipalID *UserID
+	// GroupID is the group the target belongs to. Entries are visible to its admins.
+	GroupID GroupID
+	Action  AuditAction
+	// Target is the path of the changed resource, e.g. instances/<id>.
+	Target string
+	Diff   map[string]AuditChange
+}
+
+// AuditFilter narrows down the audit entries. Zero fields do not filter.
+type AuditFilter struct {
+	Action      AuditAction
+	PrincipalID UserID
+	Target      string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+}
+
+// ListAuditEntries returns the audit entries visible to the principal, newest first.
+func (s *Service) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.Resource{Group: ResourceAudit})
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditLimit)
+
+	return s.repo.ListAuditEntries(ctx, filter, policy.Partial)
+}
+
+// RecordAudit appends an entry to the audit log, on behalf of the other modules.
+// The principal and the request id are taken from the context.
+func (s *Service) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		return s.repo.SaveAuditEntry(ctx, newAuditEntry(ctx, entry))
+	})
+}
+
+// audit appends an entry to the audit log for an action of the principal on
+// their group. It should be called in the transaction of the action.
+func (s *Service) audit(ctx context.Context, action AuditAction, target string, diff map[string]AuditChange) error {
+	entry := &AuditEntry{Action: action, Target: target, Diff: diff}
+	if principal, err := authz.GetPrincipal(ctx); err == nil {
+		entry.GroupID = GroupID{principal.GroupID}
+	}
+
+	return s.repo.SaveAuditEntry(ctx, newAuditEntry(ctx, entry))
+}
+
+func newAuditEntry(ctx context.Context, entry *AuditEntry) *AuditEntry {
+	entry.ID = AuditEntryID{uuid.Must(uuid.NewV4())}
+	entry.CreatedAt = time.Now()
+	entry.RequestID, _ = middleware.GetRequestID(ctx)
+	if principal, err := authz.GetPrincipal(ctx); err == nil {
+		entry.PrincipalID = &UserID{principal.ID}
+	}
+	return entry
+}
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/reality"
+	"vpainless/pkg/remote"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	ResourceClients = "clients"
+	xrayConfigPath  = "/usr/local/etc/xray/config.json"
+)
+
+// ClientID is the vless client id, used by the client to connect to an instance.
+type ClientID struct{ uuid.UUID }
+
+// InstanceClient is a user connecting to a shared instance. Each client has its
+// own id on the instance, so it can be revoked without affecting others.
+type InstanceClient struct {
+	ID         ClientID
+	InstanceID InstanceID
+	UserID     UserID
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// ConnectionString returns the connection string of the client given the instance
+// it belongs to. It is empty if the instance is not set up yet.
+func (c *InstanceClient) ConnectionString(instance *Instance) string {
+	if instance.Config.Reality.IsNil() {
+		return ""
+	}
+
+	return instance.Config.Reality.ForClient(c.ID).ConnectionString(instance.IP)
+}
+
+func (s *Service) ListClients(ctx context.Context, id InstanceID) ([]*InstanceClient, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.ResourceFunc(func() (string, any) {
+		return ResourceClients, map[string]any{
+			"instance_id": id,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListClients(ctx, id, policy.Partial)
+}
+
+// RevokeClient revokes the access of a user to a shared instance. The instance is
+// reconfigured without the client once it is committed, or deleted if it was the
+// last client.
+func (s *Service) RevokeClient(ctx context.Context, id InstanceID, userID UserID) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	var (
+		instance    *Instance
+		reconfigure bool
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		instance, err = s.repo.GetInstance(ctx, id, authz.Clause{})
+		if err != nil {
+			return err
+		}
+
+		policy, err := s.enforcer.Can(ctx, principal, authz.Delete, authz.ResourceFunc(func() (string, any) {
+			return ResourceClients, map[string]any{
+				"instance_id": id,
+				"user_id":     userID,
+				"group_id":    instance.GroupID,
+			}
+		}))
+		if err != nil || !policy.Allow {
+			return ErrUnauthorized
+		}
+
+		client, err := s.repo.FindClient(ctx, userID, instance.GroupID)
+		if err != nil {
+			return err
+		}
+		if client.InstanceID != id {
+			return ErrNotFound
+		}
+
+		reconfigure, err = s.revokeClient(ctx, instance, client)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if !reconfigure {
+		return nil
+	}
+	return s.reconfigureInstance(ctx, instance)
+}
+
+// revokeClient should be called in a transaction, after authorization. It reports
+// whether the instance has other clients, and should be reconfigured without the
+// client once the transaction is committed.
+func (s *Service) revokeClient(ctx context.Context, instance *Instance, client *InstanceClient) (bool, error) {
+	now := time.Now()
+	client.RevokedAt = &now
+	if _, err := s.repo.SaveClient(ctx, client); err != nil {
+		return false, err
+	}
+
+	clients, err := s.repo.ListClients(ctx, instance.ID, authz.Clause{})
+	if err != nil {
+		return false, err
+	}
+
+	if len(clients) > 0 {
+		slog.InfoContext(ctx, "core: revoking client...", "instance_id", instance.ID, "client_id", client.ID)
+		return true, nil
+	}
+
+	slog.InfoContext(ctx, "core: last client revoked, deleting instance...", "instance_id", instance.ID)
+	if err := s.repo.DeleteInstance(ctx, instance.ID, authz.Clause{}); err != nil {
+		return false, err
+	}
+
+	group, err := s.repo.GetGroup(ctx, instance.GroupID)
+	if err != nil {
+		return false, errors.Join(ErrGroups, err)
+	}
+
+	return false, s.deleteRemoteInstance(ctx, group.Host.APIKey, instance)
+}
+
+// joinSharedInstance adds the user as a client to a shared instance of the group
+// that has free capacity. It returns ErrNotFound if there is no such instance. It
+// should be called in a transaction, and the instance reconfigured with the client
+// once it is committed.
+func (s *Service) joinSharedInstance(ctx context.Context, group *Group, userID UserID) (*Instance, *InstanceClient, error) {
+	instance, err := s.repo.FindSharedInstance(ctx, group.ID, group.Settings.MaxClientsPerInstance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &InstanceClient{
+		ID:         ClientID{uuid.Must(uuid.NewV4())},
+		InstanceID: instance.ID,
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := s.repo.SaveClient(ctx, client); err != nil {
+		return nil, nil, err
+	}
+
+	slog.InfoContext(ctx, "core: adding client to shared instance...", "instance_id", instance.ID, "client_id", client.ID)
+	instance.Config.ConnectionString = client.ConnectionString(instance)
+	return instance, client, nil
+}
+
+// reconfigureInstance renders the config of a running instance again and
+// reloads xray, without recreating the instance.
+func (s *Service) reconfigureInstance(ctx context.Context, instance *Instance) error {
+	config, err := s.renderConfig(ctx, instance, instance.Config.Reality)
+	if err != nil {
+		return fmt.Errorf("error rendering xray config: %w", err)
+	}
+
+	conn, err := remote.Dial(instance.IP, instance.PrivateKey, "root")
+	if err != nil {
+		return fmt.Errorf("error connecting to instance %s: %w", instance.ID, err)
+	}
+	defer conn.Close()
+
+	if err := remote.UploadFile(conn, xrayConfigPath, bytes.NewBufferString(config)); err != nil {
+		return fmt.Errorf("error uploading xray config: %w", err)
+	}
+
+	if _, err := remote.Execute(conn, "systemctl reload-or-restart xray"); err != nil {
+		return fmt.Errorf("error reloading xray: %w", err)
+	}
+
+	return nil
+}
+
+// personalize sets the connection string of shared instances to the one
+// that belongs to the principal. Others are not supposed to see it.
+func (s *Service) personalize(ctx context.Context, principal authz.Principal, instance *Instance) error {
+	if !instance.Shared {
+		return nil
+	}
+
+	instance.Config.ConnectionString = ""
+	client, err := s.repo.FindClient(ctx, UserID{principal.ID}, instance.GroupID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if client.InstanceID == instance.ID {
+		instance.Config.ConnectionString = client.ConnectionString(instance)
+	}
+	return nil
+}
+
+type xrayClient struct {
+	ID    string `json:"id"`
+	Flow  string `json:"flow"`
+	Email string `json:"email"`
+}
+
+// applyClients renders the reality config with the clients of the vless inbound,
+// along with the client of the health checks. Clients are tagged by their user id
+// as email, so they can be told apart in logs.
+func applyClients(realityConfig XrayTemplate, clients []*InstanceClient) (string, error) {
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(realityConfig.String()), &parsed); err != nil {
+		return "", fmt.Errorf("error parsing xray config: %w", err)
+	}
+
+	inbounds, _ := parsed["inbounds"].([]any)
+	if len(inbounds) == 0 {
+		return "", fmt.Errorf("xray config has no inbounds")
+	}
+
+	inbound, _ := inbounds[0].(map[string]any)
+	settings, ok := inbound["settings"].(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("xray config inbound has no settings")
+	}
+
+	result := make([]any, 0, len(clients)+1)
+	for _, c := range clients {
+		result = append(result, xrayClient{
+			ID:    c.ID.String(),
+			Flow:  reality.FlowVision,
+			Email: c.UserID.String(),
+		})
+	}
+	// The traffic of the health checks is not accounted for, as its email is
+	// not a user id.
+	result = append(result, xrayClient{
+		ID:    realityConfig.HealthCheckClientID().String(),
+		Flow:  reality.FlowVision,
+		Email: healthCheckEmail,
+	})
+	settings["clients"] = result
+
+	b, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling xray config: %w", err)
+	}
+
+	return string(b), nil
+}
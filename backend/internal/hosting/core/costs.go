@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const (
+	ResourceCosts = "costs"
+
+	// billingHoursPerMonth is the number of hours an instance is billed for at most
+	// in a month. The hourly rate of a plan is its monthly cost divided by it.
+	billingHoursPerMonth = 672
+)
+
+var ErrBudgetExceeded = errors.New("monthly budget of the group is exceeded")
+
+// PlanPrice is the price of the provider plan an instance is created with.
+type PlanPrice struct {
+	Plan         string
+	MonthlyCents int64
+}
+
+// cost returns the cost of running an instance of the plan for the given duration.
+// Instances are billed for every started hour, up to the monthly cost.
+func (p PlanPrice) cost(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+
+	hours := math.Ceil(d.Hours())
+	cost := int64(math.Round(hours * float64(p.MonthlyCents) / billingHoursPerMonth))
+	return min(cost, p.MonthlyCents)
+}
+
+// InstanceCost is the billing record of an instance, from its creation to its deletion.
+type InstanceCost struct {
+	InstanceID InstanceID
+	UserID     UserID
+	Price      PlanPrice
+	CreatedAt  time.Time
+	// DeletedAt is nil while the instance is running.
+	DeletedAt *time.Time
+	// RetireAt is set on the replaced instances whose server keeps running, and
+	// being billed, through the grace period of their renewal.
+	RetireAt *time.Time
+}
+
+// accrued returns the cost of the instance between start and end.
+func (c *InstanceCost) accrued(start, end time.Time) int64 {
+	if c.CreatedAt.After(start) {
+		start = c.CreatedAt
+	}
+	if c.DeletedAt != nil && c.DeletedAt.Before(end) {
+		end = *c.DeletedAt
+	}
+
+	return c.Price.cost(end.Sub(start))
+}
+
+// UserCost is the spend of the instances owned by a user in a month.
+type UserCost struct {
+	UserID    UserID
+	Instances int
+	Cents     int64
+}
+
+// CostReport is the spend of a group in a month, up to now for the current month.
+type CostReport struct {
+	GroupID     GroupID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	BudgetCents int64
+	TotalCents  int64
+	Users       []*UserCost
+}
+
+// GetGroupCosts returns the spend of a group in the month of the given time.
+func (s *Service) GetGroupCosts(ctx context.Context, id GroupID, month time.Time) (*CostReport, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceCosts, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	var report *CostReport
+	if err := s.repo.Transact(ctx, sql.LevelReadCommitted, func(ctx context.Context) error {
+		group, err := s.repo.GetGroup(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		start, end := quotaPeriod(month)
+		costs, err := s.repo.ListInstanceCosts(ctx, id, start, end)
+		if err != nil {
+			return err
+		}
+
+		report = &CostReport{
+			GroupID:     id,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			BudgetCents: group.Settings.MonthlyBudget,
+		}
+
+		// The current month is reported up to now.
+		until := end
+		if now := time.Now(); now.Before(end) {
+			until = now
+		}
+
+		users := map[UserID]*UserCost{}
+		for _, c := range costs {
+			user, ok := users[c.UserID]
+			if !ok {
+				user = &UserCost{UserID: c.UserID}
+				users[c.UserID] = user
+				report.Users = append(report.Users, user)
+			}
+
+			cost := c.accrued(start, until)
+			user.Instances++
+			user.Cents += cost
+			report.TotalCents += cost
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// checkBudget returns ErrBudgetExceeded if the group can not afford another instance
+// until the end of the month, along with the instances it runs. It should be called
+// before the instance is created on the provider. The price of the plan is only asked
+// for when the group has a budget, and falls back to the price of its last instance.
+func (s *Service) checkBudget(ctx context.Context, group *Group) error {
+	budget := group.Settings.MonthlyBudget
+	if budget == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	start, end := quotaPeriod(now)
+	costs, err := s.repo.ListInstanceCosts(ctx, group.ID, start, end)
+	if err != nil {
+		return err
+	}
+
+	price, err := s.vps.PlanPrice(ctx, group.Host.APIKey)
+	if err != nil {
+		price = lastPrice(costs)
+		if price == nil {
+			return fmt.Errorf("error getting plan price: %w", err)
+		}
+		slog.WarnContext(ctx, "core: using the price of the last instance", "group_id", group.ID, "monthly_cents", price.MonthlyCents, "error", err)
+	}
+
+	// Running instances are projected to the end of the month, and the retired
+	// ones to the end of their grace period.
+	projected := price.cost(end.Sub(now))
+	for _, c := range costs {
+		cost := *c
+		if c.RetireAt != nil && c.RetireAt.After(now) {
+			cost.DeletedAt = c.RetireAt
+		}
+		projected += cost.accrued(start, end)
+	}
+
+	if projected > budget {
+		return fmt.Errorf("%w: projected spend is %d cents, budget is %d cents", ErrBudgetExceeded, projected, budget)
+	}
+	return nil
+}
+
+// lastPrice returns the price of the last created instance with a known price.
+// Costs are ordered by their creation.
+func lastPrice(costs []*InstanceCost) *PlanPrice {
+	for i := len(costs) - 1; i >= 0; i-- {
+		if costs[i].Price.MonthlyCents > 0 {
+			return &costs[i].Price
+		}
+	}
+	return nil
+}
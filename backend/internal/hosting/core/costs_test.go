@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCostRepository returns the costs it is given. The rest of the repository is
+// not used by the budget, and panics if called.
+type fakeCostRepository struct {
+	Repository
+
+	costs []*InstanceCost
+}
+
+func (r *fakeCostRepository) ListInstanceCosts(context.Context, GroupID, time.Time, time.Time) ([]*InstanceCost, error) {
+	return r.costs, nil
+}
+
+// fakePriceProvider returns the price it is given, or fails without one.
+type fakePriceProvider struct {
+	VPSProvider
+
+	price *PlanPrice
+}
+
+func (p *fakePriceProvider) PlanPrice(context.Context, string) (*PlanPrice, error) {
+	if p.price == nil {
+		return nil, errors.New("plans are not available")
+	}
+	return p.price, nil
+}
+
+func TestCheckBudget(t *testing.T) {
+	ctx := context.Background()
+	price := PlanPrice{Plan: "vc2-1c-1gb", MonthlyCents: 672 * 100}
+	now := time.Now()
+	start, end := quotaPeriod(now)
+
+	deletedAt := now.Add(-time.Hour)
+	retireAt := now.Add(24 * time.Hour)
+	retired := &InstanceCost{Price: price, CreatedAt: deletedAt.Add(-time.Hour), DeletedAt: &deletedAt, RetireAt: &retireAt}
+	// The retired instance is billed until its retirement, and a new instance until
+	// the end of the month.
+	expected := price.cost(end.Sub(now)) + (&InstanceCost{Price: price, CreatedAt: retired.CreatedAt, DeletedAt: &retireAt}).accrued(start, end)
+
+	repo := &fakeCostRepository{costs: []*InstanceCost{retired}}
+	vps := &fakePriceProvider{price: &price}
+	s := &Service{repo: repo, vps: vps}
+
+	group := &Group{Settings: GroupSettings{MonthlyBudget: expected}}
+	require.NoError(t, s.checkBudget(ctx, group), "should afford a new instance along with the retired one")
+
+	group.Settings.MonthlyBudget = expected - 100
+	require.ErrorIs(t, s.checkBudget(ctx, group), ErrBudgetExceeded, "should count the retired instance until its retirement")
+
+	// The price of the last instance is used when the provider does not tell it.
+	vps.price = nil
+	require.ErrorIs(t, s.checkBudget(ctx, group), ErrBudgetExceeded, "should fall back to the price of the last instance")
+
+	repo.costs = nil
+	err := s.checkBudget(ctx, group)
+	require.Error(t, err, "should fail without any known price")
+	require.NotErrorIs(t, err, ErrBudgetExceeded)
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// RotateCredentials replaces the credentials of an instance in place, e.g. when its
+// connection string is leaked. The instance gets a new client id, key pair and short id.
+// On shared instances, only the client id of the principal is replaced if they are a client,
+// which is enough to lock out the leaked connection string without affecting the others.
+//
+// The new credentials are committed first, and xray is restarted with them afterwards,
+// so the write lock is not held over the connection to the instance. If the restart
+// fails, the old credentials are restored, which xray still runs with.
+func (s *Service) RotateCredentials(ctx context.Context, id InstanceID) (*Instance, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.ResourceID(ResourceInstances, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	var (
+		instance *Instance
+		previous XrayConfig
+		rotated  *InstanceClient
+		old      *InstanceClient
+	)
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		instance, err = s.repo.GetInstance(ctx, id, policy.Partial)
+		if err != nil {
+			return err
+		}
+
+		if instance.Status != StatusOK {
+			return errors.Join(ErrBadRequest, fmt.Errorf("instance is %s", instance.Status))
+		}
+
+		if instance.Shared {
+			client, err := s.repo.FindClient(ctx, UserID{principal.ID}, instance.GroupID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err == nil && client.InstanceID == instance.ID {
+				old = client
+				rotated, err = s.rotateClient(ctx, instance, client)
+				return err
+			}
+		}
+
+		destination := instance.Config.Reality.FakeURL
+		if destination == "" {
+			destination = fakeURL
+		}
+
+		reality, err := NewRealityConfig(destination)
+		if err != nil {
+			return fmt.Errorf("error creating reality config: %w", err)
+		}
+
+		slog.InfoContext(ctx, "core: rotating instance credentials...", "instance_id", instance.ID)
+		previous = instance.Config
+		instance.Config.Reality = reality
+		instance.Config.ConnectionString = reality.ConnectionString(instance.IP)
+		if instance.Shared {
+			instance.Config.ConnectionString = ""
+		}
+
+		_, err = s.repo.SaveInstance(ctx, instance)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.reconfigureInstance(ctx, instance); err != nil {
+		return nil, errors.Join(err, s.restoreCredentials(ctx, instance, previous, old, rotated))
+	}
+
+	if rotated != nil {
+		instance.Config.ConnectionString = rotated.ConnectionString(instance)
+		return instance, nil
+	}
+
+	if err := s.personalize(ctx, principal, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// rotateClient replaces a client of a shared instance with a new one. It should be
+// called in a transaction, and the instance reconfigured with the new client once
+// it is committed.
+func (s *Service) rotateClient(ctx context.Context, instance *Instance, old *InstanceClient) (*InstanceClient, error) {
+	now := time.Now()
+	old.RevokedAt = &now
+	if _, err := s.repo.SaveClient(ctx, old); err != nil {
+		return nil, err
+	}
+
+	client := &InstanceClient{
+		ID:         ClientID{uuid.Must(uuid.NewV4())},
+		InstanceID: instance.ID,
+		UserID:     old.UserID,
+		CreatedAt:  now,
+	}
+	if _, err := s.repo.SaveClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "core: rotating instance client...", "instance_id", instance.ID, "client_id", client.ID)
+	return client, nil
+}
+
+// restoreCredentials puts back the credentials xray still runs with, after it
+// failed to restart with the rotated ones; the old client of a shared instance, or
+// the old config otherwise. The instance is marked as degraded, as xray may have
+// been left with either, so the health checks find out which one it serves.
+func (s *Service) restoreCredentials(ctx context.Context, instance *Instance, previous XrayConfig, old, rotated *InstanceClient) error {
+	slog.WarnContext(ctx, "core: restoring instance credentials...", "instance_id", instance.ID)
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		current, err := s.repo.GetInstance(ctx, instance.ID, authz.Clause{})
+		if err != nil {
+			return err
+		}
+
+		if rotated != nil {
+			now := time.Now()
+			rotated.RevokedAt = &now
+			if _, err := s.repo.SaveClient(ctx, rotated); err != nil {
+				return err
+			}
+
+			old.RevokedAt = nil
+			if _, err := s.repo.SaveClient(ctx, old); err != nil {
+				return err
+			}
+		} else if current.Config.Reality == instance.Config.Reality {
+			// The credentials are only restored if they were not rotated again since.
+			current.Config = previous
+		}
+
+		if current.Status == StatusOK {
+			current.Status = StatusDegraded
+		}
+		_, err = s.repo.SaveInstance(ctx, current)
+		return err
+	})
+}
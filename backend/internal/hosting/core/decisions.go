@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const (
+	ResourceDecisions = "decisions"
+
+	// verbExplain is the action of replaying a policy decision on a user.
+	verbExplain authz.Verb = "explain"
+)
+
+// DecisionReplay is a policy check to replay on behalf of a user.
+type DecisionReplay struct {
+	UserID        UserID
+	Action        authz.Verb
+	ResourceGroup string
+	Resource      map[string]any
+}
+
+// LogDecisions logs the policy decisions of hosting to the logger, and enables it
+// for the groups that log their decisions.
+func (s *Service) LogDecisions(ctx context.Context, logger *authz.DecisionLogger) error {
+	groups, err := s.repo.ListDecisionLogGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range groups {
+		logger.Enable(id.UUID, true)
+	}
+
+	s.decisions = logger
+	s.enforcer.LogDecisions(logger)
+	return nil
+}
+
+// ExplainDecision replays a hosting policy decision on the user, as a member of the
+// group the principal acts in, and explains how it is made. Only the admins of the
+// group can replay the decisions of its members.
+func (s *Service) ExplainDecision(ctx context.Context, replay DecisionReplay) (*authz.Explanation, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if replay.Action == "" || replay.ResourceGroup == "" {
+		return nil, errors.Join(ErrBadRequest, errors.New("action and resource group are required"))
+	}
+
+	user, err := s.repo.GetMember(ctx, replay.UserID, GroupID{principal.GroupID})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, verbExplain, authz.ResourceFunc(func() (string, any) {
+		return ResourceDecisions, map[string]any{
+			"group_id": user.GroupID,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	explanation, err := s.enforcer.Explain(ctx, authz.Principal{
+		ID:      user.ID.UUID,
+		GroupID: user.GroupID.UUID,
+		Role:    authz.Role(user.Role),
+	}, replay.Action, authz.Resource{Group: replay.ResourceGroup, Value: replay.Resource})
+	if errors.Is(err, authz.ErrUndefinedPolicy) {
+		return nil, errors.Join(ErrBadRequest, err)
+	}
+	return explanation, err
+}
@@ -3,9 +3,14 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"slices"
+	"time"
+
+	"vpainless/internal/pkg/authz"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -42,6 +47,156 @@ type Group struct {
 	DefaultSSHKey        SSHKeyPair
 	DefaultXrayTemplate  XrayTemplateID
 	XrayTemplates        map[XrayTemplateID]XrayTemplate
+	Settings             GroupSettings
+}
+
+const ResourceGroups = "groups"
+
+// DefaultMaxClientsPerInstance is the capacity of shared instances,
+// unless changed by the group admins.
+const DefaultMaxClientsPerInstance = 5
+
+// GroupSettings are the hosting preferences of a group, managed by its admins.
+type GroupSettings struct {
+	// SharedInstances lets the users of the group share instances instead of
+	// having one each. Each user connects with their own vless client.
+	SharedInstances       bool
+	MaxClientsPerInstance int
+	// SNIPool is the candidate domains for the reality destination of the
+	// instances. One that is suitable from the instance is picked at random.
+	SNIPool []string
+	// AutoRenew replaces the blocked instances with new ones in a different region.
+	AutoRenew         bool
+	MaxRenewalsPerDay int
+	// Regions are the provider regions replacements are created in. Defaults
+	// to the regions of the provider.
+	Regions []string
+	// RotationInterval replaces the instances once they are older than it, to stay
+	// ahead of blocking. Zero disables the rotation.
+	RotationInterval time.Duration
+	// TTL deletes the instances once they are older than it. Zero disables the expiry.
+	TTL time.Duration
+	// MinProbeReporters is the number of distinct probe agents whose reports are
+	// needed to block, or unblock, an instance.
+	MinProbeReporters int
+	// RenewalGracePeriod keeps the replaced servers running after a renewal, so
+	// the users can switch to the replacement. Not applied to blocked instances.
+	RenewalGracePeriod time.Duration
+	// Quota is the monthly quota of each user of the group, unless overridden for the user.
+	Quota Quota
+	// MonthlyBudget caps the spend of the group on the provider, in cents.
+	// New instances are refused once they would exceed it. Zero is unlimited.
+	MonthlyBudget int64
+	// UserRateLimit limits the instance creations, deletions and renewals of each
+	// user, unless overridden for the user. GroupRateLimit limits them for the group.
+	UserRateLimit  RateLimit
+	GroupRateLimit RateLimit
+	// RequireApproval turns the instance creations of the clients into requests,
+	// which are only provisioned once an admin of the group approves them.
+	RequireApproval bool
+	// DecisionLogs logs every policy decision on the principals of the group, to
+	// find out which policy denied a request.
+	DecisionLogs bool
+	// RequireTwoFactor makes the admins of the group act as clients, until they
+	// enable two factor authentication.
+	RequireTwoFactor bool
+}
+
+func (s *Service) GetGroupSettings(ctx context.Context, id GroupID) (*GroupSettings, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceGroups, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	group, err := s.repo.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group.Settings, nil
+}
+
+// RequiresTwoFactor reports whether the admins of the group should enable two
+// factor authentication, on behalf of the access module while authenticating.
+func (s *Service) RequiresTwoFactor(ctx context.Context, id GroupID) (bool, error) {
+	group, err := s.repo.GetGroup(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return group.Settings.RequireTwoFactor, nil
+}
+
+// UpdateGroupSettings changes the settings of a group. It only applies to the
+// instances created afterwards, existing instances are not shared or split.
+func (s *Service) UpdateGroupSettings(ctx context.Context, id GroupID, settings GroupSettings) (*GroupSettings, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.ResourceID(ResourceGroups, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	if settings.MaxClientsPerInstance < 1 {
+		return nil, errors.Join(ErrBadRequest, errors.New("max clients per instance should be at least 1"))
+	}
+
+	if settings.MaxRenewalsPerDay < 0 {
+		return nil, errors.Join(ErrBadRequest, errors.New("max renewals per day should not be negative"))
+	}
+
+	if settings.MinProbeReporters < 1 {
+		return nil, errors.Join(ErrBadRequest, errors.New("min probe reporters should be at least 1"))
+	}
+
+	if settings.RotationInterval < 0 || settings.TTL < 0 || settings.RenewalGracePeriod < 0 {
+		return nil, errors.Join(ErrBadRequest, errors.New("rotation interval, ttl and renewal grace period should not be negative"))
+	}
+
+	if err := settings.UserRateLimit.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := settings.GroupRateLimit.validate(); err != nil {
+		return nil, err
+	}
+
+	if settings.MonthlyBudget < 0 {
+		return nil, errors.Join(ErrBadRequest, errors.New("monthly budget should not be negative"))
+	}
+
+	if settings.Quota.TrafficBytes < 0 || settings.Quota.ActiveDays < 0 {
+		return nil, errors.Join(ErrBadRequest, errors.New("quota should not be negative"))
+	}
+
+	if slices.Contains(settings.Regions, "") {
+		return nil, errors.Join(ErrBadRequest, errors.New("regions should not be empty"))
+	}
+
+	if err := validateSNIPool(settings.SNIPool); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		if _, err := s.repo.GetGroup(ctx, id); err != nil {
+			return err
+		}
+
+		return s.repo.SaveGroupSettings(ctx, id, settings)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.decisions.Enable(id.UUID, settings.DecisionLogs)
+	return &settings, nil
 }
 
 // CreateGroup updates the group read model in hosting domain
@@ -61,6 +216,25 @@ func (s *Service) SaveGroup(ctx context.Context, group *Group) error {
 			}
 		}
 
+		if group.Settings.MaxClientsPerInstance == 0 {
+			group.Settings.MaxClientsPerInstance = DefaultMaxClientsPerInstance
+		}
+		if group.Settings.MaxRenewalsPerDay == 0 {
+			group.Settings.MaxRenewalsPerDay = DefaultMaxRenewalsPerDay
+		}
+		if group.Settings.MinProbeReporters == 0 {
+			group.Settings.MinProbeReporters = DefaultMinProbeReporters
+		}
+		if group.Settings.RenewalGracePeriod == 0 {
+			group.Settings.RenewalGracePeriod = DefaultRenewalGracePeriod
+		}
+		if group.Settings.UserRateLimit == (RateLimit{}) {
+			group.Settings.UserRateLimit = DefaultUserRateLimit
+		}
+		if group.Settings.GroupRateLimit == (RateLimit{}) {
+			group.Settings.GroupRateLimit = DefaultGroupRateLimit
+		}
+
 		sshKeyRemoteID, err := s.vps.CreateSSHKey(ctx, group.Host.APIKey, s.systemKey.PublicKey)
 		if err != nil {
 			return fmt.Errorf("error creating ssh key on vps provider: %w", err)
@@ -79,7 +253,26 @@ func (s *Service) SaveGroup(ctx context.Context, group *Group) error {
 		group.DefaultStartUpScript.ID = StartUpScriptID{UUID: uuid.Must(uuid.NewV4())}
 		group.DefaultStartUpScript.RemoteID = scriptRemoteID
 
-		_, err = s.repo.SaveGroup(ctx, group)
+		if _, err = s.repo.SaveGroup(ctx, group); err != nil {
+			return err
+		}
+
+		// Existing rule sets are managed by the admins of the group.
+		// We only seed the default preset for the groups without any.
+		rulesets, err := s.repo.ListRuleSets(ctx, authz.Clause{Condition: "group_id = ?", Values: []any{group.ID}})
+		if err != nil {
+			return err
+		}
+		if len(rulesets) > 0 {
+			return nil
+		}
+
+		ruleset, err := NewRuleSetFromPreset(group.ID, DefaultPreset)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.repo.SaveRuleSet(ctx, ruleset)
 		return err
 	})
 }
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/reality"
+	"vpainless/pkg/remote"
+)
+
+const (
+	// healthCheckTimeout bounds each of the probes of a health check.
+	healthCheckTimeout = 10 * time.Second
+	// healthCheckRetention is how long the history of health checks is kept.
+	healthCheckRetention = 7 * 24 * time.Hour
+	// healthHistorySize is the number of the latest health checks returned.
+	healthHistorySize = 100
+	// healthCheckEmail tags the client of the health checks on the instances.
+	healthCheckEmail = "health-check"
+)
+
+// monitoredStatuses are the statuses of the instances that are set up, and
+// whose status is determined by health checks afterwards.
+var monitoredStatuses = []any{StatusOK, StatusDegraded, StatusFailed, StatusBlocked}
+
+const monitoredCondition = "i.status in (?, ?, ?, ?)"
+
+// HealthCheck is the result of probing an instance.
+type HealthCheck struct {
+	InstanceID InstanceID
+	CheckedAt  time.Time
+	// TCP is true if the instance accepts connections on port 443.
+	TCP bool
+	// Reality is true if the instance verifies a reality handshake with its public
+	// key and short id, accepts the vless client of the health checks, and relays
+	// the answer of the destination. It shows that clients can connect.
+	Reality bool
+	// Xray is true if the xray service is active on the instance.
+	Xray  bool
+	Error string
+}
+
+// Status returns the status of the instance based on the health check.
+// Instances that fail the reality handshake can not serve clients.
+func (c *HealthCheck) Status() InstanceStatus {
+	switch {
+	case !c.Reality:
+		return StatusFailed
+	case !c.TCP || !c.Xray:
+		return StatusDegraded
+	default:
+		return StatusOK
+	}
+}
+
+// ListHealthChecks returns the latest health checks of an instance, newest first.
+func (s *Service) ListHealthChecks(ctx context.Context, id InstanceID) ([]*HealthCheck, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	// Health checks are part of the instance, so they are visible to
+	// whoever can see the instance.
+	policy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceInstances, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	var result []*HealthCheck
+	if err := s.repo.Transact(ctx, sql.LevelReadCommitted, func(ctx context.Context) error {
+		if _, err := s.repo.GetInstance(ctx, id, policy.Partial); err != nil {
+			return err
+		}
+
+		result, err = s.repo.ListHealthChecks(ctx, id, healthHistorySize)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// MonitorHealth checks the health of the instances every interval, until the context is done.
+// Blocked instances of the groups with auto renew are replaced afterwards.
+func (s *Service) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkInstances(ctx)
+			s.renewBlockedInstances(ctx)
+		}
+	}
+}
+
+func (s *Service) checkInstances(ctx context.Context) {
+	instances, err := s.repo.ListInstances(ctx, authz.Clause{
+		Condition: monitoredCondition,
+		Values:    monitoredStatuses,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing instances for health checks", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.checkInstance(ctx, instance); err != nil {
+				slog.ErrorContext(ctx, "core: error checking instance health", "instance_id", instance.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := s.repo.DeleteHealthChecks(ctx, time.Now().Add(-healthCheckRetention)); err != nil {
+		slog.ErrorContext(ctx, "core: error deleting old health checks", "error", err)
+	}
+}
+
+// checkInstance probes the instance, and updates its status accordingly.
+func (s *Service) checkInstance(ctx context.Context, instance *Instance) error {
+	check := probeInstance(ctx, instance)
+	status := check.Status()
+	if status != StatusOK {
+		slog.WarnContext(ctx, "core: instance is unhealthy", "instance_id", instance.ID, "status", status, "error", check.Error)
+	}
+
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		if err := s.repo.SaveHealthCheck(ctx, check); err != nil {
+			return err
+		}
+
+		// The instance may be changed or deleted while being probed.
+		instance, err := s.repo.GetInstance(ctx, instance.ID, authz.Clause{
+			Condition: monitoredCondition,
+			Values:    monitoredStatuses,
+		})
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if instance.Status == status {
+			return nil
+		}
+
+		// Health checks run from outside the country, and can not tell if
+		// the instance is unblocked. Only probe reports unblock instances.
+		if instance.Status == StatusBlocked && status != StatusFailed {
+			return nil
+		}
+
+		slog.InfoContext(ctx, "core: instance status changed", "instance_id", instance.ID, "from", instance.Status, "to", status)
+		instance.Status = status
+		_, err = s.repo.SaveInstance(ctx, instance)
+		return err
+	})
+}
+
+func probeInstance(ctx context.Context, instance *Instance) *HealthCheck {
+	check := &HealthCheck{
+		InstanceID: instance.ID,
+		CheckedAt:  time.Now(),
+	}
+
+	var errs []error
+	addr := net.JoinHostPort(instance.IP.String(), "443")
+	dialer := &net.Dialer{Timeout: healthCheckTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("tcp: %w", err))
+	} else {
+		check.TCP = true
+		conn.Close()
+	}
+
+	destination := instance.Config.Reality.FakeURL
+	if destination == "" {
+		destination = fakeURL
+	}
+
+	err = reality.Probe(ctx, addr, reality.Options{
+		ServerName: destination,
+		PublicKey:  instance.Config.Reality.Curve25519PublicKey,
+		ShortID:    instance.Config.Reality.ShortID,
+		ClientID:   instance.Config.Reality.HealthCheckClientID().UUID,
+		Dial:       dialer.DialContext,
+		Timeout:    healthCheckTimeout,
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("reality: %w", err))
+	} else {
+		check.Reality = true
+	}
+
+	if err := checkXrayService(instance); err != nil {
+		errs = append(errs, fmt.Errorf("xray: %w", err))
+	} else {
+		check.Xray = true
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func checkXrayService(instance *Instance) error {
+	conn, err := remote.Dial(instance.IP, instance.PrivateKey, "root")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// is-active exits with non zero status for inactive services,
+	// so the output is only checked when it succeeds.
+	out, err := remote.Execute(conn, "systemctl is-active xray")
+	if err != nil {
+		return err
+	}
+
+	if state := strings.TrimSpace(out); state != "active" {
+		return fmt.Errorf("service is %s", state)
+	}
+	return nil
+}
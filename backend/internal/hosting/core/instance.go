@@ -29,22 +29,41 @@ const (
 	StatusOff          InstanceStatus = "off"
 	StatusOK           InstanceStatus = "ok"
 	StatusInitializing InstanceStatus = "initializing"
+	// StatusDegraded instances serve clients, but some of the health checks fail.
+	StatusDegraded InstanceStatus = "degraded"
+	// StatusFailed instances do not serve clients.
+	StatusFailed InstanceStatus = "failed"
+	// StatusBlocked instances are healthy, but not reachable from inside the country.
+	StatusBlocked InstanceStatus = "blocked"
 )
 
 type RemoteInstance struct {
-	ID InstanceID
-	IP net.IP
+	ID     InstanceID
+	IP     net.IP
+	Region string
+	Price  PlanPrice
 }
 
 type Instance struct {
-	ID         InstanceID
-	RemoteID   InstanceID
-	Owner      UserID
+	ID       InstanceID
+	RemoteID InstanceID
+	Owner    UserID
+	// GroupID is the group the instance is created in. Users have an instance
+	// in each of the groups they are members of.
+	GroupID    GroupID
 	IP         net.IP
 	Status     InstanceStatus
 	Config     XrayConfig
 	PrivateKey []byte
 	CreatedAt  time.Time
+	// Shared instances are used by multiple users, each as a separate client.
+	Shared bool
+	Region string
+	// Replaces is set on the replacements of instances while they are being set up.
+	// It is cleared once the replacement is swapped in.
+	Replaces *InstanceID
+	// Price is the price of the instance plan when it was created.
+	Price PlanPrice
 }
 
 type SSHKeyPair struct {
@@ -59,6 +78,8 @@ type CreateInstanceParam struct {
 	SSHKey SSHKeyPair
 	Label  string
 	Script StartUpScript
+	// Region is left to the provider when empty.
+	Region string
 }
 
 func (s *Service) GetInstance(ctx context.Context, id InstanceID) (*Instance, error) {
@@ -79,7 +100,7 @@ func (s *Service) GetInstance(ctx context.Context, id InstanceID) (*Instance, er
 			return err
 		}
 
-		return nil
+		return s.personalize(ctx, principal, instance)
 	}); err != nil {
 		return nil, err
 	}
@@ -98,35 +119,66 @@ func (s *Service) DeleteInstance(ctx context.Context, id InstanceID) error {
 		return ErrUnauthorized
 	}
 
-	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+	var revoke bool
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
 		instance, err := s.repo.GetInstance(ctx, id, authz.Clause{})
 		if err != nil {
 			return err
 		}
 
-		if err := s.repo.DeleteInstance(ctx, id, deletePolicy.Partial); err != nil {
+		if err := s.limitRate(ctx, UserID{principal.ID}, instance.GroupID); err != nil {
 			return err
 		}
 
-		group, err := s.repo.GetGroup(ctx, GroupID{UUID: principal.GroupID})
-		if err != nil {
-			return errors.Join(ErrGroups, err)
+		// Clients of shared instances only give up their own access,
+		// other clients keep using the instance.
+		if instance.Shared && !principal.Role.Operates() {
+			revoke = true
+			return nil
 		}
 
-		err = s.vps.DeleteInstance(ctx, group.Host.APIKey, instance.RemoteID)
-		if err == nil {
-			return nil
+		if err := s.repo.DeleteInstance(ctx, id, deletePolicy.Partial); err != nil {
+			return err
 		}
 
-		// If the remote instance is not found, maybe it is deleted manually
-		// by any of the admins. The best we can do in this case is to log the
-		// error and continue.
-		if errors.Is(err, vultr.ErrNotFound) {
-			slog.WarnContext(ctx, "remote instance not found", "remote_id", instance.RemoteID)
-			return nil
+		if err := s.audit(ctx, AuditInstanceDelete, "instances/"+id.String(), map[string]AuditChange{
+			"owner":  {Old: instance.Owner},
+			"ip":     {Old: instance.IP.String()},
+			"region": {Old: instance.Region},
+		}); err != nil {
+			return err
 		}
+
+		group, err := s.repo.GetGroup(ctx, instance.GroupID)
+		if err != nil {
+			return errors.Join(ErrGroups, err)
+		}
+
+		return s.deleteRemoteInstance(ctx, group.Host.APIKey, instance)
+	}); err != nil {
 		return err
-	})
+	}
+
+	if revoke {
+		return s.RevokeClient(ctx, id, UserID{principal.ID})
+	}
+	return nil
+}
+
+func (s *Service) deleteRemoteInstance(ctx context.Context, apikey string, instance *Instance) error {
+	err := s.vps.DeleteInstance(ctx, apikey, instance.RemoteID)
+	if err == nil {
+		return nil
+	}
+
+	// If the remote instance is not found, maybe it is deleted manually
+	// by any of the admins. The best we can do in this case is to log the
+	// error and continue.
+	if errors.Is(err, vultr.ErrNotFound) {
+		slog.WarnContext(ctx, "remote instance not found", "remote_id", instance.RemoteID)
+		return nil
+	}
+	return err
 }
 
 func (s *Service) CreateInstance(ctx context.Context) (*Instance, error) {
@@ -136,13 +188,17 @@ func (s *Service) CreateInstance(ctx context.Context) (*Instance, error) {
 	}
 
 	var (
-		result *Instance
-		apikey string
-		param  CreateInstanceParam
+		result  *Instance
+		pending *InstanceRequest
+		joined  *InstanceClient
+		apikey  string
+		param   CreateInstanceParam
+		created bool
 	)
 
-	err = s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) (err error) {
+	err = s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
 		userID := UserID{UUID: principal.ID}
+		groupID := GroupID{UUID: principal.GroupID}
 		policy, err := s.enforcer.Can(ctx, principal, authz.Create, authz.ResourceFunc(func() (string, any) {
 			return ResourceInstances, map[string]any{
 				"user_id": userID,
@@ -156,69 +212,168 @@ func (s *Service) CreateInstance(ctx context.Context) (*Instance, error) {
 			return err
 		}
 
-		result, err = s.repo.FindInstance(ctx, userID)
+		result, err = s.repo.FindInstance(ctx, userID, groupID)
 		if err == nil {
+			return s.personalize(ctx, principal, result)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		client, err := s.repo.FindClient(ctx, userID, groupID)
+		if err == nil {
+			result, err = s.repo.GetInstance(ctx, client.InstanceID, authz.Clause{})
+			if err != nil {
+				return err
+			}
+			result.Config.ConnectionString = client.ConnectionString(result)
 			return nil
 		}
 		if !errors.Is(err, ErrNotFound) {
 			return err
 		}
 
-		group, err := s.repo.GetGroup(ctx, GroupID{principal.GroupID})
+		group, err := s.repo.GetGroup(ctx, groupID)
 		if err != nil {
 			return err
 		}
 		apikey = group.Host.APIKey
 
-		param = CreateInstanceParam{
-			SSHKey: group.DefaultSSHKey,
-			Label:  userID.String()[:8],
-			Script: group.DefaultStartUpScript,
+		if group.Settings.SharedInstances {
+			result, joined, err = s.joinSharedInstance(ctx, group, userID)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return err
+			}
 		}
 
-		slog.InfoContext(ctx, "creating vultr instance...")
-		remoteInstance, err := s.vps.CreateInstance(ctx, apikey, param)
-		if err != nil {
+		// Clients of groups that require approval only request an instance; the
+		// instance is created once an admin approves it.
+		if !s.canApprove(ctx, principal, group, userID) {
+			pending, err = s.requestInstance(ctx, group, userID)
 			return err
 		}
-		defer func() {
-			if err != nil {
-				err = errors.Join(err, s.vps.DeleteInstance(ctx, apikey, remoteInstance.ID))
-			}
-		}()
-
-		result = &Instance{
-			ID:         InstanceID{UUID: uuid.Must(uuid.NewV4())},
-			RemoteID:   remoteInstance.ID,
-			Owner:      userID,
-			IP:         remoteInstance.IP,
-			CreatedAt:  time.Now(),
-			Status:     StatusInitializing,
-			Config:     XrayConfig{},
-			PrivateKey: group.DefaultSSHKey.PrivateKey,
-		}
 
-		slog.InfoContext(ctx, "creating db instance...", "user_id", result.Owner, "remote_id", remoteInstance.ID)
-		result, err = s.repo.SaveInstance(ctx, result)
+		result, param, err = s.provisionInstance(ctx, group, userID)
 		if err != nil {
 			return err
 		}
-
-		return err
+		created = true
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if pending != nil {
+		return nil, &ApprovalPendingError{Request: pending}
+	}
+
+	if joined != nil {
+		if err := s.reconfigureInstance(ctx, result); err != nil {
+			// The client is revoked again, so joining can be retried.
+			now := time.Now()
+			joined.RevokedAt = &now
+			_, revokeErr := s.repo.SaveClient(ctx, joined)
+			return nil, errors.Join(err, revokeErr)
+		}
+		return result, nil
+	}
+
+	if !created {
+		return result, nil
+	}
+
 	// TODO: instead of this, we have to read the db and init instances in a separete
 	// go routine.
 	go s.SetupInstance(apikey, result, param)
 	return result, nil
 }
 
+// provisionInstance creates an instance on the provider for the user, and saves it.
+// The remote instance is deleted if it can not be saved. It should be called in a
+// transaction, and the instance set up once it is committed.
+func (s *Service) provisionInstance(ctx context.Context, group *Group, userID UserID) (result *Instance, param CreateInstanceParam, err error) {
+	if err := s.limitRate(ctx, userID, group.ID); err != nil {
+		return nil, param, err
+	}
+
+	if err := s.checkBudget(ctx, group); err != nil {
+		return nil, param, err
+	}
+
+	param = CreateInstanceParam{
+		SSHKey: group.DefaultSSHKey,
+		Label:  userID.String()[:8],
+		Script: group.DefaultStartUpScript,
+	}
+
+	slog.InfoContext(ctx, "creating vultr instance...")
+	remoteInstance, err := s.vps.CreateInstance(ctx, group.Host.APIKey, param)
+	if err != nil {
+		return nil, param, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, s.vps.DeleteInstance(ctx, group.Host.APIKey, remoteInstance.ID))
+		}
+	}()
+
+	result = &Instance{
+		ID:         InstanceID{UUID: uuid.Must(uuid.NewV4())},
+		RemoteID:   remoteInstance.ID,
+		Owner:      userID,
+		GroupID:    group.ID,
+		IP:         remoteInstance.IP,
+		CreatedAt:  time.Now(),
+		Status:     StatusInitializing,
+		Config:     XrayConfig{},
+		PrivateKey: group.DefaultSSHKey.PrivateKey,
+		Shared:     group.Settings.SharedInstances,
+		Region:     remoteInstance.Region,
+		Price:      remoteInstance.Price,
+	}
+
+	slog.InfoContext(ctx, "creating db instance...", "user_id", result.Owner, "remote_id", remoteInstance.ID)
+	result, err = s.repo.SaveInstance(ctx, result)
+	if err != nil {
+		return nil, param, err
+	}
+
+	if err := s.audit(ctx, AuditInstanceCreate, "instances/"+result.ID.String(), map[string]AuditChange{
+		"owner":  {New: userID},
+		"region": {New: result.Region},
+		"shared": {New: result.Shared},
+	}); err != nil {
+		return nil, param, err
+	}
+
+	if !result.Shared {
+		return result, param, nil
+	}
+
+	_, err = s.repo.SaveClient(ctx, &InstanceClient{
+		ID:         ClientID{uuid.Must(uuid.NewV4())},
+		InstanceID: result.ID,
+		UserID:     userID,
+		CreatedAt:  result.CreatedAt,
+	})
+	if err != nil {
+		return nil, param, err
+	}
+	return result, param, nil
+}
+
 func (s *Service) SetupInstance(apikey string, instance *Instance, param CreateInstanceParam) {
-	ctx := context.Background()
-	err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+	if err := s.setupInstance(context.Background(), apikey, instance, param); err != nil {
+		slog.Error("error setting up instance", "error", err)
+	}
+}
+
+func (s *Service) setupInstance(ctx context.Context, apikey string, instance *Instance, param CreateInstanceParam) error {
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
 		slog.InfoContext(ctx, "core: waiting for instance to finish initialization...", "instance_id", instance.ID.String())
 		conn, err := s.waitForSSHClient(ctx, instance, apikey, param.SSHKey.PrivateKey, "root")
 		if err != nil {
@@ -227,17 +382,31 @@ func (s *Service) SetupInstance(apikey string, instance *Instance, param CreateI
 		defer conn.Close()
 
 		slog.InfoContext(ctx, "core: uploading reality config...", "instance_id", instance.ID)
-		realityConfig, err := NewRealityConfig(fakeURL)
+		group, err := s.repo.GetGroup(ctx, instance.GroupID)
+		if err != nil {
+			return errors.Join(ErrGroups, err)
+		}
+
+		realityConfig, err := NewRealityConfig(s.pickDestination(ctx, conn, group))
 		if err != nil {
 			return fmt.Errorf("error creating reality config: %w", err)
 		}
-		b := bytes.NewBufferString(realityConfig.String())
-		if err := remote.UploadFile(conn, "/usr/local/etc/xray/config.json", b); err != nil {
+		config, err := s.renderConfig(ctx, instance, realityConfig)
+		if err != nil {
+			return fmt.Errorf("error rendering xray config: %w", err)
+		}
+		b := bytes.NewBufferString(config)
+		if err := remote.UploadFile(conn, xrayConfigPath, b); err != nil {
 			return fmt.Errorf("error uploading reality config: %w", err)
 		}
 
 		instance.Config = XrayConfig{
 			ConnectionString: realityConfig.ConnectionString(instance.IP),
+			Reality:          realityConfig,
+		}
+		// Each client of a shared instance has its own connection string.
+		if instance.Shared {
+			instance.Config.ConnectionString = ""
 		}
 
 		slog.WarnContext(ctx, "core: restarting xray...", "instance_id", instance.ID)
@@ -253,9 +422,45 @@ func (s *Service) SetupInstance(apikey string, instance *Instance, param CreateI
 		}
 		return nil
 	})
+}
+
+// renderConfig renders the reality config of an instance with its clients, except the suspended ones,
+// merged with the enabled routing rule sets of the group the instance owner belongs to.
+func (s *Service) renderConfig(ctx context.Context, instance *Instance, realityConfig XrayTemplate) (string, error) {
+	// The owner is the only client of the instances that are not shared. It is
+	// still tagged by its user id, so its traffic is accounted for.
+	clients := []*InstanceClient{{ID: ClientID(realityConfig.ID), UserID: instance.Owner}}
+	if instance.Shared {
+		// Replacements serve the clients of the instance they replace,
+		// the clients are moved over when they are swapped in.
+		id := instance.ID
+		if instance.Replaces != nil {
+			id = *instance.Replaces
+		}
+
+		var err error
+		clients, err = s.repo.ListClients(ctx, id, authz.Clause{})
+		if err != nil {
+			return "", fmt.Errorf("error listing instance clients: %w", err)
+		}
+	}
+
+	clients, err := s.activeClients(ctx, clients)
 	if err != nil {
-		slog.Error("error setting up instance", "error", err)
+		return "", fmt.Errorf("error filtering suspended clients: %w", err)
+	}
+
+	config, err := applyClients(realityConfig, clients)
+	if err != nil {
+		return "", err
 	}
+
+	rulesets, err := s.repo.FindRuleSets(ctx, instance.GroupID)
+	if err != nil {
+		return "", fmt.Errorf("error finding routing rule sets: %w", err)
+	}
+
+	return applyRuleSets(config, rulesets)
 }
 
 // waitForSSHClient tries go get a ssh client to the specified instance. It will retries until success
@@ -320,7 +525,16 @@ func (s *Service) ListInstances(ctx context.Context) ([]*Instance, error) {
 		}
 
 		instances, err = s.repo.ListInstances(ctx, policy.Partial)
-		return err
+		if err != nil {
+			return err
+		}
+
+		for _, instance := range instances {
+			if err := s.personalize(ctx, principal, instance); err != nil {
+				return err
+			}
+		}
+		return nil
 	}); err != nil {
 		return nil, err
 	}
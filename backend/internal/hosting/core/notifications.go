@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type (
+	NotificationID   struct{ uuid.UUID }
+	NotificationKind string
+)
+
+const (
+	ResourceNotifications = "notifications"
+
+	// NotificationReplaced is sent when the instance of the user is replaced,
+	// and the user needs the new connection string.
+	NotificationReplaced NotificationKind = "replaced"
+	// NotificationExpired is sent when the instance of the user is deleted at the end of its ttl.
+	NotificationExpired NotificationKind = "expired"
+
+	// notificationHistorySize is the number of the latest notifications returned.
+	notificationHistorySize = 50
+)
+
+// Notification tells a user about the changes to their instance made by vpainless.
+type Notification struct {
+	ID         NotificationID
+	UserID     UserID
+	InstanceID InstanceID
+	Kind       NotificationKind
+	Message    string
+	CreatedAt  time.Time
+}
+
+// ListNotifications returns the latest notifications of the principal, newest first.
+func (s *Service) ListNotifications(ctx context.Context) ([]*Notification, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.Resource{Group: ResourceNotifications})
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListNotifications(ctx, policy.Partial, notificationHistorySize)
+}
+
+// notifyUsers notifies the users of the instance; its owner, and its clients if shared.
+func (s *Service) notifyUsers(ctx context.Context, instance *Instance, clients []*InstanceClient, kind NotificationKind, message string) error {
+	users := []UserID{instance.Owner}
+	for _, client := range clients {
+		if client.UserID != instance.Owner {
+			users = append(users, client.UserID)
+		}
+	}
+
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		now := time.Now()
+		for _, user := range users {
+			if err := s.repo.SaveNotification(ctx, &Notification{
+				ID:         NotificationID{uuid.Must(uuid.NewV4())},
+				UserID:     user,
+				InstanceID: instance.ID,
+				Kind:       kind,
+				Message:    message,
+				CreatedAt:  now,
+			}); err != nil {
+				return err
+			}
+		}
+
+		slog.InfoContext(ctx, "core: users are notified", "instance_id", instance.ID, "kind", kind, "users", len(users))
+		return nil
+	})
+}
@@ -1,14 +1,71 @@
 package core
 
 import (
+	"context"
 	_ "embed"
 )
 
 //go:embed policy/instances.rego
 var instancesModule string
 
+//go:embed policy/rulesets.rego
+var rulesetsModule string
+
+//go:embed policy/clients.rego
+var clientsModule string
+
+//go:embed policy/groups.rego
+var groupsModule string
+
+//go:embed policy/probes.rego
+var probesModule string
+
+//go:embed policy/notifications.rego
+var notificationsModule string
+
+//go:embed policy/usage.rego
+var usageModule string
+
+//go:embed policy/quotas.rego
+var quotasModule string
+
+//go:embed policy/costs.rego
+var costsModule string
+
+//go:embed policy/ratelimits.rego
+var rateLimitsModule string
+
+//go:embed policy/audit.rego
+var auditModule string
+
+//go:embed policy/decisions.rego
+var decisionsModule string
+
 func policies() map[string]string {
 	return map[string]string{
-		"access/instances.rego": instancesModule,
+		"access/instances.rego":     instancesModule,
+		"access/rulesets.rego":      rulesetsModule,
+		"access/clients.rego":       clientsModule,
+		"access/groups.rego":        groupsModule,
+		"access/probes.rego":        probesModule,
+		"access/notifications.rego": notificationsModule,
+		"access/usage.rego":         usageModule,
+		"access/quotas.rego":        quotasModule,
+		"access/costs.rego":         costsModule,
+		"access/ratelimits.rego":    rateLimitsModule,
+		"access/audit.rego":         auditModule,
+		"access/decisions.rego":     decisionsModule,
 	}
 }
+
+// ReloadPolicies overrides or extends the embedded policies with the external
+// modules. A set that does not compile or define every policy is refused, and
+// the policies in effect are kept.
+func (s *Service) ReloadPolicies(ctx context.Context, modules map[string]string) error {
+	return s.enforcer.Reload(ctx, modules)
+}
+
+// CheckPolicies reports whether ReloadPolicies would accept the external modules.
+func (s *Service) CheckPolicies(ctx context.Context, modules map[string]string) error {
+	return s.enforcer.Check(ctx, modules)
+}
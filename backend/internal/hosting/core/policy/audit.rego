@@ -0,0 +1,17 @@
+package hosting.audit
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ List
+# Staff should be able to see the audit log of their group
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.row.group_id = input.principal.group_id
+}
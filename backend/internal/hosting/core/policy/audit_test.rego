@@ -0,0 +1,68 @@
+package hosting_test.audit
+
+import data.hosting.audit.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: list
+
+test_admins_should_be_able_to_list_audit_log_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+		"resource": {},
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_clients_should_not_be_able_to_list_audit_log if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+		"resource": {},
+	}
+
+	not allow with input as request
+}
+
+test_groupless_admins_should_not_be_able_to_list_audit_log if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"role": "admin",
+		},
+		"action": "list",
+		"resource": {},
+	}
+
+	not allow with input as request
+}
+
+test_staff_should_be_able_to_list_audit_log_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "list",
+			"resource": {},
+		}
+
+		allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+		not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+	}
+}
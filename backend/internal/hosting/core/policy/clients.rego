@@ -0,0 +1,48 @@
+package hosting.clients
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+# Clients are users connecting to shared instances. Operators manage the
+# clients of their group, and users can only see and revoke themselves.
+
+################ List
+# Staff should be able to list clients of their group instances
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.resource.instance_id
+	input.row.group_id = input.principal.group_id
+}
+
+# Clients should only see themselves
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role = "client"
+	input.resource.instance_id
+	input.row.user_id = input.principal.id
+}
+
+################ Delete
+# Operators should be able to revoke clients of their group instances
+allow if {
+	input.action = "delete"
+	input.principal.id
+	input.principal.role in roles.operators
+	input.principal.group_id = input.resource.group_id
+	input.resource.user_id
+}
+
+# Users should be able to revoke their own access
+allow if {
+	input.action = "delete"
+	input.principal.id = input.resource.user_id
+	input.principal.group_id = input.resource.group_id
+}
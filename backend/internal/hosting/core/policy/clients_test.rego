@@ -0,0 +1,154 @@
+package hosting_test.clients
+
+import data.hosting.clients.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: list
+
+test_admins_should_be_able_to_list_clients_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+		"resource": {"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_clients_should_only_list_themselves if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+		"resource": {"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+	}
+
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
+}
+
+############# Action: delete
+
+test_admins_should_be_able_to_revoke_clients_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "delete",
+		"resource": {
+			"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_admins_should_not_be_able_to_revoke_clients_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "delete",
+		"resource": {
+			"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000022",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_be_able_to_revoke_themselves if {
+	request := {
+		"principal": {
+			"id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "delete",
+		"resource": {
+			"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_clients_should_not_be_able_to_revoke_others if {
+	request := {
+		"principal": {
+			"id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "delete",
+		"resource": {
+			"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "33000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Roles
+
+test_operators_should_be_able_to_revoke_clients_of_their_group if {
+	every role in ["owner", "operator"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "delete",
+			"resource": {
+				"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+				"user_id": "22000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+			},
+		}
+
+		allow with input as request
+	}
+}
+
+test_viewers_should_only_list_clients_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "viewer",
+		},
+		"resource": {
+			"instance_id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+		"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"action": "list"})
+	not allow with input as object.union(request, {"action": "delete"})
+}
@@ -0,0 +1,15 @@
+package hosting.costs
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Get
+# Staff should be able to see the spend of their own group
+allow if {
+	input.action = "get"
+	input.principal.role in roles.staff
+	input.principal.group_id = input.resource.id
+}
@@ -0,0 +1,67 @@
+package hosting_test.costs
+
+import data.hosting.costs.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: get
+
+test_admins_should_be_able_to_get_costs_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as request
+}
+
+test_admins_should_not_be_able_to_get_costs_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {"id": "00000000-0000-0000-0000-000000000022"},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_get_costs if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	not allow with input as request
+}
+
+test_staff_should_be_able_to_get_costs_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "get",
+			"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+		}
+
+		allow with input as request
+	}
+}
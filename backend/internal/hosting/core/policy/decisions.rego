@@ -0,0 +1,16 @@
+package hosting.decisions
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Explain
+# Managers should be able to replay the decisions on the users of their group
+allow if {
+	input.action = "explain"
+	input.principal.id
+	input.principal.role in roles.managers
+	input.principal.group_id = input.resource.group_id
+}
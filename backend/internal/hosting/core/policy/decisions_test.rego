@@ -0,0 +1,66 @@
+package hosting_test.decisions
+
+import data.hosting.decisions.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: explain
+
+test_admins_should_be_able_to_explain_decisions_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "explain",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as request
+}
+
+test_admins_should_not_be_able_to_explain_decisions_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "explain",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000022"},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_explain_decisions if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "explain",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	not allow with input as request
+}
+
+test_only_managers_should_be_able_to_explain_decisions if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+		"action": "explain",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"principal": {"role": "owner"}})
+	not allow with input as object.union(request, {"principal": {"role": "operator"}})
+	not allow with input as object.union(request, {"principal": {"role": "viewer"}})
+}
@@ -0,0 +1,25 @@
+package hosting.groups
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Get
+# Staff should be able to see the hosting settings of their group
+allow if {
+	input.action = "get"
+	input.principal.id
+	input.principal.role in roles.staff
+	input.principal.group_id = input.resource.id
+}
+
+################ Update
+# Managers should be able to change the hosting settings of their group
+allow if {
+	input.action = "update"
+	input.principal.id
+	input.principal.role in roles.managers
+	input.principal.group_id = input.resource.id
+}
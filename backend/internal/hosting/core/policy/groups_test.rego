@@ -0,0 +1,79 @@
+package hosting_test.groups
+
+import data.hosting.groups.allow
+
+test_default_allow if {
+	allow == false
+}
+
+test_admins_should_be_able_to_manage_their_group_settings if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"action": "get"})
+	allow with input as object.union(request, {"action": "update"})
+}
+
+test_admins_should_not_be_able_to_manage_other_group_settings if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {"id": "00000000-0000-0000-0000-000000000022"},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_manage_group_settings if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "update",
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	not allow with input as request
+}
+
+test_owners_should_be_able_to_manage_their_group_settings if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "owner",
+		},
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"action": "get"})
+	allow with input as object.union(request, {"action": "update"})
+}
+
+test_operators_and_viewers_should_only_see_their_group_settings if {
+	every role in ["operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+		}
+
+		allow with input as object.union(request, {"action": "get"})
+		not allow with input as object.union(request, {"action": "update"})
+	}
+}
@@ -1,14 +1,18 @@
 package hosting.instances
 
+import data.authz.roles
 import rego.v1
 
 # Default deny
 default allow := false
 
-default partial := {
-	"condition": "",
-	"values": [],
-}
+# Rows are the instances, with the group of their owner and their active clients:
+# {"id", "user_id", "group_id", "client_ids"}
+
+# Users can see the instances they own, and the shared instances they are an active client of.
+own_or_shared if input.row.user_id = input.principal.id
+
+own_or_shared if input.principal.id in input.row.client_ids
 
 ################ Create
 # Allow users to create instances
@@ -25,23 +29,20 @@ allow if {
 	input.action = "get"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role in ["client", "admin"]
 	input.resource.id
+	own_or_shared
 }
 
-partial := clause if {
+# Staff should be able to see the instances of their group
+allow if {
 	input.action = "get"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role in ["client", "admin"]
+	input.principal.role in roles.staff
 	input.resource.id
-	clause := {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	}
+	input.row.group_id = input.principal.group_id
 }
 
-
 ################ List
 # Clients should be able to list their instance
 allow if {
@@ -49,39 +50,39 @@ allow if {
 	input.principal.id
 	input.principal.group_id
 	input.principal.role = "client"
+	own_or_shared
 }
 
-partial := clause if {
+# Staff should be able to list their clients instances.
+allow if {
 	input.action = "list"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role = "client"
-	clause := {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	}
+	input.principal.role in roles.staff
+	input.row.group_id = input.principal.group_id
 }
 
-# Admins should be able to list their clients instances.
+################ Update
+# Users should be able to rotate the credentials of their instances
 allow if {
-	input.action = "list"
+	input.action = "update"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role = "admin"
+	input.principal.role = "client"
+	input.resource.id
+	own_or_shared
 }
 
-partial := clause if {
-	input.action = "list"
+# Operators should be able to rotate the credentials of their clients instances
+allow if {
+	input.action = "update"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role = "admin"
-	clause := {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id],
-	}
+	input.principal.role in roles.operators
+	input.resource.id
+	input.row.group_id = input.principal.group_id
 }
 
-
 ################ Delete
 # Clients should be able to delete their instance
 allow if {
@@ -90,38 +91,33 @@ allow if {
 	input.principal.group_id
 	input.principal.role = "client"
 	input.resource.id
+	input.row.user_id = input.principal.id
 }
 
-partial := clause if {
+# Operators should be able to delete instances that belong to their clients
+allow if {
 	input.action = "delete"
 	input.principal.id
 	input.principal.group_id
-	input.principal.role = "client"
+	input.principal.role in roles.operators
 	input.resource.id
-	clause := {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	}
+	input.row.group_id = input.principal.group_id
 }
 
-
-# Admins should be able to delete instances that belong to their clients
+################ Approve
+# Operators should be able to approve the instance requests of the users of their group
 allow if {
-	input.action = "delete"
+	input.action = "approve"
 	input.principal.id
-	input.principal.group_id
-	input.principal.role = "admin"
-	input.resource.id
+	input.principal.role in roles.operators
+	input.principal.group_id = input.resource.group_id
 }
 
-partial := clause if {
-	input.action = "delete"
-	input.principal.id
-	input.principal.group_id
-	input.principal.role = "admin"
-	input.resource.id
-	clause := {
-		"condition": "user_id in (select id from users where group_id = ?)",
-		"values": [input.principal.group_id],
-	}
+# Clients' own instances are approved, unless their group requires approval
+allow if {
+	input.action = "approve"
+	input.principal.id = input.resource.user_id
+	input.principal.group_id = input.resource.group_id
+	input.principal.role = "client"
+	input.resource.require_approval = false
 }
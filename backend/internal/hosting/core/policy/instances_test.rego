@@ -1,21 +1,11 @@
 package hosting_test.instances
 
 import data.hosting.instances.allow
-import data.hosting.instances.partial
-
-nil_partial := {
-	"condition": "",
-	"values": [],
-}
 
 test_default_allow if {
 	allow == false
 }
 
-test_default_partial if {
-	partial == nil_partial
-}
-
 ############# Action: create
 
 test_clients_should_be_able_to_create_instance if {
@@ -79,12 +69,9 @@ test_clients_should_be_able_to_get_their_instance if {
 		}
 	}
 
-	allow with input as request
-
-	partial = {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id, "client_ids": []}})
+	allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": [request.principal.id]}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": []}})
 }
 
 ############# Action: list
@@ -98,12 +85,9 @@ test_clients_should_be_able_to_list_their_instance if {
 		"action": "list"
 	}
 
-	allow with input as request
-
-	partial = {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id, "client_ids": []}})
+	allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": [request.principal.id]}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": []}})
 }
 
 test_admins_should_be_able_to_list_their_client_instances if {
@@ -116,12 +100,8 @@ test_admins_should_be_able_to_list_their_client_instances if {
 		"action": "list",
 	}
 
-	allow with input as request
-
-	partial = {
-		"condition": "group_id = ?",
-		"values": [input.principal.group_id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
 }
 
 
@@ -139,12 +119,8 @@ test_clients_should_be_able_to_delete_their_instance if {
 		}
 	}
 
-	allow with input as request
-
-	partial = {
-		"condition": "user_id = ?",
-		"values": [input.principal.id],
-	} with input as request
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
 }
 
 
@@ -161,10 +137,180 @@ test_clients_should_be_able_to_delete_their_clients_instances if {
 		}
 	}
 
-	allow with input as request
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+############# Action: update
+test_clients_should_be_able_to_rotate_their_instance if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "update",
+		"resource": {
+			"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"
+		}
+	}
+
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id, "client_ids": []}})
+	allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": [request.principal.id]}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000", "client_ids": []}})
+}
+
+test_admins_should_be_able_to_rotate_their_clients_instances if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"
+		}
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_groupless_users_should_not_be_able_to_rotate_instances if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"role": "client",
+		},
+		"action": "update",
+		"resource": {
+			"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"
+		}
+	}
+
+	not allow with input as request
+}
+
+############# Action: approve
+approve_request(role, require_approval) := {
+	"principal": {
+		"id": "11000000-0000-0000-0000-000000000000",
+		"group_id": "00000000-0000-0000-0000-000000000011",
+		"role": role,
+	},
+	"action": "approve",
+	"resource": {
+		"user_id": "11000000-0000-0000-0000-000000000000",
+		"group_id": "00000000-0000-0000-0000-000000000011",
+		"require_approval": require_approval,
+	},
+}
+
+test_clients_should_not_need_approval_by_default if {
+	allow with input as approve_request("client", false)
+}
+
+test_clients_should_not_approve_their_own_instance_if_required if {
+	not allow with input as approve_request("client", true)
+}
+
+test_admins_should_be_able_to_approve_instances_of_their_group if {
+	allow with input as approve_request("admin", true)
+}
+
+test_admins_should_not_be_able_to_approve_instances_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "approve",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000022",
+			"require_approval": true,
+		},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_approve_instances_of_others if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "approve",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"require_approval": false,
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Roles
+
+test_staff_should_be_able_to_see_instances_of_their_group if {
+	every role in ["owner", "admin", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+			"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-000000000011", "client_ids": []},
+		}
+
+		allow with input as object.union(request, {"action": "get"})
+		allow with input as object.union(request, {"action": "list"})
+		not allow with input as object.union(request, {"action": "get", "row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+	}
+}
+
+test_operators_should_be_able_to_manage_instances_of_their_group if {
+	every role in ["owner", "operator"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd", "group_id": "00000000-0000-0000-0000-000000000011"},
+			"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-000000000011", "client_ids": []},
+		}
+
+		allow with input as object.union(request, {"action": "update"})
+		allow with input as object.union(request, {"action": "delete"})
+		allow with input as object.union(request, {"action": "approve"})
+	}
+}
+
+test_viewers_should_not_be_able_to_manage_instances if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "viewer",
+		},
+		"resource": {
+			"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd",
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"require_approval": false,
+		},
+		"row": {"user_id": "22000000-0000-0000-0000-000000000000", "group_id": "00000000-0000-0000-0000-000000000011", "client_ids": []},
+	}
 
-	partial := {
-		"condition": "user_id = any (select id from users where group_id = ?)",
-		"values": [input.principal.group_id],
-	} with input as request
+	not allow with input as object.union(request, {"action": "create"})
+	not allow with input as object.union(request, {"action": "update"})
+	not allow with input as object.union(request, {"action": "delete"})
+	not allow with input as object.union(request, {"action": "approve"})
 }
@@ -0,0 +1,15 @@
+package hosting.notifications
+
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ List
+# Users should be able to see their own notifications
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.role
+	input.row.user_id = input.principal.id
+}
@@ -0,0 +1,75 @@
+package hosting_test.notifications
+
+import data.hosting.notifications.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: list
+
+test_clients_should_be_able_to_list_their_notifications if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+	}
+
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
+}
+
+test_admins_should_only_list_their_own_notifications if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+	}
+
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
+}
+
+test_anonymous_users_should_not_be_able_to_list_notifications if {
+	request := {
+		"principal": {"role": "client"},
+		"action": "list",
+	}
+
+	not allow with input as request
+}
+
+test_users_should_not_be_able_to_create_notifications if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "create",
+	}
+
+	not allow with input as request
+}
+
+test_every_role_should_only_list_their_own_notifications if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "list",
+		}
+
+		allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+		not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
+	}
+}
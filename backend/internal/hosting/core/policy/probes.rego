@@ -0,0 +1,26 @@
+package hosting.probes
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Create
+# Users should be able to report the reachability of instances. The
+# instances in the reports are authorized separately.
+allow if {
+	input.action = "create"
+	input.principal.id
+	input.principal.group_id
+}
+
+################ List
+# Staff should be able to see the reports on their group instances
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.row.group_id = input.principal.group_id
+}
@@ -0,0 +1,93 @@
+package hosting_test.probes
+
+import data.hosting.probes.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: create
+
+test_clients_should_be_able_to_report if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "create",
+	}
+
+	allow with input as request
+}
+
+test_groupless_users_should_not_be_able_to_report if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"role": "client",
+		},
+		"action": "create",
+	}
+
+	not allow with input as request
+}
+
+############# Action: list
+
+test_admins_should_be_able_to_list_reports_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_clients_should_not_be_able_to_list_reports if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+	}
+
+	not allow with input as request
+}
+
+test_every_role_should_be_able_to_report if {
+	every role in ["owner", "admin", "operator", "viewer", "client"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "create",
+		}
+
+		allow with input as request
+	}
+}
+
+test_staff_should_be_able_to_list_reports_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "list",
+		}
+
+		allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	}
+}
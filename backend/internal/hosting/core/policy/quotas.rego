@@ -0,0 +1,48 @@
+package hosting.quotas
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Get
+# Users should be able to see their own quota
+allow if {
+	input.action = "get"
+	input.principal.id = input.resource.user_id
+}
+
+# Staff should be able to see the quotas of the users of their group
+allow if {
+	input.action = "get"
+	input.principal.role in roles.staff
+	input.principal.group_id = input.resource.group_id
+}
+
+################ Update
+# Managers should be able to change the quotas of the users of their group,
+# and lift their suspension.
+allow if {
+	input.action = "update"
+	input.principal.role in roles.managers
+	input.principal.group_id = input.resource.group_id
+}
+
+################ Use
+# Users are suspended once they use more than their quota in a period.
+# A zero quota is unlimited.
+allow if {
+	input.action = "use"
+	input.principal.id = input.resource.user_id
+	within_traffic_quota
+	within_active_days_quota
+}
+
+within_traffic_quota if input.resource.quota.traffic_bytes == 0
+
+within_traffic_quota if input.resource.traffic_bytes <= input.resource.quota.traffic_bytes
+
+within_active_days_quota if input.resource.quota.active_days == 0
+
+within_active_days_quota if input.resource.active_days <= input.resource.quota.active_days
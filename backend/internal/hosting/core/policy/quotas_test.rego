@@ -0,0 +1,172 @@
+package hosting_test.quotas
+
+import data.hosting.quotas.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: get
+
+test_clients_should_be_able_to_get_their_own_quota if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_clients_should_not_be_able_to_get_quota_of_others if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_admins_should_not_be_able_to_get_quota_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000022",
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Action: update
+
+test_admins_should_be_able_to_update_quota_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_clients_should_not_be_able_to_update_their_own_quota if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Action: use
+
+use_request(traffic, days, quota_traffic, quota_days) := {
+	"principal": {
+		"id": "11000000-0000-0000-0000-000000000000",
+		"group_id": "00000000-0000-0000-0000-000000000011",
+		"role": "client",
+	},
+	"action": "use",
+	"resource": {
+		"user_id": "11000000-0000-0000-0000-000000000000",
+		"group_id": "00000000-0000-0000-0000-000000000011",
+		"traffic_bytes": traffic,
+		"active_days": days,
+		"quota": {
+			"traffic_bytes": quota_traffic,
+			"active_days": quota_days,
+		},
+	},
+}
+
+test_users_within_their_quota_should_not_be_suspended if {
+	allow with input as use_request(100, 3, 100, 3)
+}
+
+test_users_without_quota_should_not_be_suspended if {
+	allow with input as use_request(1000000, 31, 0, 0)
+}
+
+test_users_over_their_traffic_quota_should_be_suspended if {
+	not allow with input as use_request(101, 3, 100, 0)
+}
+
+test_users_over_their_active_days_quota_should_be_suspended if {
+	not allow with input as use_request(100, 4, 0, 3)
+}
+
+############# Roles
+
+test_staff_should_be_able_to_get_quotas_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "get",
+			"resource": {
+				"user_id": "22000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+			},
+		}
+
+		allow with input as request
+	}
+}
+
+test_only_managers_should_be_able_to_update_quotas if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as object.union(request, {"principal": {"role": "owner"}})
+	not allow with input as object.union(request, {"principal": {"role": "operator"}})
+	not allow with input as object.union(request, {"principal": {"role": "viewer"}})
+}
@@ -0,0 +1,29 @@
+package hosting.ratelimits
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Get
+# Users should be able to see their own rate limit
+allow if {
+	input.action = "get"
+	input.principal.id = input.resource.user_id
+}
+
+# Staff should be able to see the rate limits of the users of their group
+allow if {
+	input.action = "get"
+	input.principal.role in roles.staff
+	input.principal.group_id = input.resource.group_id
+}
+
+################ Update
+# Managers should be able to override the rate limits of the users of their group
+allow if {
+	input.action = "update"
+	input.principal.role in roles.managers
+	input.principal.group_id = input.resource.group_id
+}
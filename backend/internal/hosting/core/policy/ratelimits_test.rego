@@ -0,0 +1,135 @@
+package hosting_test.ratelimits
+
+import data.hosting.ratelimits.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: get
+
+test_clients_should_be_able_to_get_their_own_rate_limit if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_clients_should_not_be_able_to_get_rate_limit_of_others if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	not allow with input as request
+}
+
+test_admins_should_not_be_able_to_get_rate_limit_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000022",
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Action: update
+
+test_admins_should_be_able_to_update_rate_limit_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as request
+}
+
+test_clients_should_not_be_able_to_update_their_own_rate_limit if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	not allow with input as request
+}
+
+############# Roles
+
+test_staff_should_be_able_to_get_rate_limits_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "get",
+			"resource": {
+				"user_id": "22000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+			},
+		}
+
+		allow with input as request
+	}
+}
+
+test_only_managers_should_be_able_to_update_rate_limits if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+		"action": "update",
+		"resource": {
+			"user_id": "22000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+		},
+	}
+
+	allow with input as object.union(request, {"principal": {"role": "owner"}})
+	not allow with input as object.union(request, {"principal": {"role": "operator"}})
+	not allow with input as object.union(request, {"principal": {"role": "viewer"}})
+}
@@ -0,0 +1,51 @@
+package hosting.rulesets
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+# Routing rule sets are owned by groups, and only operators of the
+# group can manage them. Clients are not aware of rule sets.
+
+################ Create
+# Operators should be able to create rule sets in their group
+allow if {
+	input.action = "create"
+	input.principal.id
+	input.principal.group_id = input.resource.group_id
+	input.principal.role in roles.operators
+}
+
+################ Get
+# Staff should be able to see rule sets of their group
+allow if {
+	input.action = "get"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.resource.id
+	input.row.group_id = input.principal.group_id
+}
+
+################ Update, Delete
+# Operators should be able to manage rule sets of their group
+allow if {
+	input.action in ["update", "delete"]
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.operators
+	input.resource.id
+	input.row.group_id = input.principal.group_id
+}
+
+################ List
+# Staff should be able to list rule sets of their group
+allow if {
+	input.action = "list"
+	input.principal.id
+	input.principal.group_id
+	input.principal.role in roles.staff
+	input.row.group_id = input.principal.group_id
+}
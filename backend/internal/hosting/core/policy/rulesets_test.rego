@@ -0,0 +1,165 @@
+package hosting_test.rulesets
+
+import data.hosting.rulesets.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: create
+
+test_admins_should_be_able_to_create_rule_sets_in_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "create",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as request
+}
+
+test_admins_should_not_be_able_to_create_rule_sets_in_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "create",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000022"},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_create_rule_sets if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "create",
+		"resource": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	not allow with input as request
+}
+
+############# Action: get, update, delete
+
+test_admins_should_be_able_to_manage_rule_sets_of_their_group if {
+	every action in ["get", "update", "delete"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": "admin",
+			},
+			"action": action,
+			"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+		}
+
+		allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+		not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+	}
+}
+
+test_clients_should_not_be_able_to_manage_rule_sets if {
+	every action in ["get", "update", "delete"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": "client",
+			},
+			"action": action,
+			"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+		}
+
+		not allow with input as request
+	}
+}
+
+test_groupless_admins_should_not_be_able_to_manage_rule_sets if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd"},
+	}
+
+	not allow with input as request
+}
+
+############# Action: list
+
+test_admins_should_be_able_to_list_rule_sets_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_clients_should_not_be_able_to_list_rule_sets if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+	}
+
+	not allow with input as request
+}
+
+############# Roles
+
+test_operators_should_be_able_to_manage_rule_sets_of_their_group if {
+	every role in ["owner", "operator"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"action": "update",
+			"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd", "group_id": "00000000-0000-0000-0000-000000000011"},
+		}
+
+		allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+		allow with input as object.union(request, {"action": "create"})
+	}
+}
+
+test_viewers_should_only_see_rule_sets_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "viewer",
+		},
+		"resource": {"id": "18b68320-fd20-4ec5-b84c-5f678cdf46fd", "group_id": "00000000-0000-0000-0000-000000000011"},
+		"row": {"group_id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"action": "get"})
+	allow with input as object.union(request, {"action": "list"})
+	not allow with input as object.union(request, {"action": "create"})
+	not allow with input as object.union(request, {"action": "update"})
+	not allow with input as object.union(request, {"action": "delete"})
+}
@@ -0,0 +1,33 @@
+package hosting.usage
+
+import data.authz.roles
+import rego.v1
+
+# Default deny
+default allow := false
+
+################ Get
+# Users should be able to see their own usage
+allow if {
+	input.action = "get"
+	input.principal.id = input.resource.id
+	input.row.user_id = input.principal.id
+}
+
+# Staff should be able to see the usage of the users of their group
+allow if {
+	input.action = "get"
+	input.principal.role in roles.staff
+	input.principal.group_id
+	input.resource.id
+	input.row.group_id = input.principal.group_id
+}
+
+################ List
+# Staff should be able to summarize the usage of their own group
+allow if {
+	input.action = "list"
+	input.principal.role in roles.staff
+	input.principal.group_id = input.resource.id
+	input.row.group_id = input.principal.group_id
+}
@@ -0,0 +1,115 @@
+package hosting_test.usage
+
+import data.hosting.usage.allow
+
+test_default_allow if {
+	allow == false
+}
+
+############# Action: get
+
+test_clients_should_be_able_to_get_their_own_usage if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {"id": "11000000-0000-0000-0000-000000000000"},
+	}
+
+	allow with input as object.union(request, {"row": {"user_id": request.principal.id}})
+	not allow with input as object.union(request, {"row": {"user_id": "99000000-0000-0000-0000-000000000000"}})
+}
+
+test_clients_should_not_be_able_to_get_usage_of_others if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "get",
+		"resource": {"id": "22000000-0000-0000-0000-000000000000"},
+	}
+
+	not allow with input as request
+}
+
+test_admins_should_be_able_to_get_usage_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "get",
+		"resource": {"id": "22000000-0000-0000-0000-000000000000"},
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+############# Action: list
+
+test_admins_should_be_able_to_summarize_usage_of_their_group if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	allow with input as object.union(request, {"row": {"group_id": request.principal.group_id}})
+	not allow with input as object.union(request, {"row": {"group_id": "00000000-0000-0000-0000-999999999999"}})
+}
+
+test_admins_should_not_be_able_to_summarize_usage_of_other_groups if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "admin",
+		},
+		"action": "list",
+		"resource": {"id": "00000000-0000-0000-0000-000000000022"},
+	}
+
+	not allow with input as request
+}
+
+test_clients_should_not_be_able_to_summarize_usage if {
+	request := {
+		"principal": {
+			"id": "11000000-0000-0000-0000-000000000000",
+			"group_id": "00000000-0000-0000-0000-000000000011",
+			"role": "client",
+		},
+		"action": "list",
+		"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+	}
+
+	not allow with input as request
+}
+
+test_staff_should_be_able_to_see_usage_of_their_group if {
+	every role in ["owner", "operator", "viewer"] {
+		request := {
+			"principal": {
+				"id": "11000000-0000-0000-0000-000000000000",
+				"group_id": "00000000-0000-0000-0000-000000000011",
+				"role": role,
+			},
+			"resource": {"id": "00000000-0000-0000-0000-000000000011"},
+			"row": {"group_id": "00000000-0000-0000-0000-000000000011"},
+		}
+
+		allow with input as object.union(request, {"action": "get"})
+		allow with input as object.union(request, {"action": "list"})
+	}
+}
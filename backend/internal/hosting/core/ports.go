@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"vpainless/internal/pkg/authz"
 	"vpainless/internal/pkg/db"
@@ -13,6 +14,10 @@ type VPSProvider interface {
 	DeleteInstance(ctx context.Context, apikey string, id InstanceID) error
 	CreateSSHKey(ctx context.Context, apikey string, publickey []byte) (SSHKeyID, error)
 	CreateStartupScript(ctx context.Context, apikey string, content string) (StartUpScriptID, error)
+	// Regions returns the default regions to create instances in.
+	Regions() []string
+	// PlanPrice returns the price of the plan instances are created with.
+	PlanPrice(ctx context.Context, apikey string) (*PlanPrice, error)
 }
 
 type Repository interface {
@@ -20,22 +25,149 @@ type Repository interface {
 	userRepository
 	groupRepository
 	instanceRepository
+	ruleSetRepository
+	clientRepository
+	healthRepository
+	probeRepository
+	renewalRepository
+	notificationRepository
+	usageRepository
+	quotaRepository
+	costRepository
+	rateLimitRepository
+	instanceRequestRepository
+	auditRepository
 }
 
 type userRepository interface {
 	GetUser(ctx context.Context, id UserID) (*User, error)
 	SaveUser(ctx context.Context, user *User) (*User, error)
+	// GetMember returns the user as a member of the group, with their role in it.
+	// It returns ErrNotFound if the user is not known to act in the group.
+	GetMember(ctx context.Context, id UserID, group GroupID) (*User, error)
+	// SaveMember saves the user as a member of their group, with their role in it.
+	SaveMember(ctx context.Context, user *User) error
 }
 
 type groupRepository interface {
 	GetGroup(ctx context.Context, id GroupID) (*Group, error)
 	SaveGroup(ctx context.Context, group *Group) (*Group, error)
+	SaveGroupSettings(ctx context.Context, id GroupID, settings GroupSettings) error
+	ListDecisionLogGroups(ctx context.Context) ([]GroupID, error)
 }
 
 type instanceRepository interface {
 	GetInstance(ctx context.Context, id InstanceID, partial authz.Clause) (*Instance, error)
 	DeleteInstance(ctx context.Context, id InstanceID, partial authz.Clause) error
-	FindInstance(ctx context.Context, id UserID) (*Instance, error)
+	// FindInstance returns the instance of a user in a group, ignoring the
+	// replacements that are not swapped in yet.
+	FindInstance(ctx context.Context, id UserID, group GroupID) (*Instance, error)
 	ListInstances(ctx context.Context, partial authz.Clause) ([]*Instance, error)
 	SaveInstance(ctx context.Context, instance *Instance) (*Instance, error)
+	// FindSharedInstance returns a healthy shared instance of the group
+	// with less than max active clients.
+	FindSharedInstance(ctx context.Context, id GroupID, max int) (*Instance, error)
+}
+
+type ruleSetRepository interface {
+	GetRuleSet(ctx context.Context, id RuleSetID, partial authz.Clause) (*RuleSet, error)
+	ListRuleSets(ctx context.Context, partial authz.Clause) ([]*RuleSet, error)
+	// FindRuleSets returns the enabled rule sets of a group.
+	FindRuleSets(ctx context.Context, id GroupID) ([]*RuleSet, error)
+	SaveRuleSet(ctx context.Context, ruleset *RuleSet) (*RuleSet, error)
+	DeleteRuleSet(ctx context.Context, id RuleSetID, partial authz.Clause) error
+}
+
+type clientRepository interface {
+	// ListClients returns the active clients of an instance.
+	ListClients(ctx context.Context, id InstanceID, partial authz.Clause) ([]*InstanceClient, error)
+	// FindClient returns the active client of a user on the instances of a group.
+	FindClient(ctx context.Context, id UserID, group GroupID) (*InstanceClient, error)
+	SaveClient(ctx context.Context, client *InstanceClient) (*InstanceClient, error)
+}
+
+type healthRepository interface {
+	SaveHealthCheck(ctx context.Context, check *HealthCheck) error
+	// ListHealthChecks returns the latest health checks of an instance, newest first.
+	ListHealthChecks(ctx context.Context, id InstanceID, limit int) ([]*HealthCheck, error)
+	// DeleteHealthChecks deletes the health checks older than the given time.
+	DeleteHealthChecks(ctx context.Context, before time.Time) error
+}
+
+type probeRepository interface {
+	SaveProbeReport(ctx context.Context, report *ProbeReport) error
+	// SummarizeProbeReports aggregates the reports since the given time, per instance.
+	SummarizeProbeReports(ctx context.Context, since time.Time, partial authz.Clause) ([]*ProbeSummary, error)
+	// SummarizeProbeRanges aggregates the reports on IPv4 addresses since the given
+	// time, per /24 range. Reporters are counted once per range.
+	SummarizeProbeRanges(ctx context.Context, since time.Time, partial authz.Clause) ([]*ProbeSummary, error)
+}
+
+type renewalRepository interface {
+	// RetireInstance schedules the deletion of the server of a deleted instance.
+	RetireInstance(ctx context.Context, id InstanceID, at time.Time) error
+	// ListRetiredInstances returns the deleted instances whose server is due for deletion.
+	ListRetiredInstances(ctx context.Context, before time.Time) ([]*Instance, error)
+	ClearRetirement(ctx context.Context, id InstanceID) error
+	SaveRenewal(ctx context.Context, renewal *Renewal) error
+	// CountRenewals returns the number of renewals of a group since the given time.
+	CountRenewals(ctx context.Context, id GroupID, since time.Time) (int, error)
+}
+
+type notificationRepository interface {
+	SaveNotification(ctx context.Context, notification *Notification) error
+	// ListNotifications returns the latest notifications, newest first.
+	ListNotifications(ctx context.Context, partial authz.Clause, limit int) ([]*Notification, error)
+}
+
+type usageRepository interface {
+	SaveUsage(ctx context.Context, usage *Usage) error
+	// ListUsageCounters returns the cumulative counters last read from an instance, per user.
+	ListUsageCounters(ctx context.Context, id InstanceID) (map[UserID]*Usage, error)
+	// SaveUsageCounter keeps the cumulative counters read from an instance for a user.
+	SaveUsageCounter(ctx context.Context, counter *Usage) error
+	// ListDailyUsage returns the traffic of a user since the given time, per day and instance.
+	ListDailyUsage(ctx context.Context, id UserID, since time.Time, partial authz.Clause) ([]*Usage, error)
+	// SummarizeUsage returns the traffic of the users of a group since the given time, per user.
+	SummarizeUsage(ctx context.Context, id GroupID, since time.Time, partial authz.Clause) ([]*Usage, error)
+	// SumUsage returns the traffic of a user since the given time, and the number of days with traffic.
+	SumUsage(ctx context.Context, id UserID, since time.Time) (int64, int, error)
+}
+
+type quotaRepository interface {
+	// GetUserQuota returns the quota of a user, which is empty if the user has none.
+	GetUserQuota(ctx context.Context, id UserID) (*UserQuota, error)
+	SaveUserQuota(ctx context.Context, quota *UserQuota) error
+	// ListExpiredSuspensions returns the quotas of the users suspended until before the given time.
+	ListExpiredSuspensions(ctx context.Context, before time.Time) ([]*UserQuota, error)
+}
+
+type costRepository interface {
+	// ListInstanceCosts returns the billing records of the instances of a group,
+	// including the deleted ones, that ran between start and end.
+	ListInstanceCosts(ctx context.Context, id GroupID, start, end time.Time) ([]*InstanceCost, error)
+}
+
+type rateLimitRepository interface {
+	// GetBucket returns ErrNotFound if the bucket is not used yet.
+	GetBucket(ctx context.Context, key string) (*Bucket, error)
+	SaveBucket(ctx context.Context, bucket *Bucket) error
+	// GetRateLimitOverride returns ErrNotFound if the rate limit of the user is not overridden.
+	GetRateLimitOverride(ctx context.Context, id UserID) (*RateLimit, error)
+	// SaveRateLimitOverride overrides the rate limit of a user, or removes the override if nil.
+	SaveRateLimitOverride(ctx context.Context, id UserID, limit *RateLimit) error
+}
+
+type instanceRequestRepository interface {
+	GetInstanceRequest(ctx context.Context, id InstanceRequestID) (*InstanceRequest, error)
+	// FindPendingRequest returns ErrNotFound if the user has no pending request
+	// in the group.
+	FindPendingRequest(ctx context.Context, id UserID, group GroupID) (*InstanceRequest, error)
+	ListPendingRequests(ctx context.Context, id GroupID) ([]*InstanceRequest, error)
+	SaveInstanceRequest(ctx context.Context, request *InstanceRequest) error
+}
+
+type auditRepository interface {
+	SaveAuditEntry(ctx context.Context, entry *AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter AuditFilter, partial authz.Clause) ([]*AuditEntry, error)
 }
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const (
+	ResourceProbes = "probes"
+
+	// blockedWindow is how far back the probe reports are aggregated.
+	blockedWindow = time.Hour
+	// DefaultMinProbeReporters is the number of distinct reporters needed to decide
+	// on blocking, unless changed by the group admins, so a single flaky, or
+	// malicious, reporter can not block an instance.
+	DefaultMinProbeReporters = 3
+	// blockedFailureRatio is the ratio of the reporters with failed reports that
+	// marks an instance blocked.
+	blockedFailureRatio = 0.8
+	// rangeMaskBits groups the IPv4 addresses into ranges. Censors tend
+	// to block whole ranges of the providers. The storage groups the reports
+	// by the same ranges.
+	rangeMaskBits = 24
+)
+
+// ProbeReport is the reachability of an instance, as seen by an in-country probe agent.
+type ProbeReport struct {
+	InstanceID InstanceID
+	ReporterID UserID
+	// IP of the instance when probed. It is taken from the instance, not the
+	// reporter, so reports are attributed to the right range after renewals.
+	IP         net.IP
+	ReportedAt time.Time
+	TCP        bool
+	TLS        bool
+	Latency    time.Duration
+	Error      string
+}
+
+// Reachable reports if clients can connect to the instance.
+func (r *ProbeReport) Reachable() bool {
+	return r.TCP && r.TLS
+}
+
+// probeRateLimit limits the submissions of each probe agent.
+var probeRateLimit = RateLimit{Burst: 10, PerHour: 60}
+
+// ProbeSummary aggregates the recent probe reports of an instance, or a range.
+type ProbeSummary struct {
+	InstanceID InstanceID
+	// Range is set for the summaries of ranges.
+	Range   *net.IPNet
+	IP      net.IP
+	Reports int
+	Failed  int
+	// Reporters is the number of distinct reporters, and FailedReporters the
+	// number of them with at least one failed report.
+	Reporters       int
+	FailedReporters int
+	// Blocked reports if the summary is a signal of blocking, with the minimum
+	// reporters of the group.
+	Blocked bool
+}
+
+// decided reports if there are enough reporters to decide on blocking.
+func (s *ProbeSummary) decided(minReporters int) bool {
+	return s.Reporters >= minReporters
+}
+
+// blocked reports if the summary is a signal of blocking. Each reporter counts
+// once, however many reports they send.
+func (s *ProbeSummary) blocked(minReporters int) bool {
+	return s.decided(minReporters) && float64(s.FailedReporters) >= blockedFailureRatio*float64(s.Reporters)
+}
+
+// SubmitProbeReports saves the reports of a probe agent, and marks the instances as
+// blocked, or unblocked, based on the recent reports. Agents can only report on
+// the instances they can see, and their submissions are rate limited.
+func (s *Service) SubmitProbeReports(ctx context.Context, reports []*ProbeReport) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Create, authz.Resource{Group: ResourceProbes})
+	if err != nil || !policy.Allow {
+		return ErrUnauthorized
+	}
+
+	if len(reports) == 0 {
+		return errors.Join(ErrBadRequest, errors.New("no probe reports"))
+	}
+
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		now := time.Now()
+		if err := s.takeTokens(ctx, now, []string{"probe:" + principal.ID.String()}, []RateLimit{probeRateLimit}); err != nil {
+			return err
+		}
+
+		var instances []*Instance
+		for _, report := range reports {
+			instancePolicy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceInstances, report.InstanceID.UUID))
+			if err != nil || !instancePolicy.Allow {
+				return ErrUnauthorized
+			}
+
+			instance, err := s.repo.GetInstance(ctx, report.InstanceID, instancePolicy.Partial)
+			if err != nil {
+				return err
+			}
+
+			report.ReporterID = UserID{principal.ID}
+			report.IP = instance.IP
+			report.ReportedAt = now
+			if err := s.repo.SaveProbeReport(ctx, report); err != nil {
+				return err
+			}
+
+			if !slices.ContainsFunc(instances, func(i *Instance) bool { return i.ID == instance.ID }) {
+				instances = append(instances, instance)
+			}
+		}
+
+		for _, instance := range instances {
+			if err := s.updateBlocked(ctx, instance, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateBlocked marks the instance as blocked, or unblocks it, based on its recent
+// reports on its current IP. Instances that are not set up, or failed, are left as
+// they are. Both need the minimum reporters of the group.
+func (s *Service) updateBlocked(ctx context.Context, instance *Instance, now time.Time) error {
+	group, err := s.repo.GetGroup(ctx, instance.GroupID)
+	if err != nil {
+		return errors.Join(ErrGroups, err)
+	}
+
+	summaries, err := s.repo.SummarizeProbeReports(ctx, now.Add(-blockedWindow), authz.Clause{
+		Condition: "r.instance_id = ? and r.ip = ?",
+		Values:    []any{instance.ID, instance.IP.String()},
+	})
+	if err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	minReporters := group.Settings.MinProbeReporters
+	blocked := summaries[0].blocked(minReporters)
+	status := instance.Status
+	switch {
+	case blocked && (status == StatusOK || status == StatusDegraded):
+		status = StatusBlocked
+	case !blocked && status == StatusBlocked && summaries[0].decided(minReporters):
+		status = StatusOK
+	default:
+		return nil
+	}
+
+	slog.InfoContext(ctx, "core: instance reachability changed", "instance_id", instance.ID, "from", instance.Status, "to", status)
+	instance.Status = status
+	_, err = s.repo.SaveInstance(ctx, instance)
+	return err
+}
+
+// ListBlockedRanges aggregates the recent probe reports of the instances by
+// their IP range, with the minimum reporters of the group of the principal. It
+// helps admins avoid the ranges that are blocked.
+func (s *Service) ListBlockedRanges(ctx context.Context) ([]*ProbeSummary, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.Resource{Group: ResourceProbes})
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	group, err := s.repo.GetGroup(ctx, GroupID{principal.GroupID})
+	if err != nil {
+		return nil, errors.Join(ErrGroups, err)
+	}
+
+	summaries, err := s.repo.SummarizeProbeRanges(ctx, time.Now().Add(-blockedWindow), policy.Partial)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range summaries {
+		summary.Blocked = summary.blocked(group.Settings.MinProbeReporters)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProbeRepository returns the group and the summary it is given. The rest of
+// the repository is not used by the blocking, and panics if called.
+type fakeProbeRepository struct {
+	Repository
+
+	group   *Group
+	summary *ProbeSummary
+	saved   []*Instance
+}
+
+func (r *fakeProbeRepository) GetGroup(context.Context, GroupID) (*Group, error) {
+	return r.group, nil
+}
+
+func (r *fakeProbeRepository) SummarizeProbeReports(context.Context, time.Time, authz.Clause) ([]*ProbeSummary, error) {
+	return []*ProbeSummary{r.summary}, nil
+}
+
+func (r *fakeProbeRepository) SaveInstance(_ context.Context, instance *Instance) (*Instance, error) {
+	r.saved = append(r.saved, instance)
+	return instance, nil
+}
+
+func TestUpdateBlocked(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	instance := &Instance{
+		ID:     InstanceID{uuid.Must(uuid.NewV4())},
+		IP:     net.ParseIP("192.168.0.1"),
+		Status: StatusOK,
+	}
+
+	repo := &fakeProbeRepository{
+		group:   &Group{Settings: GroupSettings{MinProbeReporters: DefaultMinProbeReporters}},
+		summary: &ProbeSummary{Reports: 10, Failed: 10, Reporters: 1, FailedReporters: 1},
+	}
+	s := &Service{repo: repo}
+
+	require.NoError(t, s.updateBlocked(ctx, instance, now))
+	require.Equal(t, StatusOK, instance.Status, "should not block on a single reporter by default")
+	require.Empty(t, repo.saved)
+
+	// Groups with a single agent in the country decide on its reports alone.
+	repo.group.Settings.MinProbeReporters = 1
+	require.NoError(t, s.updateBlocked(ctx, instance, now))
+	require.Equal(t, StatusBlocked, instance.Status, "should block on the failures of the single reporter")
+	require.Len(t, repo.saved, 1)
+
+	repo.summary = &ProbeSummary{Reports: 2, Failed: 0, Reporters: 1, FailedReporters: 0}
+	require.NoError(t, s.updateBlocked(ctx, instance, now))
+	require.Equal(t, StatusOK, instance.Status, "should unblock once the single reporter connects")
+	require.Len(t, repo.saved, 2)
+}
+
+func TestProbeSummaryBlocked(t *testing.T) {
+	summary := &ProbeSummary{Reporters: 5, FailedReporters: 4}
+	require.True(t, summary.blocked(3), "should be blocked when most reporters fail")
+	require.False(t, summary.blocked(6), "should not decide with fewer reporters than the minimum")
+
+	summary = &ProbeSummary{Reporters: 5, FailedReporters: 3}
+	require.False(t, summary.blocked(3), "should not be blocked when enough reporters connect")
+}
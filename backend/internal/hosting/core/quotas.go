@@ -0,0 +1,394 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	ResourceQuotas = "quotas"
+
+	// NotificationSuspended is sent when the user is suspended for exceeding their quota.
+	NotificationSuspended NotificationKind = "suspended"
+
+	// verbUse is the action of consuming a quota. The quota policy allows it
+	// as long as the usage is within the quota.
+	verbUse authz.Verb = "use"
+)
+
+// Quota limits the traffic and the active days of a user in a quota period,
+// which is a calendar month in UTC. Zero is unlimited.
+type Quota struct {
+	TrafficBytes int64
+	ActiveDays   int
+}
+
+// UserQuota is the quota of a user, and the state of their suspension.
+type UserQuota struct {
+	UserID UserID
+	// Override replaces the quota of the group for the user, when set.
+	Override *Quota
+	// SuspendedUntil is the end of the period the user exceeded their quota in.
+	// Their clients are disabled on the instances until then.
+	SuspendedUntil *time.Time
+	// LiftedUntil is the end of the period an admin lifted the suspension of the
+	// user in. The quota is not enforced for the rest of the period.
+	LiftedUntil *time.Time
+}
+
+// Suspended reports whether the user is suspended at the given time.
+func (q *UserQuota) Suspended(now time.Time) bool {
+	return q.SuspendedUntil != nil && now.Before(*q.SuspendedUntil)
+}
+
+func (q *UserQuota) lifted(now time.Time) bool {
+	return q.LiftedUntil != nil && now.Before(*q.LiftedUntil)
+}
+
+// QuotaStatus is the quota of a user, along with their usage in the current period.
+type QuotaStatus struct {
+	UserQuota
+	// Quota is the quota in effect; the override of the user, or the quota of their group.
+	Quota        Quota
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	TrafficBytes int64
+	ActiveDays   int
+}
+
+// quotaPeriod returns the bounds of the quota period the given time is in.
+func quotaPeriod(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// GetQuota returns the quota of a user and their usage in the current period.
+func (s *Service) GetQuota(ctx context.Context, id UserID) (*QuotaStatus, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var result *QuotaStatus
+	if err := s.repo.Transact(ctx, sql.LevelReadCommitted, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, GroupID{principal.GroupID})
+		if err != nil {
+			return err
+		}
+
+		if err := s.authorizeQuota(ctx, principal, authz.Get, user); err != nil {
+			return err
+		}
+
+		result, err = s.quotaStatus(ctx, user, time.Now())
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateQuota overrides the quota of the group for a user. A nil quota restores
+// the quota of the group. The quota is enforced on the next usage collection.
+func (s *Service) UpdateQuota(ctx context.Context, id UserID, quota *Quota) (*QuotaStatus, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if quota != nil && (quota.TrafficBytes < 0 || quota.ActiveDays < 0) {
+		return nil, errors.Join(ErrBadRequest, errors.New("quota should not be negative"))
+	}
+
+	var result *QuotaStatus
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, GroupID{principal.GroupID})
+		if err != nil {
+			return err
+		}
+
+		if err := s.authorizeQuota(ctx, principal, authz.Update, user); err != nil {
+			return err
+		}
+
+		userQuota, err := s.repo.GetUserQuota(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		userQuota.Override = quota
+		if err := s.repo.SaveUserQuota(ctx, userQuota); err != nil {
+			return err
+		}
+
+		result, err = s.quotaStatus(ctx, user, time.Now())
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LiftSuspension re-enables a suspended user. Their quota is not enforced for
+// the rest of the period.
+func (s *Service) LiftSuspension(ctx context.Context, id UserID) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, GroupID{principal.GroupID})
+		if err != nil {
+			return err
+		}
+
+		if err := s.authorizeQuota(ctx, principal, authz.Update, user); err != nil {
+			return err
+		}
+
+		quota, err := s.repo.GetUserQuota(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !quota.Suspended(now) {
+			return errors.Join(ErrBadRequest, errors.New("user is not suspended"))
+		}
+
+		_, end := quotaPeriod(now)
+		quota.SuspendedUntil = nil
+		quota.LiftedUntil = &end
+		if err := s.repo.SaveUserQuota(ctx, quota); err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "core: lifting suspension of user...", "user_id", id)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return s.reconfigureUserInstances(ctx, id)
+}
+
+func (s *Service) authorizeQuota(ctx context.Context, principal authz.Principal, verb authz.Verb, user *User) error {
+	policy, err := s.enforcer.Can(ctx, principal, verb, authz.ResourceFunc(func() (string, any) {
+		return ResourceQuotas, map[string]any{
+			"user_id":  user.ID,
+			"group_id": user.GroupID,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (s *Service) quotaStatus(ctx context.Context, user *User, now time.Time) (*QuotaStatus, error) {
+	userQuota, err := s.repo.GetUserQuota(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QuotaStatus{UserQuota: *userQuota}
+	if userQuota.Override != nil {
+		result.Quota = *userQuota.Override
+	} else {
+		group, err := s.repo.GetGroup(ctx, user.GroupID)
+		if err != nil {
+			return nil, errors.Join(ErrGroups, err)
+		}
+		result.Quota = group.Settings.Quota
+	}
+
+	result.PeriodStart, result.PeriodEnd = quotaPeriod(now)
+	result.TrafficBytes, result.ActiveDays, err = s.repo.SumUsage(ctx, user.ID, result.PeriodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// enforceQuotas suspends the users who exceeded their quota, and restores the ones
+// whose suspension ended with its period. The users are given along with the group of
+// the instance they used, whose quota applies unless the user has their own.
+func (s *Service) enforceQuotas(ctx context.Context, users []*User) {
+	now := time.Now()
+	expired, err := s.repo.ListExpiredSuspensions(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing expired suspensions", "error", err)
+	}
+
+	for _, quota := range expired {
+		if err := s.restoreUser(ctx, quota); err != nil {
+			slog.ErrorContext(ctx, "core: error restoring user", "user_id", quota.UserID, "error", err)
+		}
+	}
+
+	for _, user := range users {
+		if err := s.enforceQuota(ctx, user.ID, user.GroupID, now); err != nil {
+			slog.ErrorContext(ctx, "core: error enforcing quota", "user_id", user.ID, "group_id", user.GroupID, "error", err)
+		}
+	}
+}
+
+func (s *Service) restoreUser(ctx context.Context, quota *UserQuota) error {
+	quota.SuspendedUntil = nil
+	if err := s.repo.SaveUserQuota(ctx, quota); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "core: quota period ended, restoring user...", "user_id", quota.UserID)
+	return s.reconfigureUserInstances(ctx, quota.UserID)
+}
+
+// enforceQuota evaluates the quota policy of the group against the usage of the user in
+// the current period, and suspends the user until the end of the period if it is not
+// allowed. The instances of suspended users are reconfigured once the suspension is
+// committed.
+func (s *Service) enforceQuota(ctx context.Context, id UserID, group GroupID, now time.Time) error {
+	var instances []*Instance
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, group)
+		if err != nil {
+			return err
+		}
+
+		status, err := s.quotaStatus(ctx, user, now)
+		if err != nil {
+			return err
+		}
+
+		if status.Suspended(now) || status.lifted(now) {
+			return nil
+		}
+
+		principal := authz.Principal{ID: user.ID.UUID, GroupID: user.GroupID.UUID, Role: authz.Role(user.Role)}
+		policy, err := s.enforcer.Can(ctx, principal, verbUse, authz.ResourceFunc(func() (string, any) {
+			return ResourceQuotas, map[string]any{
+				"user_id":       user.ID,
+				"group_id":      user.GroupID,
+				"traffic_bytes": status.TrafficBytes,
+				"active_days":   status.ActiveDays,
+				"quota": map[string]any{
+					"traffic_bytes": status.Quota.TrafficBytes,
+					"active_days":   status.Quota.ActiveDays,
+				},
+			}
+		}))
+		if err != nil {
+			return fmt.Errorf("error evaluating quota policy: %w", err)
+		}
+		if policy.Allow {
+			return nil
+		}
+
+		status.UserQuota.SuspendedUntil = &status.PeriodEnd
+		if err := s.repo.SaveUserQuota(ctx, &status.UserQuota); err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "core: user exceeded quota, suspending...", "user_id", id,
+			"traffic_bytes", status.TrafficBytes, "active_days", status.ActiveDays)
+		instances, err = s.userInstances(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, instance := range instances {
+			if err := s.repo.SaveNotification(ctx, &Notification{
+				ID:         NotificationID{uuid.Must(uuid.NewV4())},
+				UserID:     id,
+				InstanceID: instance.ID,
+				Kind:       NotificationSuspended,
+				Message: fmt.Sprintf("You exceeded your quota, your access is suspended until %s.",
+					status.PeriodEnd.Format(time.DateOnly)),
+				CreatedAt: now,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		if err := s.reconfigureInstance(ctx, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconfigureUserInstances renders the config of the instances the user connects to
+// again, so their suspension is applied. Quotas are per user, across their groups.
+func (s *Service) reconfigureUserInstances(ctx context.Context, id UserID) error {
+	instances, err := s.userInstances(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		if err := s.reconfigureInstance(ctx, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userInstance returns the instance of the user in the group, or the shared instance
+// of the group they are a client of.
+func (s *Service) userInstance(ctx context.Context, id UserID, group GroupID) (*Instance, error) {
+	instance, err := s.repo.FindInstance(ctx, id, group)
+	if !errors.Is(err, ErrNotFound) {
+		return instance, err
+	}
+
+	client, err := s.repo.FindClient(ctx, id, group)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetInstance(ctx, client.InstanceID, authz.Clause{})
+}
+
+// userInstances returns the instances of the user, and the shared instances they are
+// a client of, in every group.
+func (s *Service) userInstances(ctx context.Context, id UserID) ([]*Instance, error) {
+	return s.repo.ListInstances(ctx, authz.Clause{
+		Condition: "i.replaces is null and (i.user_id = ? or i.id in (select instance_id from instance_clients where user_id = ? and revoked_at is null))",
+		Values:    []any{id, id},
+	})
+}
+
+// activeClients filters out the clients of the suspended users.
+func (s *Service) activeClients(ctx context.Context, clients []*InstanceClient) ([]*InstanceClient, error) {
+	now := time.Now()
+	result := make([]*InstanceClient, 0, len(clients))
+	for _, client := range clients {
+		quota, err := s.repo.GetUserQuota(ctx, client.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !quota.Suspended(now) {
+			result = append(result, client)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const ResourceRateLimits = "ratelimits"
+
+var (
+	// DefaultUserRateLimit limits the instance changes of each user, unless changed by the group admins.
+	DefaultUserRateLimit = RateLimit{Burst: 3, PerHour: 3}
+	// DefaultGroupRateLimit limits the instance changes of a group, unless changed by the group admins.
+	DefaultGroupRateLimit = RateLimit{Burst: 20, PerHour: 20}
+)
+
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimitError is returned when a bucket is out of tokens. It wraps ErrRateLimited.
+type RateLimitError struct {
+	// RetryAfter is how long until the bucket has a token again.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimit is a token bucket; it holds up to Burst tokens, and is refilled with
+// PerHour tokens an hour. Each creation, deletion or renewal of an instance takes a
+// token. Zero PerHour is unlimited.
+type RateLimit struct {
+	Burst   int
+	PerHour int
+}
+
+func (l RateLimit) unlimited() bool {
+	return l.PerHour == 0
+}
+
+func (l RateLimit) validate() error {
+	if l.Burst < 0 || l.PerHour < 0 {
+		return errors.Join(ErrBadRequest, errors.New("rate limits should not be negative"))
+	}
+	if l.PerHour > 0 && l.Burst < 1 {
+		return errors.Join(ErrBadRequest, errors.New("rate limit burst should be at least 1"))
+	}
+	return nil
+}
+
+// Bucket is the state of a token bucket. Buckets are stored, so they
+// survive the restarts.
+type Bucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// refill adds the tokens earned since the last update of the bucket.
+func (b *Bucket) refill(limit RateLimit, now time.Time) {
+	rate := float64(limit.PerHour) / float64(time.Hour)
+	b.Tokens = math.Min(float64(limit.Burst), b.Tokens+float64(now.Sub(b.UpdatedAt))*rate)
+	b.UpdatedAt = now
+}
+
+// retryAfter returns how long until the bucket has a token.
+func (b *Bucket) retryAfter(limit RateLimit) time.Duration {
+	rate := float64(limit.PerHour) / float64(time.Hour)
+	return time.Duration(math.Ceil((1 - b.Tokens) / rate))
+}
+
+// RateLimitStatus is the rate limit of a user.
+type RateLimitStatus struct {
+	RateLimit
+	// Overridden is true if the limit is set for the user, instead of inherited from the group.
+	Overridden bool
+}
+
+// GetRateLimit returns the rate limit of a user.
+func (s *Service) GetRateLimit(ctx context.Context, id UserID) (*RateLimitStatus, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var result *RateLimitStatus
+	if err := s.repo.Transact(ctx, sql.LevelReadCommitted, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, GroupID{principal.GroupID})
+		if err != nil {
+			return err
+		}
+
+		if err := s.authorizeRateLimit(ctx, principal, authz.Get, user); err != nil {
+			return err
+		}
+
+		result, err = s.userRateLimit(ctx, user)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateRateLimit overrides the rate limit of the group for a user. A nil limit
+// restores the limit of the group.
+func (s *Service) UpdateRateLimit(ctx context.Context, id UserID, limit *RateLimit) (*RateLimitStatus, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if limit != nil {
+		if err := limit.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *RateLimitStatus
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		user, err := s.repo.GetMember(ctx, id, GroupID{principal.GroupID})
+		if err != nil {
+			return err
+		}
+
+		if err := s.authorizeRateLimit(ctx, principal, authz.Update, user); err != nil {
+			return err
+		}
+
+		if err := s.repo.SaveRateLimitOverride(ctx, id, limit); err != nil {
+			return err
+		}
+
+		result, err = s.userRateLimit(ctx, user)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) authorizeRateLimit(ctx context.Context, principal authz.Principal, verb authz.Verb, user *User) error {
+	policy, err := s.enforcer.Can(ctx, principal, verb, authz.ResourceFunc(func() (string, any) {
+		return ResourceRateLimits, map[string]any{
+			"user_id":  user.ID,
+			"group_id": user.GroupID,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (s *Service) userRateLimit(ctx context.Context, user *User) (*RateLimitStatus, error) {
+	override, err := s.repo.GetRateLimitOverride(ctx, user.ID)
+	if err == nil {
+		return &RateLimitStatus{RateLimit: *override, Overridden: true}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	group, err := s.repo.GetGroup(ctx, user.GroupID)
+	if err != nil {
+		return nil, errors.Join(ErrGroups, err)
+	}
+	return &RateLimitStatus{RateLimit: group.Settings.UserRateLimit}, nil
+}
+
+// limitRate takes a token from the buckets of the user and the group. The user is
+// the one the instance is changed for, e.g. the requester of an approved instance, not
+// the admin approving it; the group is the one the instance belongs to. No token is
+// taken unless both buckets have one. It should be called in a transaction.
+func (s *Service) limitRate(ctx context.Context, id UserID, groupID GroupID) error {
+	userLimit, err := s.userRateLimit(ctx, &User{ID: id, GroupID: groupID})
+	if err != nil {
+		return err
+	}
+
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		return errors.Join(ErrGroups, err)
+	}
+
+	limits := []RateLimit{userLimit.RateLimit, group.Settings.GroupRateLimit}
+	keys := []string{"user:" + id.String(), "group:" + group.ID.String()}
+	return s.takeTokens(ctx, time.Now(), keys, limits)
+}
+
+// takeTokens takes a token from each of the buckets, with their limits. No token is
+// taken unless every bucket has one. It should be called in a transaction.
+func (s *Service) takeTokens(ctx context.Context, now time.Time, keys []string, limits []RateLimit) error {
+	var (
+		buckets    []*Bucket
+		retryAfter time.Duration
+	)
+	for i, limit := range limits {
+		if limit.unlimited() {
+			continue
+		}
+
+		bucket, err := s.repo.GetBucket(ctx, keys[i])
+		if errors.Is(err, ErrNotFound) {
+			bucket = &Bucket{Key: keys[i], Tokens: float64(limit.Burst), UpdatedAt: now}
+		} else if err != nil {
+			return err
+		}
+
+		bucket.refill(limit, now)
+		if bucket.Tokens < 1 {
+			retryAfter = max(retryAfter, bucket.retryAfter(limit))
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if retryAfter > 0 {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	for _, bucket := range buckets {
+		bucket.Tokens--
+		if err := s.repo.SaveBucket(ctx, bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,10 +14,17 @@ import (
 
 type XrayConfig struct {
 	ConnectionString string
+	// Reality holds the parameters the instance is set up with. They are
+	// needed to render the config again, e.g. when clients are added to
+	// shared instances.
+	Reality XrayTemplate
 }
 
 type XrayTemplateID struct{ uuid.UUID }
 
+// healthCheckNamespace derives the ids of the health check clients.
+var healthCheckNamespace = uuid.FromStringOrNil("6f0b9c1e-4d2a-4c51-9e3f-7a8d2b6c5e10")
+
 type XrayTemplate struct {
 	ID                   XrayTemplateID
 	FakeURL              string
@@ -57,7 +64,29 @@ func NewRealityConfig(fakeURL string) (XrayTemplate, error) {
 }
 
 func (c XrayTemplate) String() string {
-	return fmt.Sprintf(c.Base, c.ID, c.FakeURL, c.Curve25519PrivateKey, c.ShortID)
+	base := c.Base
+	if base == "" {
+		base = defaultXrayTemplate
+	}
+	return fmt.Sprintf(base, c.ID, c.FakeURL, c.Curve25519PrivateKey, c.ShortID)
+}
+
+// IsNil is used to determine if the reality parameters are generated.
+func (c XrayTemplate) IsNil() bool {
+	return c.Curve25519PrivateKey == ""
+}
+
+// ForClient returns the same reality config for another vless client.
+func (c XrayTemplate) ForClient(id ClientID) XrayTemplate {
+	c.ID = XrayTemplateID(id)
+	return c
+}
+
+// HealthCheckClientID returns the id of the vless client the health checks connect
+// with. It is derived from the private key, so only the server knows it, and it
+// changes along with the keys when they are rotated.
+func (c XrayTemplate) HealthCheckClientID() ClientID {
+	return ClientID{uuid.NewV5(healthCheckNamespace, c.Curve25519PrivateKey)}
 }
 
 // ConnectionString creates a connection string to connect to a server made
@@ -0,0 +1,429 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type (
+	RenewalID     struct{ uuid.UUID }
+	RenewalReason string
+)
+
+const (
+	// RenewalBlocked renewals replace the instances that are blocked inside the country.
+	RenewalBlocked RenewalReason = "blocked"
+	// RenewalRotation renewals replace the instances on the schedule of their group.
+	RenewalRotation RenewalReason = "rotation"
+	// RenewalManual renewals are requested by the users.
+	RenewalManual RenewalReason = "manual"
+)
+
+// notice is the message the users are notified with, when their instance is renewed.
+func (r RenewalReason) notice() string {
+	switch r {
+	case RenewalBlocked:
+		return "Your instance was blocked, and is replaced with a new one. Get the new connection string to reconnect."
+	case RenewalRotation:
+		return "Your instance is rotated on schedule. Get the new connection string to reconnect."
+	case RenewalManual:
+		return "Your instance is renewed. Get the new connection string to reconnect."
+	default:
+		return "Your instance is replaced with a new one. Get the new connection string to reconnect."
+	}
+}
+
+// DefaultMaxRenewalsPerDay caps the renewals of a group, unless changed by the group admins.
+const DefaultMaxRenewalsPerDay = 3
+
+// DefaultRenewalGracePeriod is how long the replaced servers are kept running,
+// unless changed by the group admins.
+const DefaultRenewalGracePeriod = time.Hour
+
+// renewalTimeout bounds the setup of a replacement.
+const renewalTimeout = 15 * time.Minute
+
+var ErrRenewalCap = errors.New("daily renewal cap is reached")
+
+// Renewal is the replacement of an instance with a new one. Renewals are kept,
+// so the renewals of a group can be capped per day; each one is a new instance
+// on the provider bill.
+type Renewal struct {
+	ID            RenewalID
+	GroupID       GroupID
+	InstanceID    InstanceID
+	ReplacementID InstanceID
+	Reason        RenewalReason
+	CreatedAt     time.Time
+}
+
+// replaceableCondition matches the instances that are not replacements, and
+// without a replacement being set up.
+const replaceableCondition = `i.replaces is null and not exists (
+	select 1 from instances r where r.replaces = i.id and r.deleted_at is null
+)`
+
+// renewBlockedInstances replaces the blocked instances of the groups with auto renew.
+func (s *Service) renewBlockedInstances(ctx context.Context) {
+	instances, err := s.repo.ListInstances(ctx, authz.Clause{
+		Condition: "i.status = ? and " + replaceableCondition,
+		Values:    []any{StatusBlocked},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing blocked instances", "error", err)
+		return
+	}
+
+	for _, instance := range instances {
+		// Setting up the replacement takes a few minutes, it should not hold back
+		// the health checks. Replacements in progress are not renewed again.
+		go func() {
+			if err := s.replaceInstance(ctx, instance, RenewalBlocked, func(settings GroupSettings) bool {
+				return settings.AutoRenew
+			}); errors.Is(err, ErrRenewalCap) || errors.Is(err, ErrBudgetExceeded) {
+				slog.WarnContext(ctx, "core: blocked instance is not renewed", "instance_id", instance.ID, "error", err)
+			} else if err != nil {
+				slog.ErrorContext(ctx, "core: error renewing blocked instance", "instance_id", instance.ID, "error", err)
+			}
+		}()
+	}
+}
+
+// RenewInstance replaces the instance with a new one in a different region, without
+// leaving its users disconnected while the new one is set up. The replacement is
+// returned right after it is created, and is swapped in once it is set up. The old
+// server is deleted after the grace period of the group.
+func (s *Service) RenewInstance(ctx context.Context, id InstanceID) (*Instance, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.ResourceID(ResourceInstances, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	var pending *pendingReplacement
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		instance, err := s.repo.GetInstance(ctx, id, policy.Partial)
+		if err != nil {
+			return err
+		}
+
+		// Renewing a shared instance changes the server of all its clients.
+		if instance.Shared && !principal.Role.Operates() {
+			return errors.Join(ErrBadRequest, errors.New("shared instances are renewed by the group operators"))
+		}
+
+		if !slices.Contains(monitoredStatuses, any(instance.Status)) {
+			return errors.Join(ErrBadRequest, fmt.Errorf("instance is %s", instance.Status))
+		}
+
+		_, err = s.repo.GetInstance(ctx, id, authz.Clause{Condition: replaceableCondition})
+		if errors.Is(err, ErrNotFound) {
+			return errors.Join(ErrBadRequest, errors.New("instance is already being renewed"))
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.limitRate(ctx, UserID{principal.ID}, instance.GroupID); err != nil {
+			return err
+		}
+
+		pending, err = s.startReplacement(ctx, instance, RenewalManual, func(GroupSettings) bool { return true })
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, AuditInstanceRenew, "instances/"+id.String(), map[string]AuditChange{
+			"replacement_id": {New: pending.replacement.ID},
+			"region":         {Old: instance.Region, New: pending.replacement.Region},
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	// The replacement takes a few minutes to set up, which should not hold the
+	// request, nor be canceled with it.
+	go func() {
+		ctx := context.Background()
+		if err := s.finishReplacement(ctx, pending); err != nil {
+			slog.ErrorContext(ctx, "core: error renewing instance", "instance_id", id, "error", err)
+		}
+	}()
+
+	return pending.replacement, nil
+}
+
+// pendingReplacement is a replacement that is created, but not set up and swapped in yet.
+type pendingReplacement struct {
+	instance    *Instance
+	replacement *Instance
+	reason      RenewalReason
+	apikey      string
+	param       CreateInstanceParam
+	grace       time.Duration
+}
+
+// replaceInstance creates a replacement for the instance in a different region, and
+// swaps it in once it is set up. The old instance is only deleted after the swap, so
+// its users are not left without a working instance if the replacement fails.
+// The instance is not replaced if the group settings do not allow it.
+func (s *Service) replaceInstance(ctx context.Context, instance *Instance, reason RenewalReason, allow func(GroupSettings) bool) error {
+	pending, err := s.startReplacement(ctx, instance, reason, allow)
+	if err != nil || pending == nil {
+		return err
+	}
+
+	return s.finishReplacement(ctx, pending)
+}
+
+// startReplacement creates the replacement of the instance on the provider, and
+// records the renewal. It returns nil if the group settings do not allow it, or the
+// instance is gone or already being replaced by the time the transaction starts.
+func (s *Service) startReplacement(ctx context.Context, instance *Instance, reason RenewalReason, allow func(GroupSettings) bool) (*pendingReplacement, error) {
+	var pending *pendingReplacement
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) (err error) {
+		group, err := s.repo.GetGroup(ctx, instance.GroupID)
+		if err != nil {
+			return errors.Join(ErrGroups, err)
+		}
+		if !allow(group.Settings) {
+			return nil
+		}
+
+		// The instance is listed before the transaction, another renewal may have
+		// replaced or deleted it since.
+		current, err := s.repo.GetInstance(ctx, instance.ID, authz.Clause{Condition: replaceableCondition})
+		if errors.Is(err, ErrNotFound) {
+			slog.InfoContext(ctx, "core: instance is not replaceable anymore", "instance_id", instance.ID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		instance = current
+
+		now := time.Now()
+		count, err := s.repo.CountRenewals(ctx, group.ID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		if count >= group.Settings.MaxRenewalsPerDay {
+			return ErrRenewalCap
+		}
+
+		regions := group.Settings.Regions
+		if len(regions) == 0 {
+			regions = s.vps.Regions()
+		}
+
+		param := CreateInstanceParam{
+			SSHKey: group.DefaultSSHKey,
+			Label:  instance.Owner.String()[:8],
+			Script: group.DefaultStartUpScript,
+			Region: pickRegion(regions, instance.Region),
+		}
+
+		// Both servers are billed until the old one is deleted.
+		if err := s.checkBudget(ctx, group); err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "core: creating replacement instance...", "instance_id", instance.ID, "reason", reason, "region", param.Region)
+		remoteInstance, err := s.vps.CreateInstance(ctx, group.Host.APIKey, param)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				err = errors.Join(err, s.vps.DeleteInstance(ctx, group.Host.APIKey, remoteInstance.ID))
+			}
+		}()
+
+		replacement := &Instance{
+			ID:         InstanceID{UUID: uuid.Must(uuid.NewV4())},
+			RemoteID:   remoteInstance.ID,
+			Owner:      instance.Owner,
+			GroupID:    instance.GroupID,
+			IP:         remoteInstance.IP,
+			CreatedAt:  now,
+			Status:     StatusInitializing,
+			PrivateKey: group.DefaultSSHKey.PrivateKey,
+			Shared:     instance.Shared,
+			Region:     remoteInstance.Region,
+			Replaces:   &instance.ID,
+			Price:      remoteInstance.Price,
+		}
+		if _, err := s.repo.SaveInstance(ctx, replacement); err != nil {
+			return err
+		}
+
+		if err := s.repo.SaveRenewal(ctx, &Renewal{
+			ID:            RenewalID{uuid.Must(uuid.NewV4())},
+			GroupID:       group.ID,
+			InstanceID:    instance.ID,
+			ReplacementID: replacement.ID,
+			Reason:        reason,
+			CreatedAt:     now,
+		}); err != nil {
+			return err
+		}
+
+		pending = &pendingReplacement{
+			instance:    instance,
+			replacement: replacement,
+			reason:      reason,
+			apikey:      group.Host.APIKey,
+			param:       param,
+			grace:       group.Settings.RenewalGracePeriod,
+		}
+		// Blocked instances are of no use to their users,
+		// there is no point in keeping them around.
+		if reason == RenewalBlocked {
+			pending.grace = 0
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// finishReplacement sets up the replacement and swaps it in. The replacement is
+// discarded if it can not be set up.
+func (s *Service) finishReplacement(ctx context.Context, pending *pendingReplacement) error {
+	setupCtx, cancel := context.WithTimeout(ctx, renewalTimeout)
+	defer cancel()
+
+	if err := s.setupInstance(setupCtx, pending.apikey, pending.replacement, pending.param); err != nil {
+		return errors.Join(fmt.Errorf("error setting up replacement: %w", err), s.discardReplacement(ctx, pending.apikey, pending.replacement))
+	}
+
+	return s.swapInstance(ctx, pending)
+}
+
+// swapInstance moves the clients of the instance to its replacement, deletes the
+// instance, and notifies the users to get their new connection strings. The old
+// server keeps running for the grace period, so the users can switch at their
+// own pace; it is deleted by the scheduler afterwards.
+func (s *Service) swapInstance(ctx context.Context, pending *pendingReplacement) error {
+	var instance *Instance
+	replacement := pending.replacement
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		var err error
+		instance, err = s.repo.GetInstance(ctx, pending.instance.ID, authz.Clause{})
+		if err != nil {
+			return err
+		}
+
+		clients, err := s.repo.ListClients(ctx, instance.ID, authz.Clause{})
+		if err != nil {
+			return err
+		}
+		for _, client := range clients {
+			client.InstanceID = replacement.ID
+			if _, err := s.repo.SaveClient(ctx, client); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.DeleteInstance(ctx, instance.ID, authz.Clause{}); err != nil {
+			return err
+		}
+
+		if pending.grace > 0 {
+			if err := s.repo.RetireInstance(ctx, instance.ID, time.Now().Add(pending.grace)); err != nil {
+				return err
+			}
+		}
+
+		replacement.Replaces = nil
+		if _, err := s.repo.SaveInstance(ctx, replacement); err != nil {
+			return err
+		}
+
+		return s.notifyUsers(ctx, replacement, clients, NotificationReplaced, pending.reason.notice())
+	}); err != nil {
+		// The instance is deleted while the replacement was being set up,
+		// so its owner does not need the replacement either.
+		if errors.Is(err, ErrNotFound) {
+			return s.discardReplacement(ctx, pending.apikey, replacement)
+		}
+		return err
+	}
+
+	slog.InfoContext(ctx, "core: instance is replaced", "instance_id", instance.ID, "replacement_id", replacement.ID, "grace", pending.grace)
+	if pending.grace > 0 {
+		return nil
+	}
+
+	// The swap is already done, failing to delete the old instance
+	// only costs money, and is left to the admins.
+	if err := s.deleteRemoteInstance(ctx, pending.apikey, instance); err != nil {
+		slog.ErrorContext(ctx, "core: error deleting replaced instance", "instance_id", instance.ID, "remote_id", instance.RemoteID, "error", err)
+	}
+	return nil
+}
+
+// retireInstances deletes the servers of the replaced instances, once their grace period is over.
+func (s *Service) retireInstances(ctx context.Context) {
+	instances, err := s.repo.ListRetiredInstances(ctx, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing retired instances", "error", err)
+		return
+	}
+
+	groups := map[GroupID]*Group{}
+	for _, instance := range instances {
+		group, err := s.instanceGroup(ctx, instance, groups)
+		if err != nil {
+			slog.ErrorContext(ctx, "core: error getting instance group", "instance_id", instance.ID, "error", err)
+			continue
+		}
+
+		if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+			if err := s.repo.ClearRetirement(ctx, instance.ID); err != nil {
+				return err
+			}
+			return s.deleteRemoteInstance(ctx, group.Host.APIKey, instance)
+		}); err != nil {
+			slog.ErrorContext(ctx, "core: error deleting retired instance", "instance_id", instance.ID, "remote_id", instance.RemoteID, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "core: retired instance is deleted", "instance_id", instance.ID)
+	}
+}
+
+func (s *Service) discardReplacement(ctx context.Context, apikey string, replacement *Instance) error {
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		if err := s.repo.DeleteInstance(ctx, replacement.ID, authz.Clause{}); err != nil {
+			return err
+		}
+		return s.deleteRemoteInstance(ctx, apikey, replacement)
+	})
+}
+
+// pickRegion picks a random region other than the current one. Censors tend to
+// block the ranges of a region, so replacements in the same region are likely
+// blocked too. The current region is only used if there is no other.
+func pickRegion(regions []string, current string) string {
+	candidates := slices.DeleteFunc(slices.Clone(regions), func(r string) bool { return r == current })
+	if len(candidates) == 0 {
+		return current
+	}
+	return candidates[rand.IntN(len(candidates))]
+}
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	ResourceRuleSets = "rulesets"
+	// DefaultPreset is applied to the groups created in the system.
+	DefaultPreset = "ir"
+)
+
+type RuleSetID struct{ uuid.UUID }
+
+// Rules match the destination of the traffic going through an instance.
+// Values follow xray routing syntax, e.g. "geosite:category-ir",
+// "domain:divar.ir" for domains and "geoip:ir", "10.0.0.0/8" for IPs.
+type Rules struct {
+	Domains []string `json:"domains,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+}
+
+func (r Rules) IsEmpty() bool {
+	return len(r.Domains) == 0 && len(r.IPs) == 0
+}
+
+// WarpOutbound is a wireguard outbound to cloudflare WARP. Traffic matching
+// its rules leaves the instance through WARP instead of the instance IP.
+type WarpOutbound struct {
+	SecretKey     string
+	Addresses     []string
+	PeerPublicKey string
+	Endpoint      string
+	Reserved      []int
+	MTU           int
+	Rules         Rules
+}
+
+// RuleSet is a set of routing rules owned by a group. All the enabled
+// rule sets of a group are merged into the config of new instances.
+type RuleSet struct {
+	ID      RuleSetID
+	GroupID GroupID
+	Name    string
+	// Preset is the country code of the preset the rule set was made from, if any.
+	Preset  string
+	Enabled bool
+	// Block is the traffic that is dropped by the instance. This is usually
+	// the domestic traffic, since it can be used to identify the instance.
+	Block Rules
+	// Direct is the traffic that always leaves directly from the instance,
+	// even if it matches the block or warp rules.
+	Direct    Rules
+	Warp      *WarpOutbound
+	CreatedAt time.Time
+}
+
+// Preset is a bundled set of rules, suitable for groups in a country.
+type Preset struct {
+	Country string `json:"country"`
+	Name    string `json:"name"`
+	Block   Rules  `json:"block"`
+	Direct  Rules  `json:"direct"`
+}
+
+// presetsContent maps the country codes to their preset.
+//
+//go:embed default/presets.json
+var presetsContent []byte
+
+var presets = mustLoadPresets()
+
+func mustLoadPresets() map[string]Preset {
+	var result map[string]Preset
+	if err := json.Unmarshal(presetsContent, &result); err != nil {
+		panic(fmt.Errorf("error parsing routing presets: %w", err))
+	}
+
+	for country, p := range result {
+		p.Country = country
+		result[country] = p
+	}
+	return result
+}
+
+// NewRuleSetFromPreset creates an enabled rule set for the group, given the preset country code.
+func NewRuleSetFromPreset(groupID GroupID, country string) (*RuleSet, error) {
+	preset, ok := presets[country]
+	if !ok {
+		return nil, errors.Join(ErrBadRequest, fmt.Errorf("unknown preset %q", country))
+	}
+
+	return &RuleSet{
+		ID:        RuleSetID{uuid.Must(uuid.NewV4())},
+		GroupID:   groupID,
+		Name:      preset.Name,
+		Preset:    preset.Country,
+		Enabled:   true,
+		Block:     preset.Block,
+		Direct:    preset.Direct,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (r *RuleSet) validate() error {
+	if r.Name == "" {
+		return errors.Join(ErrBadRequest, errors.New("rule set name missing"))
+	}
+
+	if r.Warp == nil {
+		return nil
+	}
+
+	if r.Warp.SecretKey == "" || r.Warp.PeerPublicKey == "" || r.Warp.Endpoint == "" || len(r.Warp.Addresses) == 0 {
+		return errors.Join(ErrBadRequest, errors.New("warp outbound needs secret key, peer public key, endpoint and addresses"))
+	}
+
+	if r.Warp.Rules.IsEmpty() {
+		return errors.Join(ErrBadRequest, errors.New("warp outbound has no rules"))
+	}
+
+	return nil
+}
+
+// ListPresets returns the bundled rule set presets, sorted by their country code.
+func (s *Service) ListPresets() []Preset {
+	result := make([]Preset, 0, len(presets))
+	for _, p := range presets {
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Country < result[j].Country
+	})
+	return result
+}
+
+func (s *Service) GetRuleSet(ctx context.Context, id RuleSetID) (*RuleSet, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceRuleSets, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.GetRuleSet(ctx, id, policy.Partial)
+}
+
+func (s *Service) ListRuleSets(ctx context.Context) ([]*RuleSet, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.Resource{Group: ResourceRuleSets})
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListRuleSets(ctx, policy.Partial)
+}
+
+// CreateRuleSet creates a rule set in the group of the principal. If a preset is specified
+// and no rules are given, rules are copied from the preset.
+func (s *Service) CreateRuleSet(ctx context.Context, ruleset *RuleSet) (*RuleSet, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	groupID := GroupID{principal.GroupID}
+	policy, err := s.enforcer.Can(ctx, principal, authz.Create, authz.ResourceFunc(func() (string, any) {
+		return ResourceRuleSets, map[string]any{
+			"group_id": groupID,
+		}
+	}))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	result := *ruleset
+	if result.Preset != "" {
+		preset, err := NewRuleSetFromPreset(groupID, result.Preset)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Name == "" {
+			result.Name = preset.Name
+		}
+		if result.Block.IsEmpty() && result.Direct.IsEmpty() {
+			result.Block = preset.Block
+			result.Direct = preset.Direct
+		}
+	}
+
+	result.ID = RuleSetID{uuid.Must(uuid.NewV4())}
+	result.GroupID = groupID
+	result.CreatedAt = time.Now()
+	if err := result.validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveRuleSet(ctx, &result)
+}
+
+// UpdateRuleSet replaces the rules of an existing rule set. If the warp secret key
+// is not given, the existing one is kept.
+func (s *Service) UpdateRuleSet(ctx context.Context, ruleset *RuleSet) (*RuleSet, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var result *RuleSet
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		policy, err := s.enforcer.Can(ctx, principal, authz.Update, authz.ResourceID(ResourceRuleSets, ruleset.ID.UUID))
+		if err != nil || !policy.Allow {
+			return ErrUnauthorized
+		}
+
+		old, err := s.repo.GetRuleSet(ctx, ruleset.ID, policy.Partial)
+		if err != nil {
+			return err
+		}
+
+		updated := *ruleset
+		updated.GroupID = old.GroupID
+		updated.CreatedAt = old.CreatedAt
+		if _, ok := presets[updated.Preset]; updated.Preset != "" && !ok {
+			return errors.Join(ErrBadRequest, fmt.Errorf("unknown preset %q", updated.Preset))
+		}
+		if updated.Warp != nil && updated.Warp.SecretKey == "" && old.Warp != nil {
+			updated.Warp.SecretKey = old.Warp.SecretKey
+		}
+		if err := updated.validate(); err != nil {
+			return err
+		}
+
+		result, err = s.repo.SaveRuleSet(ctx, &updated)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) DeleteRuleSet(ctx context.Context, id RuleSetID) error {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Delete, authz.ResourceID(ResourceRuleSets, id.UUID))
+	if err != nil || !policy.Allow {
+		return ErrUnauthorized
+	}
+
+	return s.repo.DeleteRuleSet(ctx, id, policy.Partial)
+}
+
+type xrayRule struct {
+	Type        string   `json:"type"`
+	OutboundTag string   `json:"outboundTag"`
+	Domain      []string `json:"domain,omitempty"`
+	IP          []string `json:"ip,omitempty"`
+}
+
+type xrayWireguardPeer struct {
+	PublicKey string `json:"publicKey"`
+	Endpoint  string `json:"endpoint"`
+}
+
+type xrayWireguardSettings struct {
+	SecretKey string              `json:"secretKey"`
+	Address   []string            `json:"address"`
+	Peers     []xrayWireguardPeer `json:"peers"`
+	Reserved  []int               `json:"reserved,omitempty"`
+	MTU       int                 `json:"mtu,omitempty"`
+}
+
+type xrayOutbound struct {
+	Protocol string                `json:"protocol"`
+	Tag      string                `json:"tag"`
+	Settings xrayWireguardSettings `json:"settings"`
+}
+
+// applyRuleSets merges the rule sets into a rendered xray config. The rules in the
+// config are kept first, then direct, block and warp rules of all the rule sets
+// are appended in this order, since xray routes the traffic by the first matching rule.
+func applyRuleSets(config string, rulesets []*RuleSet) (string, error) {
+	if len(rulesets) == 0 {
+		return config, nil
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		return "", fmt.Errorf("error parsing xray config: %w", err)
+	}
+
+	routing, ok := parsed["routing"].(map[string]any)
+	if !ok {
+		routing = map[string]any{"domainStrategy": "IPIfNonMatch"}
+		parsed["routing"] = routing
+	}
+	rules, _ := routing["rules"].([]any)
+	outbounds, _ := parsed["outbounds"].([]any)
+
+	var direct, block Rules
+	for _, rs := range rulesets {
+		direct = mergeRules(direct, rs.Direct)
+		block = mergeRules(block, rs.Block)
+	}
+	rules = append(rules, toXrayRules("direct", direct)...)
+	rules = append(rules, toXrayRules("block", block)...)
+
+	for _, rs := range rulesets {
+		if rs.Warp == nil {
+			continue
+		}
+
+		tag := fmt.Sprintf("warp-%s", rs.ID.String()[:8])
+		outbounds = append(outbounds, xrayOutbound{
+			Protocol: "wireguard",
+			Tag:      tag,
+			Settings: xrayWireguardSettings{
+				SecretKey: rs.Warp.SecretKey,
+				Address:   rs.Warp.Addresses,
+				Peers: []xrayWireguardPeer{
+					{PublicKey: rs.Warp.PeerPublicKey, Endpoint: rs.Warp.Endpoint},
+				},
+				Reserved: rs.Warp.Reserved,
+				MTU:      rs.Warp.MTU,
+			},
+		})
+		rules = append(rules, toXrayRules(tag, rs.Warp.Rules)...)
+	}
+
+	routing["rules"] = rules
+	parsed["outbounds"] = outbounds
+
+	b, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling xray config: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func toXrayRules(tag string, rules Rules) []any {
+	var result []any
+	if len(rules.IPs) > 0 {
+		result = append(result, xrayRule{Type: "field", OutboundTag: tag, IP: rules.IPs})
+	}
+	if len(rules.Domains) > 0 {
+		result = append(result, xrayRule{Type: "field", OutboundTag: tag, Domain: rules.Domains})
+	}
+	return result
+}
+
+// mergeRules appends the rules in b to a, skipping the duplicates.
+func mergeRules(a, b Rules) Rules {
+	return Rules{
+		Domains: appendUnique(a.Domains, b.Domains...),
+		IPs:     appendUnique(a.IPs, b.IPs...),
+	}
+}
+
+func appendUnique(s []string, elems ...string) []string {
+	for _, e := range elems {
+		if !slices.Contains(s, e) {
+			s = append(s, e)
+		}
+	}
+	return s
+}
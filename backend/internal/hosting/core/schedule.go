@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+)
+
+const expiredNotice = "Your instance expired, and is deleted. Create a new one to reconnect."
+
+// ScheduleInstances rotates and expires the instances based on their age and the
+// policies of their group, and deletes the replaced servers after their grace
+// period, every interval, until the context is done.
+func (s *Service) ScheduleInstances(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scheduleInstances(ctx)
+			s.retireInstances(ctx)
+		}
+	}
+}
+
+func (s *Service) scheduleInstances(ctx context.Context) {
+	instances, err := s.repo.ListInstances(ctx, authz.Clause{Condition: replaceableCondition})
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing instances for scheduling", "error", err)
+		return
+	}
+
+	groups := map[GroupID]*Group{}
+	for _, instance := range instances {
+		group, err := s.instanceGroup(ctx, instance, groups)
+		if err != nil {
+			slog.ErrorContext(ctx, "core: error getting instance group", "instance_id", instance.ID, "error", err)
+			continue
+		}
+
+		age := time.Since(instance.CreatedAt)
+		settings := group.Settings
+		switch {
+		case settings.TTL > 0 && age >= settings.TTL:
+			if err := s.expireInstance(ctx, group, instance.ID); err != nil {
+				slog.ErrorContext(ctx, "core: error expiring instance", "instance_id", instance.ID, "error", err)
+			}
+		case settings.RotationInterval > 0 && age >= settings.RotationInterval && slices.Contains(monitoredStatuses, any(instance.Status)):
+			// Rotations are renewals, and are capped the same way.
+			go func() {
+				if err := s.replaceInstance(ctx, instance, RenewalRotation, func(settings GroupSettings) bool {
+					return settings.RotationInterval > 0
+				}); errors.Is(err, ErrRenewalCap) {
+					slog.WarnContext(ctx, "core: instance is not rotated", "instance_id", instance.ID, "error", err)
+				} else if err != nil {
+					slog.ErrorContext(ctx, "core: error rotating instance", "instance_id", instance.ID, "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// instanceGroup returns the group of the instance, caching the groups across instances.
+func (s *Service) instanceGroup(ctx context.Context, instance *Instance, groups map[GroupID]*Group) (*Group, error) {
+	if group, ok := groups[instance.GroupID]; ok {
+		return group, nil
+	}
+
+	group, err := s.repo.GetGroup(ctx, instance.GroupID)
+	if err != nil {
+		return nil, errors.Join(ErrGroups, err)
+	}
+	groups[instance.GroupID] = group
+	return group, nil
+}
+
+// expireInstance deletes the instance at the end of its ttl, and notifies its users.
+func (s *Service) expireInstance(ctx context.Context, group *Group, id InstanceID) error {
+	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		instance, err := s.repo.GetInstance(ctx, id, authz.Clause{})
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		clients, err := s.repo.ListClients(ctx, instance.ID, authz.Clause{})
+		if err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "core: instance is expired", "instance_id", instance.ID, "created_at", instance.CreatedAt)
+		if err := s.repo.DeleteInstance(ctx, instance.ID, authz.Clause{}); err != nil {
+			return err
+		}
+
+		if err := s.notifyUsers(ctx, instance, clients, NotificationExpired, expiredNotice); err != nil {
+			return err
+		}
+
+		return s.deleteRemoteInstance(ctx, group.Host.APIKey, instance)
+	})
+}
@@ -26,6 +26,8 @@ type Service struct {
 	systemKey            SSHKeyPair
 	defaultStartupScript StartUpScript
 	enforcer             *authz.Validator
+	// decisions logs the policy decisions of the groups that enabled it.
+	decisions *authz.DecisionLogger
 }
 
 func NewService(repo Repository, vps VPSProvider, systemKey SSHKeyPair, startscript StartUpScript) *Service {
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+
+	"vpainless/pkg/tlsprobe"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// pickDestination picks a random domain of the SNI pool of the group, that is
+// suitable as reality destination when probed from the instance. It falls back
+// to the default fake url if the pool is empty or none of them are suitable.
+func (s *Service) pickDestination(ctx context.Context, conn *ssh.Client, group *Group) string {
+	pool := group.Settings.SNIPool
+	for _, i := range rand.Perm(len(pool)) {
+		domain := pool[i]
+		err := tlsprobe.Probe(ctx, domain, tlsprobe.Options{Dial: conn.DialContext})
+		if err == nil {
+			return domain
+		}
+
+		slog.WarnContext(ctx, "core: unsuitable reality destination", "group_id", group.ID, "domain", domain, "error", err)
+	}
+
+	if len(pool) > 0 {
+		slog.WarnContext(ctx, "core: no suitable reality destination in the pool, using the default", "group_id", group.ID)
+	}
+	return fakeURL
+}
+
+// validateSNIPool checks the pool only contains plain domain names,
+// as expected by NewRealityConfig.
+func validateSNIPool(pool []string) error {
+	for _, domain := range pool {
+		if domain == "" || strings.ContainsAny(domain, ":/ ") {
+			return errors.Join(ErrBadRequest, fmt.Errorf("invalid sni domain %q", domain))
+		}
+	}
+	return nil
+}
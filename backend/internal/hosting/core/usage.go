@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vpainless/internal/pkg/authz"
+	"vpainless/pkg/remote"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	ResourceUsage = "usage"
+
+	// DefaultUsagePeriod is how far back the usage is returned, unless asked otherwise.
+	DefaultUsagePeriod = 30 * 24 * time.Hour
+
+	// statsQueryCommand reads the traffic counters of the clients from the xray api,
+	// which only listens on the loopback of the instance. The counters are not reset,
+	// so they are not lost if the samples can not be saved.
+	statsQueryCommand = "xray api statsquery --server=127.0.0.1:10085 -pattern 'user>>>'"
+)
+
+// Usage is the traffic of a user on an instance, in bytes. Samples are collected
+// since the previous collection; aggregates cover the days or periods asked for.
+type Usage struct {
+	UserID      UserID
+	InstanceID  InstanceID
+	CollectedAt time.Time
+	Uplink      int64
+	Downlink    int64
+}
+
+// ListUserUsage returns the daily traffic of a user per instance, since the given time.
+func (s *Service) ListUserUsage(ctx context.Context, id UserID, since time.Time) ([]*Usage, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.Get, authz.ResourceID(ResourceUsage, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListDailyUsage(ctx, id, since, policy.Partial)
+}
+
+// SummarizeGroupUsage returns the total traffic of each user of a group, since the given time.
+func (s *Service) SummarizeGroupUsage(ctx context.Context, id GroupID, since time.Time) ([]*Usage, error) {
+	principal, err := authz.GetPrincipal(ctx)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	policy, err := s.enforcer.Can(ctx, principal, authz.List, authz.ResourceID(ResourceUsage, id.UUID))
+	if err != nil || !policy.Allow {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.SummarizeUsage(ctx, id, since, policy.Partial)
+}
+
+// CollectUsage pulls the traffic counters of the instances every interval, and enforces
+// the quotas of the users, until the context is done.
+func (s *Service) CollectUsage(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.collectUsage(ctx)
+		}
+	}
+}
+
+func (s *Service) collectUsage(ctx context.Context) {
+	instances, err := s.repo.ListInstances(ctx, authz.Clause{
+		Condition: monitoredCondition,
+		Values:    monitoredStatuses,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "core: error listing instances for usage collection", "error", err)
+		return
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		users []*User
+	)
+	for _, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			usages, err := s.collectInstanceUsage(ctx, instance)
+			if err != nil {
+				slog.ErrorContext(ctx, "core: error collecting instance usage", "instance_id", instance.ID, "error", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, usage := range usages {
+				users = append(users, &User{ID: usage.UserID, GroupID: instance.GroupID})
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.enforceQuotas(ctx, users)
+}
+
+// collectInstanceUsage reads the counters of the instance over ssh, and saves the ones with traffic.
+// The counters are cumulative, so each sample is the difference to the counters saved with the
+// last one. Both are saved together, so the traffic of a failed collection is in the next one.
+func (s *Service) collectInstanceUsage(ctx context.Context, instance *Instance) ([]*Usage, error) {
+	conn, err := remote.Dial(instance.IP, instance.PrivateKey, "root")
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to instance: %w", err)
+	}
+	defer conn.Close()
+
+	out, err := remote.Execute(conn, statsQueryCommand)
+	if err != nil {
+		return nil, fmt.Errorf("error querying xray stats: %w", err)
+	}
+
+	counters, err := parseUserStats(out)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Usage
+	now := time.Now()
+	if err := s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		last, err := s.repo.ListUsageCounters(ctx, instance.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, counter := range counters {
+			counter.InstanceID = instance.ID
+			counter.CollectedAt = now
+			usage := counter.since(last[counter.UserID])
+			if usage.Uplink == 0 && usage.Downlink == 0 {
+				continue
+			}
+
+			if err := s.repo.SaveUsage(ctx, usage); err != nil {
+				return err
+			}
+			if err := s.repo.SaveUsageCounter(ctx, counter); err != nil {
+				return err
+			}
+			result = append(result, usage)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// since returns the traffic of the cumulative counter since the last one read. The
+// counters start over when xray restarts, e.g. when the instance is reconfigured, so
+// counters lower than the last ones are all traffic since the restart.
+func (c *Usage) since(last *Usage) *Usage {
+	usage := *c
+	if last == nil || c.Uplink < last.Uplink || c.Downlink < last.Downlink {
+		return &usage
+	}
+
+	usage.Uplink -= last.Uplink
+	usage.Downlink -= last.Downlink
+	return &usage
+}
+
+// statValue is an int64 counter, which the xray api renders as a string.
+// It is omitted when zero.
+type statValue int64
+
+func (v *statValue) UnmarshalJSON(b []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = statValue(n)
+	return nil
+}
+
+type xrayStats struct {
+	Stat []struct {
+		Name  string    `json:"name"`
+		Value statValue `json:"value"`
+	} `json:"stat"`
+}
+
+// parseUserStats parses the output of statsquery. The counters of the users are
+// named "user>>>[email]>>>traffic>>>[uplink|downlink]", and the clients are tagged
+// by their user id as email.
+func parseUserStats(out string) ([]*Usage, error) {
+	var stats xrayStats
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		return nil, fmt.Errorf("error parsing xray stats: %w", err)
+	}
+
+	var result []*Usage
+	users := map[UserID]*Usage{}
+	for _, stat := range stats.Stat {
+		parts := strings.Split(stat.Name, ">>>")
+		if len(parts) != 4 || parts[0] != "user" || parts[2] != "traffic" {
+			continue
+		}
+
+		id, err := uuid.FromString(parts[1])
+		if err != nil {
+			continue
+		}
+
+		usage, ok := users[UserID{id}]
+		if !ok {
+			usage = &Usage{UserID: UserID{id}}
+			users[UserID{id}] = usage
+			result = append(result, usage)
+		}
+
+		switch parts[3] {
+		case "uplink":
+			usage.Uplink += int64(stat.Value)
+		case "downlink":
+			usage.Downlink += int64(stat.Value)
+		}
+	}
+
+	return result, nil
+}
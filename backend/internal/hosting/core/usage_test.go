@@ -0,0 +1,16 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageSince(t *testing.T) {
+	counter := &Usage{Uplink: 150, Downlink: 300}
+
+	require.Equal(t, &Usage{Uplink: 150, Downlink: 300}, counter.since(nil), "should count all traffic of new counters")
+	require.Equal(t, &Usage{Uplink: 50, Downlink: 100}, counter.since(&Usage{Uplink: 100, Downlink: 200}), "should count the traffic since the last counters")
+	require.Equal(t, &Usage{}, counter.since(counter), "should count no traffic without new traffic")
+	require.Equal(t, &Usage{Uplink: 150, Downlink: 300}, counter.since(&Usage{Uplink: 1000, Downlink: 2000}), "should count all traffic since xray restarted")
+}
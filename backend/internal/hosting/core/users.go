@@ -17,8 +17,11 @@ type UserID struct {
 type Role string
 
 const (
-	Admin  Role = "admin"
-	Client Role = "client"
+	Owner    Role = "owner"
+	Admin    Role = "admin"
+	Operator Role = "operator"
+	Viewer   Role = "viewer"
+	Client   Role = "client"
 )
 
 type User struct {
@@ -28,28 +31,30 @@ type User struct {
 }
 
 // savePrincipal saves the current prinicipal in the read model of users
-// in hosting domain.
+// in hosting domain, along with their membership in the group they act in.
 func (s *Service) savePrincipal(ctx context.Context) error {
 	principal, err := authz.GetPrincipal(ctx)
 	if err != nil {
 		return err
 	}
 
+	user := &User{
+		ID:      UserID{principal.ID},
+		GroupID: GroupID{principal.GroupID},
+		Role:    Role(principal.Role),
+	}
 	return s.repo.Transact(ctx, sql.LevelSerializable, func(ctx context.Context) error {
-		_, err := s.repo.GetUser(ctx, UserID{principal.ID})
-		if err == nil {
-			return nil
+		_, err := s.repo.GetUser(ctx, user.ID)
+		if errors.Is(err, ErrNotFound) {
+			_, err = s.repo.SaveUser(ctx, user)
 		}
-
-		if !errors.Is(err, ErrNotFound) {
+		if err != nil {
 			return err
 		}
 
-		_, err = s.repo.SaveUser(ctx, &User{
-			ID:      UserID{principal.ID},
-			GroupID: GroupID{principal.GroupID},
-			Role:    Role(principal.Role),
-		})
-		return err
+		if user.GroupID.IsNil() {
+			return nil
+		}
+		return s.repo.SaveMember(ctx, user)
 	})
 }
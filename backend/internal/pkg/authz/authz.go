@@ -11,11 +11,23 @@ type (
 const (
 	// Client is the least privileged user in the system.
 	Client Role = "client"
+	// Viewer is a user that can see, but not change, the resources of the group they belong.
+	Viewer Role = "viewer"
+	// Operator is a user that manages the instances of the group they belong, but not its users.
+	Operator Role = "operator"
 	// Admin is a user that have higher privileges in the group he belongs.
-	Admin Role    = "admin"
+	Admin Role = "admin"
+	// Owner is an admin that can not be demoted by other admins.
+	// Every group has at least one owner.
+	Owner Role    = "owner"
 	authz ctxtype = "authz"
 )
 
+// Operates reports whether the role manages the instances of the group.
+func (r Role) Operates() bool {
+	return r == Owner || r == Admin || r == Operator
+}
+
 // Principal encapsulates the authorization info of the logged in user,
 // including it's ID, group ID and role.
 type Principal struct {
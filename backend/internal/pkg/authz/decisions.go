@@ -0,0 +1,151 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"vpainless/pkg/collect"
+	"vpainless/pkg/middleware"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// redacted replaces the values of the sensitive fields in the logged inputs.
+const redacted = "[redacted]"
+
+// sensitiveFields are the parts of the input field names whose values are never logged.
+var sensitiveFields = []string{"password", "secret", "token", "apikey", "api_key", "private_key"}
+
+// Decision is a policy evaluation, as it is logged.
+type Decision struct {
+	ID        uuid.UUID `json:"decision_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// RequestID is the id of the api request the decision is made in.
+	RequestID     uuid.UUID `json:"request_id"`
+	Package       string    `json:"package"`
+	ResourceGroup string    `json:"resource_group"`
+	// Input is the evaluated input, with the sensitive fields redacted.
+	Input  map[string]any `json:"input"`
+	Result Policy         `json:"result"`
+	// Error is the evaluation error. Checks failing with an error are denied.
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Explanation is a replayed decision along with the trace of its evaluation.
+type Explanation struct {
+	Decision Decision `json:"decision"`
+	Trace    []string `json:"trace"`
+}
+
+func newDecision(pkgname string, input input, policy Policy, err error, duration time.Duration) Decision {
+	d := Decision{
+		ID:            uuid.Must(uuid.NewV4()),
+		Timestamp:     time.Now(),
+		Package:       pkgname,
+		ResourceGroup: input.ResourceGroup,
+		Input:         redact(input),
+		Result:        policy,
+		Duration:      duration,
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// redact returns the input as a json object, without the values of the sensitive fields.
+func redact(input input) map[string]any {
+	var result map[string]any
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil
+	}
+
+	redactValue(result)
+	return result
+}
+
+func redactValue(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if isSensitive(key) {
+				v[key] = redacted
+				continue
+			}
+			redactValue(field)
+		}
+	case []any:
+		for _, item := range v {
+			redactValue(item)
+		}
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// DecisionLogger logs the policy decisions on the principals of the enabled groups
+// as json lines. A nil logger logs nothing.
+type DecisionLogger struct {
+	logger *slog.Logger
+	groups collect.Set[uuid.UUID]
+}
+
+// NewDecisionLogger creates a decision logger writing to w. No group is enabled.
+func NewDecisionLogger(w io.Writer) *DecisionLogger {
+	return &DecisionLogger{logger: slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+// Enable turns the logging of the decisions on the principals of the group on or off.
+func (l *DecisionLogger) Enable(group uuid.UUID, enabled bool) {
+	if l == nil {
+		return
+	}
+
+	if enabled {
+		l.groups.Add(group)
+	} else {
+		l.groups.Delete(group)
+	}
+}
+
+// Enabled reports whether the decisions on the principals of the group are logged.
+func (l *DecisionLogger) Enabled(group uuid.UUID) bool {
+	if l == nil {
+		return false
+	}
+
+	return l.groups.Contains(group)
+}
+
+// Log writes the decision, filling in the id of the request from the context.
+func (l *DecisionLogger) Log(ctx context.Context, d Decision) {
+	if l == nil {
+		return
+	}
+
+	d.RequestID, _ = middleware.GetRequestID(ctx)
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "authz: decision", slog.Any("decision", d))
+}
+
+// LogDecisions logs the decisions of the validator to the logger. It should be
+// set before the validator is used.
+func (v *Validator) LogDecisions(l *DecisionLogger) {
+	v.decisions = l
+}
@@ -0,0 +1,175 @@
+package authz
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/open-policy-agent/opa/v1/bundle"
+)
+
+// reloadDelay is how long the watcher waits for changes to settle before reloading,
+// as editors and deployments usually touch several files at once.
+const reloadDelay = 500 * time.Millisecond
+
+// LoadModules reads the rego modules of a policy directory or an OPA bundle. A
+// directory is walked for .rego files, leaving out the _test.rego ones. Any other
+// file is read as a bundle tarball. Modules are keyed by their path, prefixed by
+// the loaded one, so they never collide with the embedded modules.
+func LoadModules(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadBundle(path)
+	}
+
+	modules := map[string]string{}
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(name) != ".rego" || strings.HasSuffix(name, "_test.rego") {
+			return nil
+		}
+
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		modules[name] = string(b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+func loadBundle(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := bundle.NewReader(f).Read()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[string]string{}
+	for _, module := range b.Modules {
+		if strings.HasSuffix(module.Path, "_test.rego") {
+			continue
+		}
+		modules[path+":"+module.Path] = string(module.Raw)
+	}
+	return modules, nil
+}
+
+// WatchModules calls reload with the modules of the path whenever its files change,
+// or the process receives a SIGHUP, until the context is done. Paths that fail to
+// load are logged and not passed on, so the last loaded modules stay in effect.
+func WatchModules(ctx context.Context, path string, reload func(ctx context.Context, modules map[string]string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watch(watcher, path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	bundlePath := ""
+	if !info.IsDir() {
+		bundlePath = filepath.Clean(path)
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	load := func() {
+		modules, err := LoadModules(path)
+		if err != nil {
+			slog.ErrorContext(ctx, "authz: unable to load policies, keeping the last loaded ones", "path", path, "error", err)
+			return
+		}
+		reload(ctx, modules)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hangups:
+			slog.InfoContext(ctx, "authz: reloading policies on hangup...", "path", path)
+			load()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				// New directories of a policy directory are watched as well.
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watch(watcher, event.Name)
+				}
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			if bundlePath != "" && filepath.Clean(event.Name) != bundlePath {
+				// Other files next to the bundle are of no interest.
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.WarnContext(ctx, "authz: error watching policies", "path", path, "error", err)
+		case <-timer.C:
+			slog.InfoContext(ctx, "authz: reloading changed policies...", "path", path)
+			load()
+		}
+	}
+}
+
+// watch adds the directories of the path to the watcher. Bundles are watched
+// through their directory, as they are usually replaced rather than written to.
+func watch(watcher *fsnotify.Watcher, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return watcher.Add(filepath.Dir(path))
+	}
+
+	return filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(name)
+	})
+}
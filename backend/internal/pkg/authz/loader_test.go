@@ -0,0 +1,37 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.rego":         usersModule,
+		"nested/groups.rego": groupsModule,
+		"users_test.rego":    "package access.users_test",
+		"nested/README.md":   "not a policy",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	modules, err := LoadModules(dir)
+	require.NoError(t, err, "should load the policy directory")
+	require.Equal(t, map[string]string{
+		filepath.Join(dir, "users.rego"):         usersModule,
+		filepath.Join(dir, "nested/groups.rego"): groupsModule,
+	}, modules, "should load rego modules but tests")
+
+	_, err = LoadModules(filepath.Join(dir, "missing"))
+	require.Error(t, err, "should not load missing paths")
+
+	_, err = LoadModules(filepath.Join(dir, "nested/README.md"))
+	require.Error(t, err, "should not load files that are not bundles")
+}
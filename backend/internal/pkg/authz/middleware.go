@@ -5,13 +5,34 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"vpainless/pkg/middleware"
 )
 
-var ErrPrincipalNotFound = errors.New("no principal was found on context")
+var (
+	ErrPrincipalNotFound = errors.New("no principal was found on context")
+	ErrLockedOut         = errors.New("too many failed attempts")
+)
+
+// LockoutError is returned by authenticators when the user or the address the
+// request is sent from is locked out after failed attempts. It wraps ErrLockedOut.
+type LockoutError struct {
+	// RetryAfter is how long until the lockout ends.
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrLockedOut, e.RetryAfter)
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrLockedOut
+}
 
 type Authenticator interface {
 	Authenticate(ctx context.Context, cred middleware.Credentials) (Principal, error)
@@ -19,7 +40,8 @@ type Authenticator interface {
 
 // AuthenticationMiddleware authenticates users with their provided creds.
 // If their creds are valid, a principal is set on the context, otherwise
-// the request is rejected.
+// the request is rejected. Locked out requests are rejected with 429 and
+// the Retry-After header.
 func AuthenticationMiddleware(auth Authenticator, exclusions []middleware.Exclusion) middleware.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,13 +60,20 @@ func AuthenticationMiddleware(auth Authenticator, exclusions []middleware.Exclus
 					next.ServeHTTP(w, r)
 					return
 				}
-				writeJSONError(w, err)
+				writeJSONError(w, http.StatusUnauthorized, err)
 				return
 			}
 
 			principal, err := auth.Authenticate(ctx, creds)
 			if err != nil {
-				writeJSONError(w, fmt.Errorf("invalid username or password: %w", err))
+				var lockout *LockoutError
+				if errors.As(err, &lockout) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+					writeJSONError(w, http.StatusTooManyRequests, err)
+					return
+				}
+
+				writeJSONError(w, http.StatusUnauthorized, fmt.Errorf("invalid username or password: %w", err))
 				return
 			}
 
@@ -69,8 +98,8 @@ func GetPrincipal(ctx context.Context) (Principal, error) {
 	return principal, nil
 }
 
-func writeJSONError(w http.ResponseWriter, err error) {
+func writeJSONError(w http.ResponseWriter, status int, err error) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 }
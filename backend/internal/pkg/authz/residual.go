@@ -0,0 +1,261 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vpainless/pkg/querybuilder"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// rowRef is the unknown the policies filter the rows of the resources on, e.g.
+// input.row.group_id == input.principal.group_id. Policies are partially evaluated
+// with it, and the residual queries are translated to sql by the repositories.
+const rowRef = "input.row"
+
+// ErrUnsupportedResidual is returned when a residual query can not be translated to sql.
+var ErrUnsupportedResidual = errors.New("unsupported residual")
+
+var (
+	row = ast.MustParseRef(rowRef)
+
+	comparisons = map[string]string{
+		ast.Equality.Name:      "=",
+		ast.Equal.Name:         "=",
+		ast.NotEqual.Name:      "!=",
+		ast.LessThan.Name:      "<",
+		ast.LessThanEq.Name:    "<=",
+		ast.GreaterThan.Name:   ">",
+		ast.GreaterThanEq.Name: ">=",
+		ast.Member.Name:        "in",
+	}
+)
+
+// Columns maps the fields of the rows the policies filter on to the sql expressions
+// of a query, e.g. "group_id" to "u.group_id". Fields holding collections, used as
+// in `input.principal.id in input.row.client_ids`, are mapped to subqueries.
+type Columns map[string]string
+
+// Clause is an additional condition passed to the DB to enforce authz policies.
+// It holds the residual of the partial evaluation of a policy, which repositories
+// translate to sql with Cond.
+type Clause struct {
+	// Condition and Values are a raw sql condition, for the internal queries of
+	// the system that are not authorized by policies.
+	Condition string
+	Values    []any
+	// residual are the queries left of the partial evaluation of a policy. Rows
+	// matching any of them are allowed.
+	residual []ast.Body
+}
+
+// IsNil is used to determine if a clause should be
+// applied on the query or not.
+func (c Clause) IsNil() bool {
+	return c.Condition == "" && len(c.residual) == 0
+}
+
+// Residual returns the residual queries of the clause, in rego.
+func (c Clause) Residual() []string {
+	var result []string
+	for _, query := range c.residual {
+		result = append(result, query.String())
+	}
+	return result
+}
+
+func (c Clause) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Condition string   `json:"condition,omitempty"`
+		Values    []any    `json:"values,omitempty"`
+		Residual  []string `json:"residual,omitempty"`
+	}{c.Condition, c.Values, c.Residual()})
+}
+
+// Cond translates the clause to a condition of a query, with the sql expressions
+// the fields of the rows are mapped to.
+func (c Clause) Cond(columns Columns) (querybuilder.Cond, error) {
+	var (
+		parts []string
+		args  []any
+	)
+	if c.Condition != "" {
+		parts = append(parts, c.Condition)
+		args = append(args, c.Values...)
+	}
+
+	var queries []string
+	for _, query := range c.residual {
+		var exprs []string
+		for _, expr := range query {
+			text, exprArgs, err := translateExpr(expr, columns)
+			if err != nil {
+				return querybuilder.Cond{}, err
+			}
+			exprs = append(exprs, text)
+			args = append(args, exprArgs...)
+		}
+		queries = append(queries, strings.Join(exprs, " and "))
+	}
+	if len(queries) > 0 {
+		parts = append(parts, strings.Join(queries, " or "))
+	}
+
+	if len(parts) > 1 {
+		for i := range parts {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return querybuilder.Cond{Text: strings.Join(parts, " and "), Args: args}, nil
+}
+
+func translateExpr(expr *ast.Expr, columns Columns) (string, []any, error) {
+	if len(expr.With) > 0 {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsupportedResidual, expr)
+	}
+
+	var (
+		text string
+		args []any
+		err  error
+	)
+	switch terms := expr.Terms.(type) {
+	case []*ast.Term:
+		text, args, err = translateCall(expr, columns)
+	case *ast.Term:
+		// A bare field of the row is true when the column is.
+		text, args, err = translateOperand(terms, columns)
+	default:
+		err = ErrUnsupportedResidual
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s", err, expr)
+	}
+
+	if expr.Negated {
+		text = "not (" + text + ")"
+	}
+	return text, args, nil
+}
+
+func translateCall(expr *ast.Expr, columns Columns) (string, []any, error) {
+	operands := expr.Operands()
+	op := comparisons[expr.Operator().String()]
+	if op == "" || len(operands) != 2 {
+		return "", nil, ErrUnsupportedResidual
+	}
+
+	left, leftArgs, err := translateOperand(operands[0], columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if op == "in" {
+		return translateIn(left, leftArgs, operands[1], columns)
+	}
+
+	right, rightArgs, err := translateOperand(operands[1], columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Comparisons with null are never true in sql.
+	if column, ok := nullComparison(left, right, operands); ok {
+		switch op {
+		case "=":
+			return column + " is null", nil, nil
+		case "!=":
+			return column + " is not null", nil, nil
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s", left, op, right), append(leftArgs, rightArgs...), nil
+}
+
+func translateIn(left string, args []any, collection *ast.Term, columns Columns) (string, []any, error) {
+	if ref, ok := collection.Value.(ast.Ref); ok && ref.HasPrefix(row) {
+		column, _, err := translateOperand(collection, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s in %s", left, column), args, nil
+	}
+
+	if !collection.IsGround() {
+		return "", nil, ErrUnsupportedResidual
+	}
+
+	value, err := ast.JSON(collection.Value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return "", nil, ErrUnsupportedResidual
+	}
+	if len(items) == 0 {
+		return "0", nil, nil
+	}
+
+	for _, item := range items {
+		args = append(args, sqlValue(item))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(items)), ", ")
+	return fmt.Sprintf("%s in (%s)", left, placeholders), args, nil
+}
+
+// translateOperand returns the column of a field of the row, or a placeholder and
+// the value of a ground term.
+func translateOperand(term *ast.Term, columns Columns) (string, []any, error) {
+	if ref, ok := term.Value.(ast.Ref); ok && ref.HasPrefix(row) {
+		if len(ref) != len(row)+1 {
+			return "", nil, ErrUnsupportedResidual
+		}
+		field, ok := ref[len(row)].Value.(ast.String)
+		if !ok {
+			return "", nil, ErrUnsupportedResidual
+		}
+
+		column, ok := columns[string(field)]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: no column for %s", ErrUnsupportedResidual, field)
+		}
+		return column, nil, nil
+	}
+
+	if _, ok := term.Value.(ast.Ref); ok || !term.IsGround() {
+		return "", nil, ErrUnsupportedResidual
+	}
+
+	value, err := ast.JSON(term.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	return "?", []any{sqlValue(value)}, nil
+}
+
+func sqlValue(value any) any {
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	return value
+}
+
+// nullComparison returns the other side of a comparison with null.
+func nullComparison(left, right string, operands []*ast.Term) (string, bool) {
+	if _, ok := operands[1].Value.(ast.Null); ok {
+		return left, true
+	}
+	if _, ok := operands[0].Value.(ast.Null); ok {
+		return right, true
+	}
+	return "", false
+}
@@ -0,0 +1,22 @@
+package authz.roles
+
+import rego.v1
+
+# Roles of the users within their group, from the most privileged one.
+# Owners can not be demoted by admins, and every group has at least one.
+
+# Staff can see every resource of their group.
+staff := {"owner", "admin", "operator", "viewer"}
+
+# Operators manage the instances of their group, but not its users.
+operators := {"owner", "admin", "operator"}
+
+# Managers manage the users and the settings of their group.
+managers := {"owner", "admin"}
+
+# grantable are the roles managers can grant to the users of their group, or
+# take from them. Only owners can grant ownership, or change other owners.
+grantable := {
+	"owner": {"owner", "admin", "operator", "viewer", "client"},
+	"admin": {"admin", "operator", "viewer", "client"},
+}
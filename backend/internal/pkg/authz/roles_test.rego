@@ -0,0 +1,28 @@
+package authz_test.roles
+
+import data.authz.roles.grantable
+import data.authz.roles.managers
+import data.authz.roles.operators
+import data.authz.roles.staff
+
+test_managers_are_operators if {
+	every role in managers {
+		role in operators
+	}
+}
+
+test_operators_are_staff if {
+	every role in operators {
+		role in staff
+	}
+}
+
+test_admins_cannot_grant_ownership if {
+	not "owner" in grantable.admin
+}
+
+test_only_managers_grant_roles if {
+	every role, _ in grantable {
+		role in managers
+	}
+}
@@ -2,34 +2,34 @@ package authz
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vpainless/pkg/collect"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/topdown"
 )
 
-// Clauses are additional conditions passed to the DB
-// to enforce authz policies.
-type Clause struct {
-	Condition string `json:"condition"`
-	Values    []any  `json:"values"`
-}
-
-// IsNil is used to determine if a clause should be
-// applied on the query or not.
-func (c *Clause) IsNil() bool {
-	return c.Condition == ""
-}
-
 // Policy encapsulates the outcome of a policy evaluations.
 type Policy struct {
 	// When false, the principal is not authorized to perform
 	// the requested verb on the specified resource.
 	Allow bool `json:"allow"`
 	// Partial is an additional clause to be passed to the DB
-	// for further filtration. Partial should be discarded when
-	// Allow is false.
+	// for further filtration. It is nil when the principal is allowed
+	// on every row. Partial should be discarded when Allow is false.
 	Partial Clause `json:"partial"`
 }
 
@@ -59,31 +59,190 @@ func (i input) String() string {
 // to perform requested actions, or allowing partial execution of the actions
 // by passing additional DB clauses.
 type Validator struct {
-	defaultOptions []func(*rego.Rego)
-	pkgname        string
+	pkgname string
+	// embedded are the modules the validator is created with, by path.
+	embedded map[string]string
+	// decisions logs the decisions of the groups that enabled it. Nil logs none.
+	decisions *DecisionLogger
+	// policies is the set of modules checks are evaluated against. It is swapped
+	// as a whole on reload, so checks never see a partially loaded set.
+	policies atomic.Pointer[policySet]
 }
 
+// policySet is a loaded set of modules along with their prepared queries.
+type policySet struct {
+	modules map[string]string
+	// compile compiles the modules once, for the queries of every resource group.
+	compile func() (*ast.Compiler, error)
+	// queries are the prepared queries of the resource groups. Preparing is only
+	// done on the first check of each group.
+	queries collect.Map[string, rego.PreparedPartialQuery]
+}
+
+func newPolicySet(modules map[string]string) *policySet {
+	return &policySet{
+		modules: modules,
+		compile: sync.OnceValues(func() (*ast.Compiler, error) {
+			return ast.CompileModules(modules)
+		}),
+	}
+}
+
+// rolesModule defines the sets of roles the policies of every package refer to,
+// as data.authz.roles.
+//
+//go:embed roles.rego
+var rolesModule string
+
 type ValidatorOption func(e *Validator)
 
 func WithRegoModule(path, content string) ValidatorOption {
 	return func(v *Validator) {
-		v.defaultOptions = append(v.defaultOptions, rego.Module(path, content))
+		v.embedded[path] = content
 	}
 }
 
 // NewValidator creates a new authz validator
 func NewValidator(pkgname string, opts ...ValidatorOption) *Validator {
-	v := &Validator{pkgname: pkgname}
+	v := &Validator{pkgname: pkgname, embedded: map[string]string{"authz/roles.rego": rolesModule}}
 
 	for _, opt := range opts {
 		opt(v)
 	}
 
+	v.policies.Store(newPolicySet(v.embedded))
 	return v
 }
 
+// Reload replaces the loaded policies with the embedded modules, overridden or
+// extended by the external ones. An external module overrides the embedded modules
+// of its package, and extends the policies otherwise. External modules of packages
+// other than the validator's are ignored, so the same set can be passed to every
+// validator. When the set does not pass Check, the error is returned and the last
+// loaded set is kept.
+func (v *Validator) Reload(ctx context.Context, external map[string]string) error {
+	set, err := v.load(ctx, external)
+	if err != nil {
+		return err
+	}
+
+	v.policies.Store(set)
+	slog.InfoContext(ctx, "authz: policies reloaded", "package", v.pkgname, "modules", len(set.modules))
+	return nil
+}
+
+// Check reports whether Reload would accept the external modules, without loading
+// them. The set is compiled, and every resource group is checked to still define
+// allow, and to keep its rules on the rows inlinable. The residuals are evaluated
+// without a principal, and are not checked against the columns of the storage; a
+// row field without a column only fails the queries it ends up in.
+func (v *Validator) Check(ctx context.Context, external map[string]string) error {
+	_, err := v.load(ctx, external)
+	return err
+}
+
+func (v *Validator) load(ctx context.Context, external map[string]string) (*policySet, error) {
+	root := ast.MustParseRef("data." + v.pkgname)
+	overridden := map[string]bool{}
+	modules := map[string]string{}
+	parsed := map[string]*ast.Module{}
+	for path, content := range external {
+		module, err := ast.ParseModule(path, content)
+		if err != nil {
+			return nil, err
+		}
+		if !module.Package.Path.HasPrefix(root) {
+			continue
+		}
+
+		overridden[module.Package.Path.String()] = true
+		modules[path] = content
+		parsed[path] = module
+	}
+
+	for path, content := range v.embedded {
+		module, err := ast.ParseModule(path, content)
+		if err != nil {
+			return nil, err
+		}
+		if overridden[module.Package.Path.String()] {
+			continue
+		}
+		modules[path] = content
+		parsed[path] = module
+	}
+
+	set := newPolicySet(modules)
+	if _, err := set.compile(); err != nil {
+		return nil, err
+	}
+
+	for _, rgroup := range v.resourceGroups(parsed) {
+		if _, err := set.eval(ctx, v.pkgname, input{ResourceGroup: rgroup}); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// ErrUndefinedPolicy is returned when the policies of a resource group do not
+// define allow.
+var ErrUndefinedPolicy = errors.New("allow is not defined")
+
+// resourceGroups returns the resource groups the modules define policies for, that
+// is, the packages right under the validator's.
+func (v *Validator) resourceGroups(modules map[string]*ast.Module) []string {
+	root := ast.MustParseRef("data." + v.pkgname)
+	groups := map[string]bool{}
+	for _, module := range modules {
+		path := module.Package.Path
+		if len(path) <= len(root) || !path.HasPrefix(root) {
+			continue
+		}
+		if group, ok := path[len(root)].Value.(ast.String); ok {
+			groups[string(group)] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(groups))
+}
+
 // Can verifies if the principal can perform the requested verb on the specified resource.
 func (v *Validator) Can(ctx context.Context, p Principal, op Verb, resourcer Resourcer) (Policy, error) {
+	input := newInput(p, op, resourcer)
+
+	start := time.Now()
+	policy, err := v.policies.Load().eval(ctx, v.pkgname, input)
+	if v.decisions.Enabled(p.GroupID) {
+		v.decisions.Log(ctx, newDecision(v.pkgname, input, policy, err, time.Since(start)))
+	}
+
+	return policy, err
+}
+
+// Explain replays the decision on the principal performing the verb on the resource,
+// along with the trace of the evaluation. It is meant for debugging the policies.
+func (v *Validator) Explain(ctx context.Context, p Principal, op Verb, resourcer Resourcer) (*Explanation, error) {
+	input := newInput(p, op, resourcer)
+	tracer := topdown.NewBufferTracer()
+
+	start := time.Now()
+	policy, err := v.policies.Load().eval(ctx, v.pkgname, input, rego.EvalQueryTracer(tracer))
+	if errors.Is(err, ErrUndefinedPolicy) {
+		return nil, err
+	}
+
+	var trace strings.Builder
+	topdown.PrettyTraceWithLocation(&trace, *tracer)
+
+	return &Explanation{
+		Decision: newDecision(v.pkgname, input, policy, err, time.Since(start)),
+		Trace:    strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n"),
+	}, nil
+}
+
+func newInput(p Principal, op Verb, resourcer Resourcer) input {
 	rgroup, rvalue := resourcer.Resource()
 
 	input := input{
@@ -107,36 +266,67 @@ func (v *Validator) Can(ctx context.Context, p Principal, op Verb, resourcer Res
 		input.Principal["group_id"] = p.GroupID
 	}
 
-	// TODO: remove this
-	fmt.Println(input)
-
-	options := append(v.defaultOptions,
-		rego.Query(fmt.Sprintf(`policy := {"allow": data.%[1]s.%[2]s.allow, "partial": data.%[1]s.%[2]s.partial}`, v.pkgname, rgroup)),
-	)
-	query := rego.New(options...)
+	return input
+}
 
-	prepared, err := query.PrepareForEval(ctx)
+// eval partially evaluates the allow rule of the resource group, with the rows of
+// the resources unknown. The principal is allowed on the rows matching any of the
+// residual queries; no query denies, and an empty one allows every row.
+func (s *policySet) eval(ctx context.Context, pkgname string, input input, opts ...rego.EvalOption) (Policy, error) {
+	prepared, err := s.prepare(ctx, pkgname, input.ResourceGroup)
 	if err != nil {
-		return Policy{}, err
+		return Policy{}, fmt.Errorf("policies of %s: %w", input.ResourceGroup, err)
 	}
 
-	result, err := prepared.Eval(ctx, rego.EvalInput(input))
+	result, err := prepared.Partial(ctx, append(opts, rego.EvalInput(input))...)
 	if err != nil {
-		return Policy{}, err
+		return Policy{}, fmt.Errorf("policies of %s: %w", input.ResourceGroup, err)
+	}
+
+	if len(result.Support) > 0 {
+		return Policy{}, fmt.Errorf("policies of %s: %w: rules on rows should be inlinable", input.ResourceGroup, ErrUnsupportedResidual)
+	}
+
+	if len(result.Queries) == 0 {
+		return Policy{}, nil
+	}
+
+	for _, query := range result.Queries {
+		if len(query) == 0 {
+			return Policy{Allow: true}, nil
+		}
 	}
 
-	if len(result) == 0 {
-		return Policy{}, fmt.Errorf("unexpected empty policy result")
+	return Policy{Allow: true, Partial: Clause{residual: result.Queries}}, nil
+}
+
+// prepare returns the prepared query of the resource group, and prepares it on the
+// first call. Prepared queries are safe to be evaluated concurrently. Concurrent
+// first calls may prepare the query more than once, which is harmless.
+func (s *policySet) prepare(ctx context.Context, pkgname, rgroup string) (rego.PreparedPartialQuery, error) {
+	if prepared, ok := s.queries.Load(rgroup); ok {
+		return prepared, nil
 	}
 
-	b, err := json.Marshal(result[0].Bindings["policy"])
+	compiler, err := s.compile()
 	if err != nil {
-		return Policy{}, err
+		return rego.PreparedPartialQuery{}, err
 	}
 
-	var policy Policy
-	if err = json.Unmarshal(b, &policy); err != nil {
-		return Policy{}, err
+	allow := ast.MustParseRef(fmt.Sprintf("data.%s.%s.allow", pkgname, rgroup))
+	if len(compiler.GetRulesExact(allow)) == 0 {
+		return rego.PreparedPartialQuery{}, ErrUndefinedPolicy
 	}
-	return policy, nil
+
+	prepared, err := rego.New(
+		rego.Compiler(compiler),
+		rego.Query(fmt.Sprintf("%s == true", allow)),
+		rego.Unknowns([]string{rowRef}),
+	).PrepareForPartial(ctx)
+	if err != nil {
+		return rego.PreparedPartialQuery{}, err
+	}
+
+	s.queries.Store(rgroup, prepared)
+	return prepared, nil
 }
@@ -1,15 +1,21 @@
 package authz
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"sync"
 	"testing"
 
+	"vpainless/pkg/querybuilder"
+
 	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 var content string = `
-package access
+package access.users
 
 import rego.v1
 
@@ -19,26 +25,15 @@ default allow := false
 # Allow if the user is requesting their own data
 allow if {
 	input.principal.role == "user"
-	input.resource.type == "users"
 	input.action in ["view"]
-	input.principal.id == input.resource.value
+	input.principal.id == input.resource.id
 }
 
 # Allow if the user is an admin and the requested user is in the same group
 allow if {
 	input.principal.role == "admin"
-	input.resource.type == "users"
 	input.action in ["view"]
-}
-
-partial := clause if {
-	input.principal.role == "admin"
-	input.resource.type == "users"
-	input.action in ["view"]
-	clause := {
-		"condition": "g.id = ?",
-		"values": [input.principal.group_id],
-	}
+	input.row.group_id == input.principal.group_id
 }
 `
 
@@ -52,15 +47,325 @@ func TestPolicyMaking(t *testing.T) {
 	}
 	resouceID := uuid.FromStringOrNil("ffffffff-ffff-ffff-ffff-ffffffffffff")
 	e := NewValidator("access", WithRegoModule("", content))
-	expect := Policy{
-		Allow: true,
-		Partial: Clause{
-			Condition: "g.id = ?",
-			Values:    []any{"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"},
-		},
-	}
 
 	policy, err := e.Can(ctx, p, "view", ResourceID("users", resouceID))
 	require.NoError(t, err, "should evaluate correctly")
-	require.Equal(t, expect, policy, "policy should match")
+	require.True(t, policy.Allow, "should allow admins")
+
+	cond, err := policy.Partial.Cond(Columns{"group_id": "g.id"})
+	require.NoError(t, err, "should translate the partial")
+	require.Equal(t, querybuilder.Cond{
+		Text: "? = g.id",
+		Args: []any{"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"},
+	}, cond, "partial should match")
+
+	_, err = policy.Partial.Cond(Columns{})
+	require.ErrorIs(t, err, ErrUnsupportedResidual, "should not translate unmapped fields")
+
+	p.Role = "user"
+	p.ID = resouceID
+	policy, err = e.Can(ctx, p, "view", ResourceID("users", resouceID))
+	require.NoError(t, err, "should evaluate correctly")
+	require.True(t, policy.Allow, "should allow users viewing themselves")
+	require.True(t, policy.Partial.IsNil(), "should allow users on every row")
+
+	policy, err = e.Can(ctx, p, "delete", ResourceID("users", resouceID))
+	require.NoError(t, err, "should evaluate correctly")
+	require.False(t, policy.Allow, "should deny unknown actions")
+}
+
+func TestResidualCond(t *testing.T) {
+	ctx := context.Background()
+
+	module := `
+package access.instances
+
+import rego.v1
+
+default allow := false
+
+allow if {
+	input.principal.role == "client"
+	input.row.user_id == input.principal.id
+}
+
+allow if {
+	input.principal.role == "client"
+	input.principal.id in input.row.client_ids
+}
+
+allow if {
+	input.principal.role == "admin"
+	input.row.group_id == input.principal.group_id
+	input.row.region in ["fra", "ams"]
+	input.row.cost < 500
+	not input.row.deleted_at == null
+}
+`
+	columns := Columns{
+		"user_id":    "i.user_id",
+		"client_ids": "(select user_id from instance_clients where instance_id = i.id)",
+		"group_id":   "u.group_id",
+		"region":     "i.region",
+		"cost":       "i.monthly_cost_cents",
+		"deleted_at": "i.deleted_at",
+	}
+	v := NewValidator("access", WithRegoModule("instances.rego", module))
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Client,
+	}
+
+	policy, err := v.Can(ctx, p, List, Resource{Group: "instances"})
+	require.NoError(t, err, "should evaluate instances policy")
+	require.True(t, policy.Allow, "should allow clients on some rows")
+
+	cond, err := policy.Partial.Cond(columns)
+	require.NoError(t, err, "should translate the residual")
+	require.Equal(t, querybuilder.Cond{
+		Text: "? = i.user_id or ? in (select user_id from instance_clients where instance_id = i.id)",
+		Args: []any{p.ID.String(), p.ID.String()},
+	}, cond, "should join the residual queries with or")
+
+	p.Role = Admin
+	policy, err = v.Can(ctx, p, List, Resource{Group: "instances"})
+	require.NoError(t, err, "should evaluate instances policy")
+
+	policy.Partial.Condition = "i.deleted_at is null"
+	cond, err = policy.Partial.Cond(columns)
+	require.NoError(t, err, "should translate the residual")
+	require.Equal(t, querybuilder.Cond{
+		Text: "(i.deleted_at is null) and (? = u.group_id and i.region in (?, ?) and i.monthly_cost_cents < ? and not (i.deleted_at is null))",
+		Args: []any{p.GroupID.String(), "fra", "ams", int64(500)},
+	}, cond, "should join the expressions with and")
+}
+
+var usersModule string = `
+package access.users
+
+import rego.v1
+
+default allow := false
+
+allow if {
+	input.action = "get"
+	input.principal.role = "admin"
+	input.row.group_id = input.principal.group_id
+}
+`
+
+var groupsModule string = `
+package access.groups
+
+import rego.v1
+
+default allow := false
+
+allow if {
+	input.action = "get"
+	input.principal.id = input.resource.id
+}
+`
+
+func TestPreparedQueries(t *testing.T) {
+	ctx := context.Background()
+
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Admin,
+	}
+	resourceID := uuid.FromStringOrNil("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	v := NewValidator("access", WithRegoModule("users.rego", usersModule), WithRegoModule("groups.rego", groupsModule))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				policy, err := v.Can(ctx, p, Get, ResourceID("users", resourceID))
+				assert.NoError(t, err, "should evaluate users policy correctly")
+				assert.True(t, policy.Allow, "admins should be able to get users")
+
+				policy, err = v.Can(ctx, p, Get, ResourceID("groups", resourceID))
+				assert.NoError(t, err, "should evaluate groups policy correctly")
+				assert.False(t, policy.Allow, "admins should not be able to get other groups")
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 2, v.policies.Load().queries.Len(), "should prepare a query per resource group")
+}
+
+func BenchmarkCan(b *testing.B) {
+	ctx := context.Background()
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Admin,
+	}
+	resource := ResourceID("users", uuid.FromStringOrNil("ffffffff-ffff-ffff-ffff-ffffffffffff"))
+	opts := []ValidatorOption{WithRegoModule("users.rego", usersModule), WithRegoModule("groups.rego", groupsModule)}
+
+	// Uncached prepares the query on every check, as a new validator does.
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := NewValidator("access", opts...).Can(ctx, p, Get, resource); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		v := NewValidator("access", opts...)
+		for b.Loop() {
+			if _, err := v.Can(ctx, p, Get, resource); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Admin,
+	}
+	resourceID := uuid.FromStringOrNil("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	v := NewValidator("access", WithRegoModule("users.rego", usersModule), WithRegoModule("groups.rego", groupsModule))
+
+	// The override denies admins getting users, and a new policy for probes is added.
+	external := map[string]string{
+		"policies/users.rego": `
+package access.users
+
+import rego.v1
+
+default allow := false
+`,
+		"policies/probes.rego": `
+package access.probes
+
+import rego.v1
+
+default allow := true
+`,
+		"policies/other.rego": `
+package hosting.users
+
+import rego.v1
+
+default allow := true
+`,
+	}
+
+	policy, err := v.Can(ctx, p, Get, ResourceID("users", resourceID))
+	require.NoError(t, err, "should evaluate embedded users policy")
+	require.True(t, policy.Allow, "embedded policy should allow admins to get users")
+
+	require.NoError(t, v.Reload(ctx, external), "should reload the external policies")
+
+	policy, err = v.Can(ctx, p, Get, ResourceID("users", resourceID))
+	require.NoError(t, err, "should evaluate overridden users policy")
+	require.False(t, policy.Allow, "overridden policy should not allow admins to get users")
+
+	policy, err = v.Can(ctx, p, Get, ResourceID("probes", resourceID))
+	require.NoError(t, err, "should evaluate extended probes policy")
+	require.True(t, policy.Allow, "extended policy should allow getting probes")
+
+	policy, err = v.Can(ctx, p, Get, ResourceID("groups", resourceID))
+	require.NoError(t, err, "should evaluate embedded groups policy")
+	require.False(t, policy.Allow, "embedded groups policy should be kept")
+
+	for name, modules := range map[string]map[string]string{
+		"invalid syntax": {"policies/users.rego": "package access.users\n\nallow if {"},
+		"unsafe rule":    {"policies/users.rego": "package access.users\n\nimport rego.v1\n\nallow if x > 1\n"},
+		"missing allow": {"policies/users.rego": `
+package access.users
+
+import rego.v1
+
+deny := true
+`},
+	} {
+		require.Error(t, v.Check(ctx, modules), "%s: check should refuse the policies", name)
+		require.Error(t, v.Reload(ctx, modules), "%s: reload should refuse the policies", name)
+
+		policy, err = v.Can(ctx, p, Get, ResourceID("probes", resourceID))
+		require.NoError(t, err, "%s: should evaluate the last loaded policies", name)
+		require.True(t, policy.Allow, "%s: last loaded policies should be kept", name)
+	}
+}
+
+func TestDecisionLogs(t *testing.T) {
+	ctx := context.Background()
+
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Admin,
+	}
+	resource := Resource{Group: "users", Value: map[string]any{
+		"id":       "ffffffff-ffff-ffff-ffff-ffffffffffff",
+		"password": "secret password",
+		"provider": map[string]any{"api_key": "secret key"},
+	}}
+
+	var out bytes.Buffer
+	logger := NewDecisionLogger(&out)
+	v := NewValidator("access", WithRegoModule("users.rego", usersModule), WithRegoModule("groups.rego", groupsModule))
+	v.LogDecisions(logger)
+
+	_, err := v.Can(ctx, p, Get, resource)
+	require.NoError(t, err, "should evaluate users policy")
+	require.Zero(t, out.Len(), "should not log decisions of groups that did not enable it")
+
+	logger.Enable(p.GroupID, true)
+	policy, err := v.Can(ctx, p, Get, resource)
+	require.NoError(t, err, "should evaluate users policy")
+
+	var line struct {
+		Decision Decision `json:"decision"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line), "should log decisions as json")
+	require.Equal(t, "access", line.Decision.Package, "should log the package")
+	require.Equal(t, "users", line.Decision.ResourceGroup, "should log the resource group")
+	require.Equal(t, policy.Allow, line.Decision.Result.Allow, "should log the result")
+	require.Equal(t, map[string]any{
+		"id":       "ffffffff-ffff-ffff-ffff-ffffffffffff",
+		"password": redacted,
+		"provider": map[string]any{"api_key": redacted},
+	}, line.Decision.Input["resource"], "should redact the sensitive fields")
+
+	logger.Enable(p.GroupID, false)
+	out.Reset()
+	_, err = v.Can(ctx, p, Get, resource)
+	require.NoError(t, err, "should evaluate users policy")
+	require.Zero(t, out.Len(), "should not log decisions once disabled")
+}
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+
+	p := Principal{
+		ID:      uuid.FromStringOrNil("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		GroupID: uuid.FromStringOrNil("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		Role:    Admin,
+	}
+	resourceID := uuid.FromStringOrNil("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	v := NewValidator("access", WithRegoModule("users.rego", usersModule), WithRegoModule("groups.rego", groupsModule))
+
+	explanation, err := v.Explain(ctx, p, Get, ResourceID("groups", resourceID))
+	require.NoError(t, err, "should explain groups policy")
+	require.False(t, explanation.Decision.Result.Allow, "should replay the decision")
+	require.NotEmpty(t, explanation.Trace, "should trace the evaluation")
+
+	_, err = v.Explain(ctx, p, Get, ResourceID("probes", resourceID))
+	require.ErrorIs(t, err, ErrUndefinedPolicy, "should not explain undefined policies")
 }
@@ -1,5 +1,8 @@
 begin;
 
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
 drop table if exists access.users;
 drop table if exists access.groups;
 
@@ -79,3 +79,6 @@ create table if not exists hosting.instances (
 create unique index hosting.idx_unique_user_id_not_deleted on instances (user_id) where deleted_at is null;
 
 commit;
+
+detach database access;
+detach database hosting;
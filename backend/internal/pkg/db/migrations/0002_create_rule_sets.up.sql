@@ -0,0 +1,40 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+create table if not exists hosting.rule_sets (
+	id uuid not null primary key default (gen_uuid_v4()),
+	group_id uuid not null,
+	name text not null,
+	preset text,
+	enabled integer not null default 1,
+	block text not null default '{}',
+	direct text not null default '{}',
+	warp text,
+	created_at text not null,
+	updated_at text not null,
+	foreign key (group_id) references groups(id)
+);
+
+-- Routing rules used to be hard coded in the xray template for Iran.
+-- Existing groups keep the same rules through the Iran preset.
+insert into hosting.rule_sets (id, group_id, name, preset, enabled, block, created_at, updated_at)
+select
+	lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))),
+	g.id,
+	'Iran',
+	'ir',
+	1,
+	'{"domains":["geosite:category-ir","snapp","digikala","tapsi","blogfa","bank","sb24.com","sheypoor.com","tebyan.net","beytoote.com","telewebion.com","Film2movie.ws","Setare.com","Filimo.com","Torob.com","Tgju.org","Sarzamindownload.com","downloadha.com","P30download.com","Sanjesh.org","domain:intrack.ir","domain:divar.ir","domain:irancell.ir","domain:yooz.ir","domain:iran-cell.com","domain:irancell.i-r","domain:shaparak.ir","domain:learnit.ir","domain:baadesaba.ir","domain:webgozar.ir","domain:dt.beyla.site"],"ips":["geoip:ir"]}',
+	datetime('now'),
+	datetime('now')
+from hosting.groups g;
+
+commit;
+
+detach database access;
+detach database hosting;
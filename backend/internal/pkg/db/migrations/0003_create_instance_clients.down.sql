@@ -0,0 +1,22 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_unique_client_user_id_not_revoked;
+drop table if exists hosting.instance_clients;
+
+alter table hosting.instances drop column short_id;
+alter table hosting.instances drop column reality_public_key;
+alter table hosting.instances drop column reality_private_key;
+alter table hosting.instances drop column fake_url;
+alter table hosting.instances drop column client_id;
+alter table hosting.instances drop column shared;
+
+alter table hosting.groups drop column max_clients_per_instance;
+alter table hosting.groups drop column shared_instances;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,34 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups add column shared_instances integer not null default 0;
+alter table hosting.groups add column max_clients_per_instance integer not null default 5;
+
+-- Reality parameters are kept, so the config of shared instances can be
+-- rendered again when their clients change.
+alter table hosting.instances add column shared integer not null default 0;
+alter table hosting.instances add column client_id uuid;
+alter table hosting.instances add column fake_url text;
+alter table hosting.instances add column reality_private_key text;
+alter table hosting.instances add column reality_public_key text;
+alter table hosting.instances add column short_id text;
+
+create table if not exists hosting.instance_clients (
+	id uuid not null primary key default (gen_uuid_v4()),
+	instance_id uuid not null,
+	user_id uuid not null,
+	created_at text not null,
+	revoked_at text,
+	foreign key (instance_id) references instances(id),
+	foreign key (user_id) references users(id)
+);
+
+create unique index hosting.idx_unique_client_user_id_not_revoked on instance_clients (user_id) where revoked_at is null;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,11 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups drop column sni_pool;
+
+commit;
+
+detach database access;
+detach database hosting;
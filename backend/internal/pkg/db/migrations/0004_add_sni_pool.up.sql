@@ -0,0 +1,12 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups add column sni_pool text not null default '[]';
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,12 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_health_checks_instance_id_checked_at;
+drop table if exists hosting.health_checks;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,22 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+create table if not exists hosting.health_checks (
+	instance_id uuid not null,
+	checked_at text not null,
+	tcp integer not null,
+	reality integer not null,
+	xray integer not null,
+	error text,
+	foreign key (instance_id) references instances(id)
+);
+
+create index hosting.idx_health_checks_instance_id_checked_at on health_checks (instance_id, checked_at);
+
+commit;
+
+detach database access;
+detach database hosting;
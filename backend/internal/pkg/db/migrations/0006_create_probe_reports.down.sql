@@ -0,0 +1,14 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups drop column min_probe_reporters;
+
+drop index if exists hosting.idx_probe_reports_reported_at;
+drop table if exists hosting.probe_reports;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,27 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+create table if not exists hosting.probe_reports (
+	instance_id uuid not null,
+	user_id uuid not null,
+	ip text,
+	reported_at text not null,
+	tcp integer not null,
+	tls integer not null,
+	latency_ms integer not null default 0,
+	error text,
+	foreign key (instance_id) references instances(id),
+	foreign key (user_id) references users(id)
+);
+
+create index hosting.idx_probe_reports_reported_at on probe_reports (reported_at);
+
+alter table hosting.groups add column min_probe_reporters integer not null default 3;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,23 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_renewals_group_id_created_at;
+drop table if exists hosting.renewals;
+
+drop index if exists hosting.idx_unique_replaces_not_deleted;
+drop index if exists hosting.idx_unique_user_id_not_deleted;
+create unique index hosting.idx_unique_user_id_not_deleted on instances (user_id) where deleted_at is null;
+
+alter table hosting.instances drop column replaces;
+alter table hosting.instances drop column region;
+
+alter table hosting.groups drop column regions;
+alter table hosting.groups drop column max_renewals_per_day;
+alter table hosting.groups drop column auto_renew;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,39 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups add column auto_renew integer not null default 0;
+alter table hosting.groups add column max_renewals_per_day integer not null default 3;
+alter table hosting.groups add column regions text not null default '[]';
+
+alter table hosting.instances add column region text;
+alter table hosting.instances add column replaces uuid;
+
+-- Replacements live alongside the instance they replace until they are set up,
+-- so they are not counted as the instance of the user until then.
+drop index if exists hosting.idx_unique_user_id_not_deleted;
+create unique index hosting.idx_unique_user_id_not_deleted on instances (user_id) where deleted_at is null and replaces is null;
+
+-- An instance has at most one live replacement at a time.
+create unique index hosting.idx_unique_replaces_not_deleted on instances (replaces) where deleted_at is null and replaces is not null;
+
+create table if not exists hosting.renewals (
+	id uuid not null primary key default (gen_uuid_v4()),
+	group_id uuid not null,
+	instance_id uuid not null,
+	replacement_id uuid not null,
+	reason text not null,
+	created_at text not null,
+	foreign key (group_id) references groups(id),
+	foreign key (instance_id) references instances(id),
+	foreign key (replacement_id) references instances(id)
+);
+
+create index hosting.idx_renewals_group_id_created_at on renewals (group_id, created_at);
+
+commit;
+
+detach database access;
+detach database hosting;
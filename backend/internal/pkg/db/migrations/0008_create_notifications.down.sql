@@ -0,0 +1,15 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_notifications_user_id_created_at;
+drop table if exists hosting.notifications;
+
+alter table hosting.groups drop column ttl_hours;
+alter table hosting.groups drop column rotation_interval_hours;
+
+commit;
+
+detach database access;
+detach database hosting;
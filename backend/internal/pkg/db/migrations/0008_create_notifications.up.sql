@@ -0,0 +1,27 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Zero disables the rotation, or the expiry, of the group instances.
+alter table hosting.groups add column rotation_interval_hours integer not null default 0;
+alter table hosting.groups add column ttl_hours integer not null default 0;
+
+create table if not exists hosting.notifications (
+	id uuid not null primary key default (gen_uuid_v4()),
+	user_id uuid not null,
+	instance_id uuid not null,
+	kind text not null,
+	message text not null,
+	created_at text not null,
+	foreign key (user_id) references users(id),
+	foreign key (instance_id) references instances(id)
+);
+
+create index hosting.idx_notifications_user_id_created_at on notifications (user_id, created_at);
+
+commit;
+
+detach database access;
+detach database hosting;
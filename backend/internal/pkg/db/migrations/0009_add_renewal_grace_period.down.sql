@@ -0,0 +1,14 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_instances_retire_at;
+
+alter table hosting.instances drop column retire_at;
+alter table hosting.groups drop column renewal_grace_minutes;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,18 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups add column renewal_grace_minutes integer not null default 60;
+
+-- Replaced instances are deleted right away, but their servers are kept
+-- running until retire_at, so their users can switch to the replacement.
+alter table hosting.instances add column retire_at text;
+
+create index hosting.idx_instances_retire_at on instances (retire_at) where retire_at is not null;
+
+commit;
+
+detach database access;
+detach database hosting;
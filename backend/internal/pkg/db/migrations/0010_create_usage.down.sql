@@ -0,0 +1,13 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop table if exists hosting.usage_counters;
+drop index if exists hosting.idx_usage_user_id_collected_at;
+drop table if exists hosting.usage;
+
+commit;
+
+detach database access;
+detach database hosting;
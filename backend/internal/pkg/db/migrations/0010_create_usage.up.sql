@@ -0,0 +1,35 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Each row is the traffic of a user on an instance since the previous collection, in bytes.
+create table if not exists hosting.usage (
+	user_id uuid not null,
+	instance_id uuid not null,
+	collected_at text not null,
+	uplink integer not null default 0,
+	downlink integer not null default 0,
+	foreign key (user_id) references users(id),
+	foreign key (instance_id) references instances(id)
+);
+
+create index hosting.idx_usage_user_id_collected_at on usage (user_id, collected_at);
+
+-- The cumulative counters last read from xray, per user on an instance. Samples are
+-- the difference to them, so the counters are never reset on the instance.
+create table if not exists hosting.usage_counters (
+	instance_id uuid not null,
+	user_id uuid not null,
+	uplink integer not null default 0,
+	downlink integer not null default 0,
+	primary key (instance_id, user_id),
+	foreign key (user_id) references users(id),
+	foreign key (instance_id) references instances(id)
+);
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,15 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_quotas_suspended_until;
+drop table if exists hosting.quotas;
+
+alter table hosting.groups drop column active_days_quota;
+alter table hosting.groups drop column traffic_quota_bytes;
+
+commit;
+
+detach database access;
+detach database hosting;
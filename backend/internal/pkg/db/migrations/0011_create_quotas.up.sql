@@ -0,0 +1,27 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Monthly quota of each user of the group. Zero is unlimited.
+alter table hosting.groups add column traffic_quota_bytes integer not null default 0;
+alter table hosting.groups add column active_days_quota integer not null default 0;
+
+-- The quota overrides of the users, and the state of their suspension.
+-- A null quota inherits the quota of the group.
+create table if not exists hosting.quotas (
+	user_id uuid not null primary key,
+	traffic_bytes integer,
+	active_days integer,
+	suspended_until text,
+	lifted_until text,
+	foreign key (user_id) references users(id)
+);
+
+create index hosting.idx_quotas_suspended_until on quotas (suspended_until);
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,14 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups drop column monthly_budget_cents;
+
+alter table hosting.instances drop column monthly_cost_cents;
+alter table hosting.instances drop column plan;
+
+commit;
+
+detach database access;
+detach database hosting;
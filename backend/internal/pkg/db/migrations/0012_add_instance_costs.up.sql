@@ -0,0 +1,17 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- The plan of the instances and its price when they were created, in cents.
+alter table hosting.instances add column plan text not null default '';
+alter table hosting.instances add column monthly_cost_cents integer not null default 0;
+
+-- Zero is unlimited.
+alter table hosting.groups add column monthly_budget_cents integer not null default 0;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,17 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop table if exists hosting.rate_limit_overrides;
+drop table if exists hosting.rate_limit_buckets;
+
+alter table hosting.groups drop column group_rate_per_hour;
+alter table hosting.groups drop column group_rate_burst;
+alter table hosting.groups drop column user_rate_per_hour;
+alter table hosting.groups drop column user_rate_burst;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,31 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Token buckets of the instance changes; zero per hour is unlimited.
+alter table hosting.groups add column user_rate_burst integer not null default 3;
+alter table hosting.groups add column user_rate_per_hour integer not null default 3;
+alter table hosting.groups add column group_rate_burst integer not null default 20;
+alter table hosting.groups add column group_rate_per_hour integer not null default 20;
+
+-- Buckets are keyed by user or group, e.g. 'user:<id>'.
+create table if not exists hosting.rate_limit_buckets (
+	key text not null primary key,
+	tokens real not null,
+	updated_at text not null
+);
+
+-- The rate limit overrides of the users; users without one inherit the limit of the group.
+create table if not exists hosting.rate_limit_overrides (
+	user_id uuid not null primary key,
+	burst integer not null,
+	per_hour integer not null,
+	foreign key (user_id) references users(id)
+);
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,15 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_instance_requests_group_status;
+drop index if exists hosting.idx_instance_requests_user_status;
+drop table if exists hosting.instance_requests;
+
+alter table hosting.groups drop column require_approval;
+
+commit;
+
+detach database access;
+detach database hosting;
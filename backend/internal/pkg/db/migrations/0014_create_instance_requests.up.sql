@@ -0,0 +1,29 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups add column require_approval integer not null default 0;
+
+-- The instances requested by the clients of the groups that require approval.
+create table if not exists hosting.instance_requests (
+	id uuid not null primary key,
+	user_id uuid not null,
+	group_id uuid not null,
+	status text not null,
+	created_at text not null,
+	decided_at text,
+	decided_by uuid,
+	instance_id uuid,
+	foreign key (user_id) references users(id),
+	foreign key (group_id) references groups(id)
+);
+
+create index hosting.idx_instance_requests_user_status on instance_requests (user_id, status);
+create index hosting.idx_instance_requests_group_status on instance_requests (group_id, status);
+
+commit;
+
+detach database access;
+detach database hosting;
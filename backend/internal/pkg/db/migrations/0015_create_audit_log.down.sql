@@ -0,0 +1,14 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop trigger if exists hosting.audit_log_no_delete;
+drop trigger if exists hosting.audit_log_no_update;
+drop index if exists hosting.idx_audit_log_group_created_at;
+drop table if exists hosting.audit_log;
+
+commit;
+
+detach database access;
+detach database hosting;
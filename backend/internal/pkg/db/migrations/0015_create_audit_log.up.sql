@@ -0,0 +1,36 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Who changed what. The users may be deleted later, so the principals are not
+-- foreign keys; the entries outlive them.
+create table if not exists hosting.audit_log (
+	id uuid not null primary key,
+	created_at text not null,
+	request_id uuid,
+	principal_id uuid,
+	group_id uuid,
+	action text not null,
+	target text not null,
+	diff text not null
+);
+
+create index hosting.idx_audit_log_group_created_at on audit_log (group_id, created_at);
+
+-- The audit log is append-only.
+create trigger hosting.audit_log_no_update before update on audit_log
+begin
+	select raise(abort, 'audit log is append-only');
+end;
+
+create trigger hosting.audit_log_no_delete before delete on audit_log
+begin
+	select raise(abort, 'audit log is append-only');
+end;
+
+commit;
+
+detach database access;
+detach database hosting;
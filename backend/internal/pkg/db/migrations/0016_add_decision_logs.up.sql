@@ -0,0 +1,13 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Logs the policy decisions of the principals of the group.
+alter table hosting.groups add column decision_logs integer not null default 0;
+
+commit;
+
+detach database access;
+detach database hosting;
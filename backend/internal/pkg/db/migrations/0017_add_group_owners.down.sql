@@ -0,0 +1,15 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+update access.users set role = 'admin' where role = 'owner';
+update access.users set role = 'client' where role in ('operator', 'viewer');
+
+update hosting.users set role = 'admin' where role = 'owner';
+update hosting.users set role = 'client' where role in ('operator', 'viewer');
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,21 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Every group has at least one owner. The first admin of each group becomes its owner.
+update access.users set role = 'owner'
+where rowid in (
+	select min(rowid) from access.users
+	where role = 'admin' and group_id is not null
+	group by group_id
+);
+
+update hosting.users set role = 'owner'
+where id in (select id from access.users where role = 'owner');
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,19 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop index if exists hosting.idx_unique_client_user_id_not_revoked;
+create unique index hosting.idx_unique_client_user_id_not_revoked on instance_clients (user_id) where revoked_at is null;
+
+drop index if exists hosting.idx_unique_user_id_not_deleted;
+create unique index hosting.idx_unique_user_id_not_deleted on instances (user_id) where deleted_at is null and replaces is null;
+
+alter table hosting.instances drop column group_id;
+
+drop table if exists access.memberships;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,39 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Users belong to groups through memberships, with a role per group. The group
+-- of a user is their default group, the one they act in when no group is chosen.
+create table if not exists access.memberships (
+	user_id uuid not null,
+	group_id uuid not null,
+	role text not null default 'client',
+	primary key (user_id, group_id),
+	foreign key (user_id) references users(id),
+	foreign key (group_id) references groups(id)
+);
+
+insert into access.memberships (user_id, group_id, role)
+select id, group_id, role from access.users where group_id is not null;
+
+-- Instances belong to the group they are created in, rather than to the
+-- default group of their owners.
+alter table hosting.instances add column group_id uuid;
+
+update hosting.instances set group_id = (
+	select group_id from hosting.users where users.id = instances.user_id
+);
+
+-- Users have an instance, and are a client of an instance, per group.
+drop index if exists hosting.idx_unique_user_id_not_deleted;
+create unique index hosting.idx_unique_user_id_not_deleted on instances (user_id, group_id) where deleted_at is null and replaces is null;
+
+drop index if exists hosting.idx_unique_client_user_id_not_revoked;
+create unique index hosting.idx_unique_client_user_id_not_revoked on instance_clients (user_id, instance_id) where revoked_at is null;
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,16 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+alter table hosting.groups drop column require_two_factor;
+
+drop index if exists access.idx_sessions_expires_at;
+drop table if exists access.sessions;
+drop table if exists access.recovery_codes;
+drop table if exists access.two_factors;
+
+commit;
+
+detach database access;
+detach database hosting;
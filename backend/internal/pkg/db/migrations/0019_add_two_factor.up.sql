@@ -0,0 +1,46 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- The TOTP secrets of the users. Secrets are pending until the user verifies a
+-- code, and only enforced once enabled. last_step is the step of the last code
+-- accepted from the user. Codes are valid for a few steps, the ones of the last
+-- accepted step and before are rejected as replays.
+create table if not exists access.two_factors (
+	user_id uuid primary key,
+	secret text not null,
+	enabled integer not null default 0,
+	last_step integer not null default 0,
+	foreign key (user_id) references users(id)
+);
+
+-- Recovery codes are kept hashed, and deleted once used.
+create table if not exists access.recovery_codes (
+	user_id uuid not null,
+	code text not null,
+	primary key (user_id, code),
+	foreign key (user_id) references users(id)
+);
+
+-- Sessions of the users who logged in with their password. Only the hashes of
+-- the tokens are kept.
+create table if not exists access.sessions (
+	token text primary key,
+	user_id uuid not null,
+	two_factor integer not null default 0,
+	created_at text not null,
+	expires_at text not null,
+	foreign key (user_id) references users(id)
+);
+
+create index if not exists access.idx_sessions_expires_at on sessions (expires_at);
+
+-- Admins of the group act as clients until they enable two factor authentication.
+alter table hosting.groups add column require_two_factor integer not null default 0;
+
+commit;
+
+detach database access;
+detach database hosting;
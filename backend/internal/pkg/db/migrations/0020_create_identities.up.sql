@@ -0,0 +1,19 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Users of external identity providers, linked to the users they log in as.
+create table if not exists access.identities (
+	issuer text not null,
+	subject text not null,
+	user_id uuid not null,
+	primary key (issuer, subject),
+	foreign key (user_id) references users(id)
+);
+
+commit;
+
+detach database access;
+detach database hosting;
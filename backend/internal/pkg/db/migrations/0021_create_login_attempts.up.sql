@@ -0,0 +1,19 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- Failed logins and registrations, keyed by the username or the ip address
+-- they were made from, such as 'user:john' or 'ip:10.0.0.1'.
+create table if not exists access.login_attempts (
+	key text primary key,
+	failures integer not null default 0,
+	locked_until text,
+	updated_at text not null
+);
+
+commit;
+
+detach database access;
+detach database hosting;
@@ -0,0 +1,11 @@
+begin;
+
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+drop table if exists hosting.members;
+
+commit;
+
+detach database access;
+detach database hosting;
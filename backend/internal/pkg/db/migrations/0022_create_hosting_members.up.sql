@@ -0,0 +1,30 @@
+begin;
+
+PRAGMA foreign_keys = ON;
+attach database 'data/access.db' as access;
+attach database 'data/hosting.db' as hosting;
+
+-- The read model of the memberships in the hosting domain. The group of a user
+-- is only their default group; quotas and rate limits are resolved in the group
+-- the user acts in, or the group of the instance.
+create table if not exists hosting.members (
+	user_id uuid not null,
+	group_id uuid not null,
+	role text not null default 'client',
+	primary key (user_id, group_id),
+	foreign key (user_id) references users(id),
+	foreign key (group_id) references groups(id)
+);
+
+insert or ignore into hosting.members (user_id, group_id, role)
+select id, group_id, role from hosting.users;
+
+insert or ignore into hosting.members (user_id, group_id, role)
+select m.user_id, m.group_id, m.role from access.memberships m
+where m.user_id in (select id from hosting.users)
+	and m.group_id in (select id from hosting.groups);
+
+commit;
+
+detach database access;
+detach database hosting;
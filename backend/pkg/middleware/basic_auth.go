@@ -9,9 +9,26 @@ import (
 	"strings"
 )
 
+// GroupHeader is the header the group a request acts in is chosen with. The
+// default group of the user is acted in when it is not set. It is the only way to
+// choose the group, with a password as well as a bearer token; the group claims of
+// the identity provider are only used to provision users.
+const GroupHeader = "X-Group-ID"
+
+// OTPHeader is the header the two factor code of the users who enabled it is
+// sent with, along with their password.
+const OTPHeader = "X-OTP"
+
 type Credentials struct {
 	Username string
 	Password string
+	// Group is the id of the group the request acts in, if any.
+	Group string
+	// OTP is a TOTP code or a recovery code, if any.
+	OTP string
+	// Token is the bearer token of the users who logged in through an identity
+	// provider, instead of their username and password.
+	Token string
 }
 
 var ErrCredsNotFoundOnContext = errors.New("credntials not found on the context")
@@ -23,8 +40,8 @@ type Exclusion struct {
 }
 
 // BasicAuthMiddleware is a middleware to ensure that authorization header
-// is present and is of type basic. It extracts the username and password
-// and sets them on the request context.
+// is present and is of type basic or bearer. It extracts the username and
+// password, or the token, and sets them on the request context.
 func BasicAuthMiddleware(exclusions []Exclusion) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -46,8 +63,18 @@ func BasicAuthMiddleware(exclusions []Exclusion) MiddlewareFunc {
 			}
 
 			parts := strings.Split(auth, " ")
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				ctx := context.WithValue(r.Context(), basic, Credentials{
+					Token: parts[1],
+					Group: r.Header.Get(GroupHeader),
+				})
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			if len(parts) != 2 || parts[0] != "Basic" {
-				writeJSONError(w, `Only "Basic" authorization is allowed`)
+				writeJSONError(w, `Only "Basic" and "Bearer" authorizations are allowed`)
 				return
 			}
 
@@ -66,6 +93,8 @@ func BasicAuthMiddleware(exclusions []Exclusion) MiddlewareFunc {
 			ctx := context.WithValue(r.Context(), basic, Credentials{
 				Username: creds[0],
 				Password: creds[1],
+				Group:    r.Header.Get(GroupHeader),
+				OTP:      r.Header.Get(OTPHeader),
 			})
 
 			next.ServeHTTP(w, r.WithContext(ctx))
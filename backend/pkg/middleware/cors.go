@@ -12,7 +12,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		// Add CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+GroupHeader+", "+OTPHeader)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Handle preflight (OPTIONS) requests
@@ -12,6 +12,7 @@ type ctxtype string
 const (
 	basic     ctxtype = "basic"
 	requestID ctxtype = "request_id"
+	clientIP  ctxtype = "client_ip"
 )
 
 func writeJSONError(w http.ResponseWriter, message string) {
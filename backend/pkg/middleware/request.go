@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/gofrs/uuid/v5"
@@ -30,3 +31,34 @@ func GetRequestID(ctx context.Context) (uuid.UUID, error) {
 
 	return requestID, nil
 }
+
+// RealIPHeader is the header the reverse proxy sets to the address of the client.
+const RealIPHeader = "X-Real-IP"
+
+// ClientIPMiddleware attaches the address of the client to each request context.
+// The X-Real-IP header is only trusted behind a reverse proxy, which sets it;
+// otherwise clients could send any address they like.
+func ClientIPMiddleware(trustProxy bool) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := r.Header.Get(RealIPHeader)
+			if !trustProxy || ip == "" {
+				host, _, err := net.SplitHostPort(r.RemoteAddr)
+				if err != nil {
+					host = r.RemoteAddr
+				}
+				ip = host
+			}
+
+			ctx := context.WithValue(r.Context(), clientIP, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP returns the address of the client attached to the context, or an
+// empty string if there is none.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIP).(string)
+	return ip
+}
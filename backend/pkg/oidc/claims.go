@@ -0,0 +1,63 @@
+package oidc
+
+import (
+	"encoding/json"
+)
+
+// Claims are the claims of an id token. Raw holds every claim, including the
+// ones specific to the provider, such as the groups of the user.
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud"`
+	Expiry    int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf"`
+	Nonce     string   `json:"nonce"`
+
+	Raw map[string]any `json:"-"`
+}
+
+// Audience is the clients a token is issued for. It is either a single string
+// or a list of them.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// String returns the claim, if it is a string.
+func (c *Claims) String(name string) string {
+	s, _ := c.Raw[name].(string)
+	return s
+}
+
+// Strings returns the claim as a list, if it is a string or a list of them.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
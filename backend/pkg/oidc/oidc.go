@@ -0,0 +1,328 @@
+// Package oidc implements the authorization code flow of OpenID Connect, as the
+// relying party, with the id tokens signed by RS256.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Leeway is the clock drift tolerated when checking the times of the tokens.
+	Leeway = time.Minute
+	// KeysRefetchInterval is the least time between two fetches of the signing keys,
+	// so tokens with unknown key ids can not make the provider fetch them on every
+	// request.
+	KeysRefetchInterval = time.Minute
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid id token")
+	ErrExpiredToken = errors.New("expired id token")
+)
+
+// Config is the registration of the client at the provider.
+type Config struct {
+	// Issuer is the url of the provider, the discovery document is served under.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the users back to, with the code.
+	RedirectURL string
+	// Scopes are requested along with the openid scope.
+	Scopes []string
+}
+
+// Provider is an OpenID provider, discovered from its issuer.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	metadata metadata
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Discover fetches the discovery document of the issuer.
+func Discover(ctx context.Context, client *http.Client, config Config) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	p := &Provider{config: config, client: client}
+	u := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.get(ctx, u, &p.metadata); err != nil {
+		return nil, fmt.Errorf("error discovering %s: %w", config.Issuer, err)
+	}
+
+	if p.metadata.Issuer != config.Issuer {
+		return nil, fmt.Errorf("issuer %s does not match the configured %s", p.metadata.Issuer, config.Issuer)
+	}
+
+	return p, nil
+}
+
+// Issuer returns the issuer of the provider.
+func (p *Provider) Issuer() string {
+	return p.metadata.Issuer
+}
+
+// AuthCodeURL returns the url of the provider the users log in at. The state and
+// the nonce should be random, and checked once the users are back.
+func (p *Provider) AuthCodeURL(state, nonce string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("scope", strings.Join(append([]string{"openid"}, p.config.Scopes...), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange redeems the code for the id token, and verifies it. The nonce of the
+// token should match the one the login started with.
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (string, *Claims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("error exchanging code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, fmt.Errorf("error decoding token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("error exchanging code: %s %s: %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+
+	if body.IDToken == "" {
+		return "", nil, fmt.Errorf("%w: missing from the token response", ErrInvalidToken)
+	}
+
+	claims, err := p.Verify(ctx, body.IDToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if claims.Nonce != nonce {
+		return "", nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	return body.IDToken, claims, nil
+}
+
+// Verify checks the signature, the issuer, the audience and the expiry of the id
+// token, and returns its claims.
+func (p *Provider) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != p.metadata.Issuer {
+		return nil, fmt.Errorf("%w: issued by %s", ErrInvalidToken, claims.Issuer)
+	}
+
+	if !slices.Contains(claims.Audience, p.config.ClientID) {
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	now := time.Now()
+	if time.Unix(claims.Expiry, 0).Add(Leeway).Before(now) {
+		return nil, ErrExpiredToken
+	}
+
+	if claims.NotBefore != 0 && time.Unix(claims.NotBefore, 0).Add(-Leeway).After(now) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+// key returns the signing key of the provider with the id. Keys are fetched
+// again when missing, as the providers rotate them, but at most once every
+// KeysRefetchInterval.
+func (p *Provider) key(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(id); ok {
+		return key, nil
+	}
+
+	if time.Since(p.fetchedAt) < KeysRefetchInterval {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, id)
+	}
+	p.fetchedAt = time.Now()
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.get(ctx, p.metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("error fetching keys: %w", err)
+	}
+
+	p.keys = map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		p.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if key, ok := p.lookup(id); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, id)
+}
+
+// lookup finds the key with the id. Tokens without an id are verified with the
+// only key of the provider.
+func (p *Provider) lookup(id string) (*rsa.PublicKey, bool) {
+	if id == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[id]
+	return key, ok
+}
+
+func (p *Provider) get(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return nil
+}
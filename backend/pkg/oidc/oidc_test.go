@@ -0,0 +1,154 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vpainless/pkg/oidc"
+	"vpainless/pkg/oidc/oidctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oidc/callback"
+
+func setup(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+
+	server, ts, err := oidctest.NewTestServer("vpainless", "secret")
+	require.NoError(t, err, "should start the mock provider")
+	t.Cleanup(ts.Close)
+
+	provider, err := oidc.Discover(context.Background(), ts.Client(), oidc.Config{
+		Issuer:       server.Issuer,
+		ClientID:     "vpainless",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"profile", "groups"},
+	})
+	require.NoError(t, err, "should discover the mock provider")
+
+	return server, provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	server, provider := setup(t)
+	server.Claims = map[string]any{"sub": "42", "preferred_username": "john", "groups": []string{"family"}}
+
+	u, err := url.Parse(provider.AuthCodeURL("state", "nonce"))
+	require.NoError(t, err, "should return a valid url")
+	require.Equal(t, "openid profile groups", u.Query().Get("scope"))
+	require.Equal(t, redirectURL, u.Query().Get("redirect_uri"))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(u.String())
+	require.NoError(t, err, "should log in at the mock provider")
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode, "should redirect back with the code")
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err, "should redirect to a valid url")
+	require.Equal(t, "state", callback.Query().Get("state"), "should keep the state")
+
+	ctx := context.Background()
+	_, _, err = provider.Exchange(ctx, callback.Query().Get("code"), "other")
+	require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tokens of other logins")
+
+	code := server.Code(redirectURL, "nonce", server.Claims)
+	token, claims, err := provider.Exchange(ctx, code, "nonce")
+	require.NoError(t, err, "should exchange the code for a verified token")
+	require.NotEmpty(t, token)
+	require.Equal(t, "42", claims.Subject)
+	require.Equal(t, "john", claims.String("preferred_username"))
+	require.Equal(t, []string{"family"}, claims.Strings("groups"))
+
+	_, _, err = provider.Exchange(ctx, code, "nonce")
+	require.Error(t, err, "codes should be redeemed once")
+
+	verified, err := provider.Verify(ctx, token)
+	require.NoError(t, err, "should verify the issued token")
+	require.Equal(t, claims, verified)
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	server, provider := setup(t)
+	ctx := context.Background()
+
+	expired, err := server.Sign(map[string]any{"sub": "42", "exp": time.Now().Add(-time.Hour).Unix()})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, expired)
+	require.ErrorIs(t, err, oidc.ErrExpiredToken, "should reject expired tokens")
+
+	audience, err := server.Sign(map[string]any{"sub": "42", "aud": "other"})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, audience)
+	require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tokens of other clients")
+
+	issuer, err := server.Sign(map[string]any{"sub": "42", "iss": "https://example.com"})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, issuer)
+	require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tokens of other issuers")
+
+	valid, err := server.Sign(map[string]any{"sub": "42"})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, valid[:len(valid)-4]+"AAAA")
+	require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tampered tokens")
+
+	other, err := oidctest.New(server.Issuer, "vpainless", "secret")
+	require.NoError(t, err)
+	forged, err := other.Sign(map[string]any{"sub": "42"})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, forged)
+	require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tokens signed by other keys")
+}
+
+func TestVerify_KeysRefetch(t *testing.T) {
+	server, err := oidctest.New("", "vpainless", "secret")
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks" {
+			fetches.Add(1)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	server.Issuer = ts.URL
+
+	ctx := context.Background()
+	provider, err := oidc.Discover(ctx, ts.Client(), oidc.Config{Issuer: server.Issuer, ClientID: "vpainless"})
+	require.NoError(t, err)
+
+	valid, err := server.Sign(map[string]any{"sub": "42"})
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, valid)
+	require.NoError(t, err, "should verify the token with the fetched keys")
+	require.EqualValues(t, 1, fetches.Load(), "should fetch the keys once")
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"unknown"}`))
+	for range 3 {
+		_, err = provider.Verify(ctx, header+".e30.AAAA")
+		require.ErrorIs(t, err, oidc.ErrInvalidToken, "should reject tokens of unknown keys")
+	}
+	require.EqualValues(t, 1, fetches.Load(), "should not fetch the keys again within the interval")
+
+	_, err = provider.Verify(ctx, valid)
+	require.NoError(t, err, "should keep verifying with the known keys")
+}
+
+func TestAudience(t *testing.T) {
+	var claims oidc.Claims
+	require.NoError(t, claims.Audience.UnmarshalJSON([]byte(`"a"`)))
+	require.Equal(t, oidc.Audience{"a"}, claims.Audience)
+	require.NoError(t, claims.Audience.UnmarshalJSON([]byte(`["a", "b"]`)))
+	require.Equal(t, oidc.Audience{"a", "b"}, claims.Audience)
+}
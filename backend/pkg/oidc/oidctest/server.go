@@ -0,0 +1,239 @@
+// Package oidctest provides a mock OpenID provider, to test the login flow
+// without running a real one.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// KeyID is the id of the signing key of the mock provider.
+	KeyID = "mock"
+	// TokenTTL is the lifetime of the issued tokens.
+	TokenTTL = 5 * time.Minute
+)
+
+// Server is a mock OpenID provider. Every login through its authorize endpoint
+// succeeds, as the user with the Claims.
+type Server struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Claims are the claims of the user logging in, along with the standard ones.
+	Claims map[string]any
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+type grant struct {
+	redirectURI string
+	nonce       string
+	claims      map[string]any
+}
+
+// New returns a mock provider with a new signing key. The issuer should be the
+// url it is served on.
+func New(issuer, clientID, clientSecret string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("error generating signing key: %w", err)
+	}
+
+	return &Server{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Claims:       map[string]any{"sub": "mock-user", "preferred_username": "mock"},
+		key:          key,
+		codes:        map[string]grant{},
+	}, nil
+}
+
+// NewTestServer starts a mock provider on a local server, which the caller
+// should close.
+func NewTestServer(clientID, clientSecret string) (*Server, *httptest.Server, error) {
+	s, err := New("", clientID, clientSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts := httptest.NewServer(s)
+	s.Issuer = ts.URL
+	return s, ts, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                s.Issuer,
+			"authorization_endpoint":                s.Issuer + "/authorize",
+			"token_endpoint":                        s.Issuer + "/token",
+			"jwks_uri":                              s.Issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	case "/jwks":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"kid": KeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+			}},
+		})
+	case "/authorize":
+		s.authorize(w, r)
+	case "/token":
+		s.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Code issues a code for the claims, as if the user logged in with the nonce.
+func (s *Server) Code(redirectURI, nonce string, claims map[string]any) string {
+	code := random()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = grant{redirectURI: redirectURI, nonce: nonce, claims: claims}
+	return code
+}
+
+// Sign issues an id token with the claims, filling in the standard ones that
+// are missing.
+func (s *Server) Sign(claims map[string]any) (string, error) {
+	now := time.Now()
+	all := map[string]any{
+		"iss": s.Issuer,
+		"aud": s.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(TokenTTL).Unix(),
+	}
+	maps.Copy(all, claims)
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": KeyID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(all)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	code := s.Code(q.Get("redirect_uri"), q.Get("nonce"), s.Claims)
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id != s.ClientID || secret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.codes[r.PostFormValue("code")]
+	delete(s.codes, r.PostFormValue("code"))
+	s.mu.Unlock()
+	if !ok || g.redirectURI != r.PostFormValue("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := maps.Clone(g.claims)
+	if claims == nil {
+		claims = map[string]any{}
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+
+	token, err := s.Sign(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error", "error_description": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": random(),
+		"token_type":   "Bearer",
+		"expires_in":   int(TokenTTL.Seconds()),
+		"id_token":     token,
+	})
+}
+
+func random() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,122 @@
+package qr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type (
+	// Format is the image format a QR code is rendered into.
+	Format string
+	// Level is the error correction level of a QR code.
+	Level string
+)
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+
+	// Low recovers 7% of data.
+	Low Level = "low"
+	// Medium recovers 15% of data.
+	Medium Level = "medium"
+	// High recovers 25% of data.
+	High Level = "high"
+	// Highest recovers 30% of data.
+	Highest Level = "highest"
+
+	MinSize     = 64
+	MaxSize     = 1024
+	DefaultSize = 256
+)
+
+// Options controls how a QR code is rendered.
+type Options struct {
+	Format Format
+	Level  Level
+	// Size is the width and height of the image in pixels.
+	Size int
+}
+
+// ContentType returns the mime type of the rendered image.
+func (f Format) ContentType() string {
+	switch f {
+	case SVG:
+		return "image/svg+xml"
+	default:
+		return "image/png"
+	}
+}
+
+func (l Level) recoveryLevel() (qrcode.RecoveryLevel, error) {
+	switch l {
+	case Low:
+		return qrcode.Low, nil
+	case Medium, "":
+		return qrcode.Medium, nil
+	case High:
+		return qrcode.High, nil
+	case Highest:
+		return qrcode.Highest, nil
+	}
+
+	return 0, fmt.Errorf("invalid error correction level %q", l)
+}
+
+// Encode renders content as a QR code image and writes it to w.
+func Encode(w io.Writer, content string, opts Options) error {
+	if opts.Size == 0 {
+		opts.Size = DefaultSize
+	}
+	if opts.Size < MinSize || opts.Size > MaxSize {
+		return fmt.Errorf("invalid size %d, size should be between %d and %d", opts.Size, MinSize, MaxSize)
+	}
+
+	level, err := opts.Level.recoveryLevel()
+	if err != nil {
+		return err
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return fmt.Errorf("error encoding qr code: %w", err)
+	}
+
+	switch opts.Format {
+	case PNG, "":
+		b, err := code.PNG(opts.Size)
+		if err != nil {
+			return fmt.Errorf("error rendering png: %w", err)
+		}
+		_, err = w.Write(b)
+		return err
+	case SVG:
+		_, err := io.WriteString(w, svg(code.Bitmap(), opts.Size))
+		return err
+	}
+
+	return fmt.Errorf("invalid format %q", opts.Format)
+}
+
+// svg renders the bitmap as a scalable image. Each dark module is drawn as
+// a unit square in a single path, and the view box scales it to size.
+func svg(bitmap [][]bool, size int) string {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 %[2]d %[2]d" shape-rendering="crispEdges">`, size, len(bitmap))
+	fmt.Fprintf(&sb, `<rect width="%[1]d" height="%[1]d" fill="#ffffff"/>`, len(bitmap))
+	fmt.Fprintf(&sb, `<path fill="#000000" d="%s"/>`, path.String())
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
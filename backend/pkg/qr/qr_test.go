@@ -0,0 +1,53 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const content = "vless://11111111-1111-1111-1111-111111111111@127.0.0.1:443?security=reality#xray"
+
+func TestEncode_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, content, Options{Format: PNG, Level: High, Size: 128})
+	require.NoError(t, err, "should encode png successfully")
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err, "should decode the rendered png")
+	require.Equal(t, 128, img.Bounds().Dx(), "width should match the requested size")
+	require.Equal(t, 128, img.Bounds().Dy(), "height should match the requested size")
+}
+
+func TestEncode_SVG(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, content, Options{Format: SVG, Size: 300})
+	require.NoError(t, err, "should encode svg successfully")
+
+	out := buf.String()
+	require.True(t, strings.HasPrefix(out, "<svg"), "should start with svg tag")
+	require.Contains(t, out, `width="300" height="300"`, "should have the requested size")
+	require.Contains(t, out, "M", "should draw dark modules")
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	tt := []struct {
+		name string
+		opts Options
+	}{
+		{name: "small size", opts: Options{Size: MinSize - 1}},
+		{name: "large size", opts: Options{Size: MaxSize + 1}},
+		{name: "format", opts: Options{Format: "gif"}},
+		{name: "level", opts: Options{Level: "extreme"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.Error(t, Encode(&buf, content, tc.opts), "should reject invalid options")
+		})
+	}
+}
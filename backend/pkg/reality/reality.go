@@ -0,0 +1,356 @@
+// Package reality connects to xray servers as a VLESS client over Reality, to
+// check that clients can connect, rather than that the server only answers TLS.
+//
+// Reality servers forward the handshakes of unknown clients to their destination,
+// so a plain TLS handshake succeeds whenever the destination does. Clients are told
+// apart by their session id, which is encrypted with a key shared with the server,
+// and the server proves it has the key with the certificate it presents.
+package reality
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	ErrUnreachable = errors.New("server is not reachable")
+	ErrHandshake   = errors.New("reality handshake failed")
+	// ErrNotVerified is returned when the handshake is answered by the destination,
+	// rather than the server, which does not accept the public key or the short id.
+	ErrNotVerified = errors.New("reality handshake is not verified by the server")
+	ErrRejected    = errors.New("vless client is rejected")
+)
+
+// DefaultTimeout is used when no timeout is given in the options.
+const DefaultTimeout = 5 * time.Second
+
+// FlowVision is the flow of the clients xray is set up with.
+const FlowVision = "xtls-rprx-vision"
+
+// clientVersion is the xray version reported in the session id. Servers reject
+// the clients older than their minimum version.
+var clientVersion = [3]byte{25, 10, 15}
+
+// DialFunc dials the address of the server.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Options are the parameters of the client, as in its connection string.
+type Options struct {
+	// ServerName is the destination of the server, sent as SNI.
+	ServerName string
+	// PublicKey is the x25519 public key of the server, base64 url encoded.
+	PublicKey string
+	// ShortID is the hex encoded short id of the client.
+	ShortID string
+	// ClientID is the vless id of the client.
+	ClientID uuid.UUID
+	// Flow is the flow of the client. Defaults to FlowVision.
+	Flow string
+	// RootCAs verify the certificate of the destination, when the handshake is
+	// not verified by the server. Defaults to the system pool.
+	RootCAs *x509.CertPool
+	// Dial is used to connect to the server. Defaults to dialing locally.
+	Dial    DialFunc
+	Timeout time.Duration
+}
+
+// Probe connects to the server at the address as the client, and asks it to
+// connect to the destination on port 443. It returns nil if the server verifies
+// the handshake, accepts the client and relays the answer of the destination.
+func Probe(ctx context.Context, addr string, opts Options) error {
+	if opts.Dial == nil {
+		opts.Dial = (&net.Dialer{}).DialContext
+	}
+	if opts.Flow == "" {
+		opts.Flow = FlowVision
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	conn, err := opts.Dial(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Join(ErrUnreachable, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	client, err := Handshake(ctx, conn, opts)
+	if err != nil {
+		return err
+	}
+
+	hello, err := clientHello(opts.ServerName)
+	if err != nil {
+		return err
+	}
+
+	request, err := vlessRequest(opts, opts.ServerName, 443, hello)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Write(request); err != nil {
+		return fmt.Errorf("error sending vless request: %w", err)
+	}
+
+	// The server answers once the destination does, so the clients it rejects,
+	// and the destinations it can not reach, are both told by the connection
+	// closing without an answer.
+	var response [2]byte
+	if _, err := io.ReadFull(client, response[:]); err != nil {
+		return errors.Join(ErrRejected, err)
+	}
+	if response[0] != 0 {
+		return fmt.Errorf("%w: unexpected version %d", ErrRejected, response[0])
+	}
+
+	return nil
+}
+
+// Handshake does the Reality handshake as the client over the connection, and
+// returns the connection to send the vless requests over.
+func Handshake(ctx context.Context, conn net.Conn, opts Options) (net.Conn, error) {
+	publicKey, err := base64.RawURLEncoding.DecodeString(opts.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	serverKey, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	shortID, err := hex.DecodeString(opts.ShortID)
+	if err != nil || len(shortID) > 8 {
+		return nil, fmt.Errorf("invalid short id %q", opts.ShortID)
+	}
+
+	var (
+		authKey  []byte
+		verified bool
+	)
+	client := utls.UClient(conn, &utls.Config{
+		ServerName:             opts.ServerName,
+		SessionTicketsDisabled: true,
+		// The certificate is verified below, as it is either signed with the
+		// shared key by the server, or a certificate of the destination.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			var verr error
+			verified, verr = verifyCertificate(rawCerts, authKey, opts.ServerName, opts.RootCAs)
+			return verr
+		},
+	}, utls.HelloChrome_Auto)
+
+	if err := client.BuildHandshakeState(); err != nil {
+		return nil, errors.Join(ErrHandshake, err)
+	}
+
+	keys := client.HandshakeState.State13.KeyShareKeys
+	if keys == nil {
+		return nil, fmt.Errorf("%w: no key share", ErrHandshake)
+	}
+	ecdhe := keys.Ecdhe
+	if ecdhe == nil {
+		ecdhe = keys.MlkemEcdhe
+	}
+	if ecdhe == nil {
+		return nil, fmt.Errorf("%w: no x25519 key share", ErrHandshake)
+	}
+
+	hello := client.HandshakeState.Hello
+	authKey, err = sealSessionID(hello, ecdhe, serverKey, shortID, time.Now())
+	if err != nil {
+		return nil, errors.Join(ErrHandshake, err)
+	}
+
+	if err := client.HandshakeContext(ctx); err != nil {
+		return nil, errors.Join(ErrHandshake, err)
+	}
+	if !verified {
+		return nil, ErrNotVerified
+	}
+
+	return client, nil
+}
+
+// sealSessionID writes the encrypted session id into the client hello, and
+// returns the key shared with the server. The session id holds the version of the
+// client, the time and the short id, and is sealed with the whole hello.
+func sealSessionID(hello *utls.PubClientHelloMsg, ecdhe *ecdh.PrivateKey, serverKey *ecdh.PublicKey, shortID []byte, now time.Time) ([]byte, error) {
+	// The session id is at a fixed offset, after the handshake header, the
+	// version, the random and the length of the session id.
+	const offset = 39
+	if len(hello.Raw) < offset+32 {
+		return nil, errors.New("client hello is too short")
+	}
+
+	sessionID := make([]byte, 32)
+	copy(hello.Raw[offset:], sessionID)
+	copy(sessionID, clientVersion[:])
+	binary.BigEndian.PutUint32(sessionID[4:], uint32(now.Unix()))
+	copy(sessionID[8:], shortID)
+
+	shared, err := ecdhe.ECDH(serverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	authKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, hello.Random[:20], []byte("REALITY")), authKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(authKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	aead.Seal(sessionID[:0], hello.Random[20:], sessionID[:16], hello.Raw)
+	copy(hello.Raw[offset:], sessionID)
+	hello.SessionId = sessionID
+	return authKey, nil
+}
+
+// verifyCertificate reports whether the certificate is signed by the server with
+// the shared key. Other certificates are of the destination, and are verified as
+// usual, so the handshake succeeds without being verified.
+func verifyCertificate(rawCerts [][]byte, authKey []byte, serverName string, roots *x509.CertPool) (bool, error) {
+	if len(rawCerts) == 0 {
+		return false, errors.New("no certificates")
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return false, err
+	}
+
+	if pub, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		h := hmac.New(sha512.New, authKey)
+		h.Write(pub)
+		if hmac.Equal(h.Sum(nil), cert.Signature) {
+			return true, nil
+		}
+	}
+
+	opts := x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, raw := range rawCerts[1:] {
+		intermediate, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return false, err
+		}
+		opts.Intermediates.AddCert(intermediate)
+	}
+
+	_, err = cert.Verify(opts)
+	return false, err
+}
+
+// vlessRequest encodes the request to connect to the host and port over tcp,
+// along with the first payload, padded as the flow requires.
+func vlessRequest(opts Options, host string, port uint16, payload []byte) ([]byte, error) {
+	if len(host) > 255 {
+		return nil, fmt.Errorf("invalid host %q", host)
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(0) // version
+	b.Write(opts.ClientID.Bytes())
+
+	// The addons are a protobuf message, with the flow as its first field.
+	var addons []byte
+	if opts.Flow != "" {
+		addons = append([]byte{0x0a, byte(len(opts.Flow))}, opts.Flow...)
+	}
+	b.WriteByte(byte(len(addons)))
+	b.Write(addons)
+
+	b.WriteByte(1) // tcp
+	b.Write(binary.BigEndian.AppendUint16(nil, port))
+	if ip := net.ParseIP(host); ip.To4() != nil {
+		b.WriteByte(1)
+		b.Write(ip.To4())
+	} else if ip != nil {
+		b.WriteByte(3)
+		b.Write(ip.To16())
+	} else {
+		b.WriteByte(2)
+		b.WriteByte(byte(len(host)))
+		b.WriteString(host)
+	}
+
+	if opts.Flow != FlowVision {
+		b.Write(payload)
+		return b.Bytes(), nil
+	}
+
+	// Vision pads the first payloads, starting with the id of the client. The
+	// end command stops the padding, so the rest is sent as is.
+	if len(payload) > 0xffff {
+		return nil, errors.New("payload is too large")
+	}
+	b.Write(opts.ClientID.Bytes())
+	b.WriteByte(1) // end
+	b.Write(binary.BigEndian.AppendUint16(nil, uint16(len(payload))))
+	b.Write([]byte{0, 0}) // no padding
+	b.Write(payload)
+	return b.Bytes(), nil
+}
+
+// clientHello returns a TLS client hello for the server name, for the destination
+// to answer.
+func clientHello(serverName string) ([]byte, error) {
+	conn := &captureConn{}
+	_ = tls.Client(conn, &tls.Config{ServerName: serverName, NextProtos: []string{"h2", "http/1.1"}}).Handshake()
+	if len(conn.written) == 0 {
+		return nil, errors.New("error encoding client hello")
+	}
+	return conn.written, nil
+}
+
+// captureConn keeps the first write, and fails it to stop the handshake there.
+type captureConn struct {
+	net.Conn
+	written []byte
+}
+
+var errCaptured = errors.New("captured")
+
+func (c *captureConn) Write(b []byte) (int, error) {
+	c.written = append(c.written, b...)
+	return 0, errCaptured
+}
+
+func (c *captureConn) Close() error { return nil }
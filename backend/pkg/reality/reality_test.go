@@ -0,0 +1,169 @@
+package reality
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+	xreality "github.com/xtls/reality"
+)
+
+// newServer starts a reality server in front of a test destination, as xray does,
+// which accepts the client id with the vision flow. It returns the address of the
+// server, and the options of a client it accepts.
+func newServer(t *testing.T) (string, Options) {
+	t.Helper()
+
+	dest := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	dest.EnableHTTP2 = true
+	dest.StartTLS()
+	t.Cleanup(dest.Close)
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	shortID := [8]byte{0xab, 0xcd, 0xef}
+	clientID := uuid.Must(uuid.NewV4())
+	config := &xreality.Config{
+		DialContext:            (&net.Dialer{}).DialContext,
+		Type:                   "tcp",
+		Dest:                   dest.Listener.Addr().String(),
+		ServerNames:            map[string]bool{"example.com": true},
+		PrivateKey:             key.Bytes(),
+		MinClientVer:           []byte{1, 8, 0},
+		ShortIds:               map[[8]byte]bool{shortID: true},
+		SessionTicketsDisabled: true,
+	}
+
+	// Servers wait for the records the destination sends after its handshakes
+	// to be detected, to send the same.
+	xreality.DetectPostHandshakeRecordsLens(config)
+	require.Eventually(t, func() bool {
+		for alpn := range 3 {
+			val, _ := xreality.GlobalPostHandshakeRecordsLens.Load(fmt.Sprintf("%s example.com %d", config.Dest, alpn))
+			if _, ok := val.([]int); !ok {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "should detect the records of the destination")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				server, err := xreality.Server(context.Background(), conn, config)
+				if err != nil {
+					return
+				}
+				if acceptRequest(server, clientID) {
+					server.Write([]byte{0, 0})
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), Options{
+		ServerName: "example.com",
+		PublicKey:  base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		ShortID:    hex.EncodeToString(shortID[:3]),
+		ClientID:   clientID,
+		RootCAs:    dest.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+	}
+}
+
+// acceptRequest reads the vless request, and reports whether it is of the client
+// with the vision flow, to the destination on port 443.
+func acceptRequest(r io.Reader, clientID uuid.UUID) bool {
+	header := make([]byte, 18)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false
+	}
+	if header[0] != 0 || !bytes.Equal(header[1:17], clientID.Bytes()) {
+		return false
+	}
+
+	addons := make([]byte, header[17])
+	if _, err := io.ReadFull(r, addons); err != nil {
+		return false
+	}
+	if !bytes.Equal(addons, append([]byte{0x0a, byte(len(FlowVision))}, FlowVision...)) {
+		return false
+	}
+
+	target := make([]byte, 5)
+	if _, err := io.ReadFull(r, target); err != nil {
+		return false
+	}
+	if target[0] != 1 || target[1] != 1 || target[2] != 187 || target[3] != 2 {
+		return false
+	}
+	host := make([]byte, target[4])
+	if _, err := io.ReadFull(r, host); err != nil || string(host) != "example.com" {
+		return false
+	}
+
+	padding := make([]byte, 21)
+	if _, err := io.ReadFull(r, padding); err != nil {
+		return false
+	}
+	return bytes.Equal(padding[:16], clientID.Bytes()) && padding[16] == 1
+}
+
+func TestProbe(t *testing.T) {
+	addr, opts := newServer(t)
+	ctx := context.Background()
+
+	require.NoError(t, Probe(ctx, addr, opts), "should connect as the client")
+
+	other := opts
+	other.ClientID = uuid.Must(uuid.NewV4())
+	require.ErrorIs(t, Probe(ctx, addr, other), ErrRejected, "should be rejected with another client id")
+
+	other = opts
+	other.ShortID = "012345"
+	require.ErrorIs(t, Probe(ctx, addr, other), ErrNotVerified, "should be answered by the destination with another short id")
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	other = opts
+	other.PublicKey = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	require.ErrorIs(t, Probe(ctx, addr, other), ErrNotVerified, "should be answered by the destination with another public key")
+
+	other = opts
+	other.RootCAs = nil
+	other.PublicKey = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	require.ErrorIs(t, Probe(ctx, addr, other), ErrHandshake, "should not trust the destination without its certificate")
+
+	require.ErrorIs(t, Probe(ctx, "127.0.0.1:1", opts), ErrUnreachable, "should not reach closed ports")
+}
+
+func TestVLESSRequest(t *testing.T) {
+	clientID := uuid.Must(uuid.NewV4())
+	request, err := vlessRequest(Options{ClientID: clientID}, "1.2.3.4", 80, []byte("hi"))
+	require.NoError(t, err)
+
+	expected := append([]byte{0}, clientID.Bytes()...)
+	expected = append(expected, 0, 1, 0, 80, 1, 1, 2, 3, 4)
+	require.Equal(t, append(expected, "hi"...), request, "should not pad without a flow")
+}
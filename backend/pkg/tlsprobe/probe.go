@@ -0,0 +1,88 @@
+// Package tlsprobe checks if a domain is suitable as a Reality destination.
+//
+// Reality borrows the TLS handshake of the destination, so the destination
+// should support TLS 1.3 and HTTP/2, and present a valid certificate. It
+// should also be reachable from the server, that's why the probe can dial
+// through another machine, e.g. an ssh client connected to the server.
+package tlsprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var (
+	ErrUnreachable = errors.New("destination is not reachable")
+	ErrHandshake   = errors.New("tls handshake failed")
+	ErrTLSVersion  = errors.New("tls 1.3 is not supported")
+	ErrALPN        = errors.New("h2 is not supported")
+)
+
+// DefaultTimeout is used when no timeout is given in the options.
+const DefaultTimeout = 5 * time.Second
+
+// DialFunc dials the address of the destination.
+// *net.Dialer and *ssh.Client both satisfy it through their DialContext method.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Options controls how a domain is probed.
+type Options struct {
+	// Dial is used to connect to the destination. Defaults to dialing locally.
+	Dial DialFunc
+	// Port of the destination. Defaults to 443.
+	Port int
+	// RootCAs verify the certificate of the destination. Defaults to the system pool.
+	RootCAs *x509.CertPool
+	Timeout time.Duration
+}
+
+// Probe connects to the domain and returns an error describing why it is not
+// suitable as a Reality destination, or nil if it is.
+func Probe(ctx context.Context, domain string, opts Options) error {
+	if opts.Dial == nil {
+		opts.Dial = (&net.Dialer{}).DialContext
+	}
+	if opts.Port == 0 {
+		opts.Port = 443
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	conn, err := opts.Dial(ctx, "tcp", net.JoinHostPort(domain, fmt.Sprint(opts.Port)))
+	if err != nil {
+		return errors.Join(ErrUnreachable, err)
+	}
+	defer conn.Close()
+
+	// Older versions and protocols are offered too, so that the handshake
+	// succeeds and the reason of unsuitability can be told apart.
+	client := tls.Client(conn, &tls.Config{
+		ServerName: domain,
+		RootCAs:    opts.RootCAs,
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+
+	if err := client.HandshakeContext(ctx); err != nil {
+		return errors.Join(ErrHandshake, err)
+	}
+
+	state := client.ConnectionState()
+	if state.Version != tls.VersionTLS13 {
+		return ErrTLSVersion
+	}
+	if state.NegotiatedProtocol != "h2" {
+		return ErrALPN
+	}
+
+	return nil
+}
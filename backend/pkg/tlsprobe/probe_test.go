@@ -0,0 +1,91 @@
+package tlsprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newServer starts a test server and returns the options to probe it
+// as example.com, the name its certificate is issued for.
+func newServer(t *testing.T, configure func(*httptest.Server)) Options {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	configure(server)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return Options{
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+		RootCAs: server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		domain    string
+		configure func(*httptest.Server)
+		err       error
+	}{
+		{
+			name:   "suitable destination",
+			domain: "example.com",
+			configure: func(s *httptest.Server) {
+				s.EnableHTTP2 = true
+			},
+		},
+		{
+			name:      "no h2",
+			domain:    "example.com",
+			configure: func(s *httptest.Server) {},
+			err:       ErrALPN,
+		},
+		{
+			name:   "no tls 1.3",
+			domain: "example.com",
+			configure: func(s *httptest.Server) {
+				s.EnableHTTP2 = true
+				s.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+			},
+			err: ErrTLSVersion,
+		},
+		{
+			name:   "invalid certificate",
+			domain: "www.speedtest.net",
+			configure: func(s *httptest.Server) {
+				s.EnableHTTP2 = true
+			},
+			err: ErrHandshake,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := newServer(t, tt.configure)
+			err := Probe(context.Background(), tt.domain, opts)
+			if tt.err == nil {
+				require.NoError(t, err, "should find the destination suitable")
+				return
+			}
+			require.ErrorIs(t, err, tt.err, "should find the destination unsuitable")
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		err := Probe(context.Background(), "example.com", Options{
+			Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return nil, net.ErrClosed
+			},
+		})
+		require.ErrorIs(t, err, ErrUnreachable, "should not reach the destination")
+	})
+}
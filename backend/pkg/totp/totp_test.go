@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// secret is the key of the test vectors of RFC 6238, "12345678901234567890".
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	tt := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range tt {
+		actual, err := Code(secret, time.Unix(tc.unix, 0))
+		require.NoError(t, err, "should generate the code without any error")
+		require.Equal(t, tc.code, actual, "should match the test vector at %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	require.True(t, Validate(secret, "005924", now), "should accept the current code")
+	require.True(t, Validate(secret, "005924", now.Add(Period)), "should accept the previous code")
+	require.True(t, Validate(secret, "005924", now.Add(-Period)), "should accept the next code")
+	require.False(t, Validate(secret, "005924", now.Add(2*Period)), "should reject older codes")
+	require.False(t, Validate(secret, "005925", now), "should reject wrong codes")
+	require.False(t, Validate(secret, "5924", now), "should reject short codes")
+	require.False(t, Validate("not base32!", "005924", now), "should reject invalid secrets")
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / int64(Period.Seconds())
+
+	actual, ok := Match(secret, "005924", now)
+	require.True(t, ok, "should accept the current code")
+	require.Equal(t, step, actual, "should match the current step")
+
+	actual, ok = Match(secret, "005924", now.Add(Period))
+	require.True(t, ok, "should accept the previous code")
+	require.Equal(t, step, actual, "should match the step of the code, not the current one")
+
+	_, ok = Match(secret, "005925", now)
+	require.False(t, ok, "should reject wrong codes")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	require.NoError(t, err, "should generate a secret without any error")
+	b, err := GenerateSecret()
+	require.NoError(t, err, "should generate a secret without any error")
+	require.NotEqual(t, a, b, "secrets should be random")
+
+	_, err = Code(a, time.Now())
+	require.NoError(t, err, "generated secrets should be valid")
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Vpainless", "john", secret))
+	require.NoError(t, err, "should be a valid uri")
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Vpainless:john", u.Path)
+	require.Equal(t, secret, u.Query().Get("secret"))
+	require.Equal(t, "Vpainless", u.Query().Get("issuer"))
+}
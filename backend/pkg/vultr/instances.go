@@ -27,6 +27,10 @@ const (
 	Debian12       OSID         = 2136
 	Frankfurt      RegionID     = "fra"
 	Warsaw         RegionID     = "waw"
+	Amsterdam      RegionID     = "ams"
+	Stockholm      RegionID     = "sto"
+	Paris          RegionID     = "cdg"
+	London         RegionID     = "lhr"
 	BackupEnabled  BackupStatus = "enabled"
 	BackupDisabled BackupStatus = "disabled"
 
@@ -48,6 +52,7 @@ type Instance struct {
 	ID           InstanceID   `json:"id"`
 	OS           string       `json:"os"`
 	MainIP       string       `json:"main_ip"`
+	Region       RegionID     `json:"region"`
 	DateCreated  time.Time    `json:"date_created"`
 	Label        string       `json:"label"`
 	Tags         []string     `json:"tags"`
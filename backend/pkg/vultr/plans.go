@@ -0,0 +1,41 @@
+package vultr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type Plan struct {
+	ID PlanID `json:"id"`
+	// MonthlyCost is in US dollars. Instances are billed hourly, up to the monthly cost.
+	MonthlyCost float64  `json:"monthly_cost"`
+	Type        string   `json:"type"`
+	Locations   []string `json:"locations"`
+}
+
+type ListPlansResponse struct {
+	Plans []Plan `json:"plans"`
+}
+
+func (c *Client) ListPlans(ctx context.Context) (*ListPlansResponse, error) {
+	res, err := c.do(ctx, http.MethodGet, "v2/plans", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing plans: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("error listing plans: %s %s", http.StatusText(res.StatusCode), body)
+	}
+
+	resp := ListPlansResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}